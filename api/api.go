@@ -0,0 +1,400 @@
+// Package api implements an HTTP API to inspect and control
+// the proxies of a gateway while it is running.
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/haveachin/infrared"
+	"github.com/haveachin/infrared/callback"
+)
+
+// maxBodySize limits the size of proxy configs that are sent to the API
+const maxBodySize = 1 << 20
+
+// Server serves the API for a gateway
+type Server struct {
+	Gateway *infrared.Gateway
+	// Token has to be sent as a bearer token with every request except health checks.
+	// If it is empty, every request except health checks is rejected.
+	Token string
+	// Events is streamed to the subscribers of the event endpoints.
+	// If nil, the event endpoints are disabled.
+	Events *callback.Stream
+}
+
+// redactedValue replaces the secrets of configs in responses
+const redactedValue = "REDACTED"
+
+type proxyResponse struct {
+	UID      string                   `json:"uid"`
+	UIDs     []string                 `json:"uids"`
+	Players  int                      `json:"players"`
+	Backends []infrared.BackendHealth `json:"backends"`
+	Config   json.RawMessage          `json:"config,omitempty"`
+}
+
+type processResponse struct {
+	Running bool `json:"running"`
+	Pinned  bool `json:"pinned"`
+}
+
+type statusResponse struct {
+	Status string `json:"status"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func (server Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	segments, err := pathSegments(r.URL.EscapedPath())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if len(segments) == 1 {
+		switch segments[0] {
+		case "healthz":
+			server.handleHealth(w, r)
+			return
+		case "readyz":
+			server.handleReady(w, r)
+			return
+		}
+	}
+
+	if !server.isAuthorized(r, segments) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, errors.New("invalid token"))
+		return
+	}
+
+	if len(segments) > 0 && segments[0] == "events" {
+		server.handleEvents(w, r, segments[1:])
+		return
+	}
+
+	if len(segments) == 0 || segments[0] != "proxies" {
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+
+	if len(segments) == 1 {
+		switch r.Method {
+		case http.MethodGet:
+			server.handleListProxies(w)
+		case http.MethodPost:
+			server.handleRegisterProxy(w, r)
+		default:
+			writeMethodNotAllowed(w, http.MethodGet, http.MethodPost)
+		}
+		return
+	}
+
+	proxy, ok := server.Gateway.Proxy(segments[1])
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.New("no proxy with uid "+segments[1]))
+		return
+	}
+
+	switch {
+	case len(segments) == 2:
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, newProxyResponse(proxy, true))
+		case http.MethodDelete:
+			server.Gateway.UnregisterProxy(proxy)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			writeMethodNotAllowed(w, http.MethodGet, http.MethodDelete)
+		}
+	case len(segments) == 3 && segments[2] == "players":
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w, http.MethodGet)
+			return
+		}
+		players := proxy.Players()
+		if players == nil {
+			players = []infrared.Player{}
+		}
+		writeJSON(w, http.StatusOK, players)
+	case len(segments) == 4 && segments[2] == "players":
+		if r.Method != http.MethodDelete {
+			writeMethodNotAllowed(w, http.MethodDelete)
+			return
+		}
+		if proxy.KickPlayer(segments[3]) <= 0 {
+			writeError(w, http.StatusNotFound, errors.New("no player with username "+segments[3]))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case len(segments) >= 3 && segments[2] == "process":
+		server.handleProcess(w, r, proxy, segments[3:])
+	default:
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+	}
+}
+
+func (server Server) isAuthorized(r *http.Request, segments []string) bool {
+	if server.Token == "" {
+		return false
+	}
+
+	var token string
+	if header := r.Header.Get("Authorization"); header != "" {
+		// Only the Bearer scheme is accepted, not a bare token
+		if !strings.HasPrefix(header, "Bearer ") {
+			return false
+		}
+		token = header[len("Bearer "):]
+	} else if isEventStream(r, segments) {
+		// Browsers can't set headers for EventSource and WebSocket connections
+		token = r.URL.Query().Get("token")
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(server.Token)) == 1
+}
+
+// isEventStream checks if the request is a GET of /events or /events/ws, the only
+// endpoints that accept the token as a query parameter, since it might be logged
+func isEventStream(r *http.Request, segments []string) bool {
+	if r.Method != http.MethodGet || len(segments) == 0 || segments[0] != "events" {
+		return false
+	}
+	return len(segments) == 1 || len(segments) == 2 && segments[1] == "ws"
+}
+
+func (server Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+	writeJSON(w, http.StatusOK, statusResponse{Status: "ok"})
+}
+
+// handleReady reports if the gateway accepts connections
+func (server Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	if !server.Gateway.IsListening() {
+		writeJSON(w, http.StatusServiceUnavailable, statusResponse{Status: "not ready"})
+		return
+	}
+	writeJSON(w, http.StatusOK, statusResponse{Status: "ready"})
+}
+
+func (server Server) handleListProxies(w http.ResponseWriter) {
+	proxies := []proxyResponse{}
+	for _, proxy := range server.Gateway.Proxies() {
+		proxies = append(proxies, newProxyResponse(proxy, false))
+	}
+	writeJSON(w, http.StatusOK, proxies)
+}
+
+// handleRegisterProxy registers a proxy with the config in the request body.
+// Unlike proxies from config files, it is gone after a restart.
+func (server Server) handleRegisterProxy(w http.ResponseWriter, r *http.Request) {
+	bb, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	cfg, err := infrared.NewProxyConfigFromJSON(bb)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	proxy := &infrared.Proxy{Config: cfg}
+	for _, proxyUID := range proxy.UIDs() {
+		if _, ok := server.Gateway.Proxy(proxyUID); ok {
+			writeError(w, http.StatusConflict, errors.New("proxy with uid "+proxyUID+" is already registered"))
+			return
+		}
+	}
+
+	if err := server.Gateway.RegisterProxy(proxy); err != nil {
+		server.Gateway.UnregisterProxy(proxy)
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	log.Println("Registered proxy with UID", proxy.UID(), "via API")
+	writeJSON(w, http.StatusCreated, newProxyResponse(proxy, true))
+}
+
+func (server Server) handleProcess(w http.ResponseWriter, r *http.Request, proxy *infrared.Proxy, segments []string) {
+	var err error
+	switch {
+	case len(segments) == 0:
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w, http.MethodGet)
+			return
+		}
+		server.writeProcess(w, proxy)
+		return
+	case len(segments) == 1 && (segments[0] == "start" || segments[0] == "stop"):
+		if r.Method != http.MethodPost {
+			writeMethodNotAllowed(w, http.MethodPost)
+			return
+		}
+		if segments[0] == "start" {
+			err = proxy.StartProcess()
+		} else {
+			err = proxy.StopProcess()
+		}
+	case len(segments) == 1 && segments[0] == "pin":
+		switch r.Method {
+		case http.MethodPut:
+			err = proxy.PinProcess(true)
+		case http.MethodDelete:
+			err = proxy.PinProcess(false)
+		default:
+			writeMethodNotAllowed(w, http.MethodPut, http.MethodDelete)
+			return
+		}
+	default:
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+
+	if errors.Is(err, infrared.ErrNoProcess) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	server.writeProcess(w, proxy)
+}
+
+func (server Server) writeProcess(w http.ResponseWriter, proxy *infrared.Proxy) {
+	process := proxy.Process()
+	if process == nil {
+		writeError(w, http.StatusNotFound, infrared.ErrNoProcess)
+		return
+	}
+
+	running, err := process.IsRunning()
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, processResponse{
+		Running: running,
+		Pinned:  proxy.IsProcessPinned(),
+	})
+}
+
+func newProxyResponse(proxy *infrared.Proxy, withConfig bool) proxyResponse {
+	res := proxyResponse{
+		UID:      proxy.UID(),
+		UIDs:     proxy.UIDs(),
+		Players:  len(proxy.Players()),
+		Backends: proxy.BackendHealth(),
+	}
+
+	if withConfig {
+		bb, err := redactedConfig(proxy.Config)
+		if err == nil {
+			res.Config = bb
+		}
+	}
+
+	return res
+}
+
+// redactedConfig returns the config as JSON without the secrets that it contains,
+// since every API client can read it. Secrets that are set are replaced with redactedValue.
+func redactedConfig(config *infrared.ProxyConfig) ([]byte, error) {
+	config.RLock()
+	bb, err := json.Marshal(config)
+	config.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg infrared.ProxyConfig
+	if err := json.Unmarshal(bb, &cfg); err != nil {
+		return nil, err
+	}
+
+	redact(&cfg.Forwarding.Secret)
+	redact(&cfg.Docker.Portainer.Password)
+	redactCallbackServer(&cfg.CallbackServer)
+	for i := range cfg.CallbackServers {
+		redactCallbackServer(&cfg.CallbackServers[i])
+	}
+
+	return json.Marshal(&cfg)
+}
+
+func redactCallbackServer(cfg *infrared.CallbackServerConfig) {
+	redact(&cfg.Token)
+	redact(&cfg.Secret)
+
+	// Headers often carry credentials, e.g. API keys
+	headers := make(map[string]string, len(cfg.Headers))
+	for name, value := range cfg.Headers {
+		redact(&value)
+		headers[name] = value
+	}
+	cfg.Headers = headers
+}
+
+func redact(secret *string) {
+	if *secret != "" {
+		*secret = redactedValue
+	}
+}
+
+// pathSegments splits an escaped path into its unescaped segments,
+// so that proxy UIDs and usernames can contain escaped slashes
+func pathSegments(escapedPath string) ([]string, error) {
+	var segments []string
+	for _, segment := range strings.Split(strings.Trim(escapedPath, "/"), "/") {
+		if segment == "" {
+			continue
+		}
+
+		segment, err := url.PathUnescape(segment)
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, segment)
+	}
+	return segments, nil
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println("[w] Failed writing API response; error:", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, statusCode int, err error) {
+	writeJSON(w, statusCode, errorResponse{Error: err.Error()})
+}
+
+func writeMethodNotAllowed(w http.ResponseWriter, methods ...string) {
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+}
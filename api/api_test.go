@@ -0,0 +1,191 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/haveachin/infrared"
+)
+
+const testToken = "secret"
+
+func TestServer(t *testing.T) {
+	proxyUID := "api.example.com@:20670"
+	proxyPath := "/proxies/" + url.PathEscape(proxyUID)
+	server := Server{
+		Gateway: &infrared.Gateway{},
+		Token:   testToken,
+	}
+
+	tt := []struct {
+		name         string
+		method       string
+		path         string
+		token        string
+		header       string
+		body         string
+		expectedCode int
+	}{
+		{name: "health", method: http.MethodGet, path: "/healthz", expectedCode: http.StatusOK},
+		{name: "not ready", method: http.MethodGet, path: "/readyz", expectedCode: http.StatusServiceUnavailable},
+		{name: "no token", method: http.MethodGet, path: "/proxies", expectedCode: http.StatusUnauthorized},
+		{name: "wrong token", method: http.MethodGet, path: "/proxies", token: "wrong", expectedCode: http.StatusUnauthorized},
+		{name: "query token", method: http.MethodGet, path: "/proxies?token=" + testToken, expectedCode: http.StatusUnauthorized},
+		{name: "token without scheme", method: http.MethodGet, path: "/proxies", header: testToken, expectedCode: http.StatusUnauthorized},
+		{name: "basic scheme", method: http.MethodGet, path: "/proxies", header: "Basic " + testToken, expectedCode: http.StatusUnauthorized},
+		{name: "lowercase scheme", method: http.MethodGet, path: "/proxies", header: "bearer " + testToken, expectedCode: http.StatusUnauthorized},
+		{
+			name:         "register",
+			method:       http.MethodPost,
+			path:         "/proxies",
+			token:        testToken,
+			body:         `{"domainName": "api.example.com", "listenTo": ":20670", "proxyTo": ":20671"}`,
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "register twice",
+			method:       http.MethodPost,
+			path:         "/proxies",
+			token:        testToken,
+			body:         `{"domainName": "api.example.com", "listenTo": ":20670"}`,
+			expectedCode: http.StatusConflict,
+		},
+		{name: "invalid config", method: http.MethodPost, path: "/proxies", token: testToken, body: `{`, expectedCode: http.StatusBadRequest},
+		{name: "ready", method: http.MethodGet, path: "/readyz", expectedCode: http.StatusOK},
+		{name: "list", method: http.MethodGet, path: "/proxies", token: testToken, expectedCode: http.StatusOK},
+		{name: "get", method: http.MethodGet, path: proxyPath, token: testToken, expectedCode: http.StatusOK},
+		{name: "unknown proxy", method: http.MethodGet, path: "/proxies/unknown", token: testToken, expectedCode: http.StatusNotFound},
+		{name: "players", method: http.MethodGet, path: proxyPath + "/players", token: testToken, expectedCode: http.StatusOK},
+		{name: "kick unknown player", method: http.MethodDelete, path: proxyPath + "/players/Steve", token: testToken, expectedCode: http.StatusNotFound},
+		{name: "no process", method: http.MethodPost, path: proxyPath + "/process/start", token: testToken, expectedCode: http.StatusNotFound},
+		{name: "wrong method", method: http.MethodGet, path: proxyPath + "/process/pin", token: testToken, expectedCode: http.StatusMethodNotAllowed},
+		{name: "unregister", method: http.MethodDelete, path: proxyPath, token: testToken, expectedCode: http.StatusNoContent},
+		{name: "unregistered", method: http.MethodGet, path: proxyPath, token: testToken, expectedCode: http.StatusNotFound},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			if tc.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			if tc.header != "" {
+				r.Header.Set("Authorization", tc.header)
+			}
+			w := httptest.NewRecorder()
+
+			server.ServeHTTP(w, r)
+
+			if w.Code != tc.expectedCode {
+				t.Errorf("got: %v, want: %v; body: %s", w.Code, tc.expectedCode, w.Body)
+			}
+		})
+	}
+}
+
+func TestServer_ListProxies(t *testing.T) {
+	gateway := &infrared.Gateway{}
+	server := Server{Gateway: gateway, Token: testToken}
+
+	cfg, err := infrared.NewProxyConfigFromJSON([]byte(`{"domainNames": ["a.example.com", "b.example.com"], "listenTo": ":20672"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxy := &infrared.Proxy{Config: cfg}
+	if err := gateway.RegisterProxy(proxy); err != nil {
+		t.Fatal(err)
+	}
+	defer gateway.UnregisterProxy(proxy)
+
+	r := httptest.NewRequest(http.MethodGet, "/proxies", nil)
+	r.Header.Set("Authorization", "Bearer "+testToken)
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, r)
+
+	var proxies []proxyResponse
+	if err := json.NewDecoder(w.Body).Decode(&proxies); err != nil {
+		t.Fatal(err)
+	}
+
+	// A proxy with multiple UIDs is only listed once
+	if len(proxies) != 1 || len(proxies[0].UIDs) != 2 {
+		t.Errorf("got: %v, want: one proxy with two UIDs", proxies)
+	}
+
+	if proxies[0].Config != nil {
+		t.Error("configs should only be returned for a single proxy")
+	}
+}
+
+func TestServer_RedactsSecrets(t *testing.T) {
+	server := Server{Gateway: &infrared.Gateway{}, Token: testToken}
+	secrets := []string{"forwarding-secret", "portainer-password", "callback-token", "callback-secret", "callback-header"}
+	body := `{
+		"domainName": "secrets.example.com",
+		"listenTo": ":20673",
+		"forwarding": {"mode": "velocity", "secret": "forwarding-secret"},
+		"docker": {"portainer": {"password": "portainer-password"}},
+		"callbackServer": {"url": "http://localhost", "token": "callback-token"},
+		"callbackServers": [{"url": "http://localhost", "secret": "callback-secret", "headers": {"X-Api-Key": "callback-header"}}]
+	}`
+
+	tt := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+	}{
+		{name: "register", method: http.MethodPost, path: "/proxies", body: body},
+		{name: "get", method: http.MethodGet, path: "/proxies/" + url.PathEscape("secrets.example.com@:20673")},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			r.Header.Set("Authorization", "Bearer "+testToken)
+			w := httptest.NewRecorder()
+			server.ServeHTTP(w, r)
+
+			res := w.Body.String()
+			if !strings.Contains(res, redactedValue) {
+				t.Fatalf("got: %s, want: redacted config", res)
+			}
+			for _, secret := range secrets {
+				if strings.Contains(res, secret) {
+					t.Errorf("got: %s, want: no %q", res, secret)
+				}
+			}
+		})
+	}
+
+	r := httptest.NewRequest(http.MethodDelete, tt[1].path, nil)
+	r.Header.Set("Authorization", "Bearer "+testToken)
+	server.ServeHTTP(httptest.NewRecorder(), r)
+}
+
+func TestPathSegments(t *testing.T) {
+	tt := []struct {
+		path     string
+		segments []string
+	}{
+		{path: "/", segments: nil},
+		{path: "/proxies/", segments: []string{"proxies"}},
+		{path: "/proxies/mc.example.com@:25565", segments: []string{"proxies", "mc.example.com@:25565"}},
+		{path: "/proxies/a%2Fb/players", segments: []string{"proxies", "a/b", "players"}},
+	}
+
+	for _, tc := range tt {
+		segments, err := pathSegments(tc.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if strings.Join(segments, "|") != strings.Join(tc.segments, "|") || len(segments) != len(tc.segments) {
+			t.Errorf("got: %v, want: %v", segments, tc.segments)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/haveachin/infrared/callback"
+	"golang.org/x/net/websocket"
+)
+
+// keepAliveInterval is how often an idle Server-Sent Events stream
+// sends a comment, so that it isn't closed by proxies in between
+const keepAliveInterval = 15 * time.Second
+
+// handleEvents streams events as Server-Sent Events on /events
+// and as WebSocket messages on /events/ws
+func (server Server) handleEvents(w http.ResponseWriter, r *http.Request, segments []string) {
+	if server.Events == nil {
+		writeError(w, http.StatusNotFound, errors.New("event stream is disabled"))
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	filter := callback.StreamFilter{
+		Events:    queryList(r, "events"),
+		ProxyUIDs: queryList(r, "proxyUids"),
+	}
+
+	// Browsers send the Last-Event-ID header when they reconnect to a Server-Sent Events stream
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("lastEventId")
+	}
+
+	var lastID uint64
+	if lastEventID != "" {
+		var err error
+		lastID, err = strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errors.New("invalid last event id "+lastEventID))
+			return
+		}
+	}
+
+	switch {
+	case len(segments) == 0:
+		server.serveEventSource(w, r, filter, lastID)
+	case len(segments) == 1 && segments[0] == "ws":
+		server.serveEventWebSocket(w, r, filter, lastID)
+	default:
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+	}
+}
+
+func (server Server) serveEventSource(w http.ResponseWriter, r *http.Request, filter callback.StreamFilter, lastID uint64) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+
+	subscription := server.Events.Subscribe(filter, lastID)
+	defer subscription.Unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-subscription.Events():
+			if !ok {
+				// The subscriber was too slow and has to reconnect with the last event ID
+				return
+			}
+
+			bb, err := json.Marshal(event)
+			if err != nil {
+				return
+			}
+
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Event, bb); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+func (server Server) serveEventWebSocket(w http.ResponseWriter, r *http.Request, filter callback.StreamFilter, lastID uint64) {
+	websocketServer := websocket.Server{
+		// Browsers send the origin of the dashboard, but the API is already protected by the token
+		Handshake: func(*websocket.Config, *http.Request) error {
+			return nil
+		},
+		Handler: func(conn *websocket.Conn) {
+			defer conn.Close()
+
+			subscription := server.Events.Subscribe(filter, lastID)
+			defer subscription.Unsubscribe()
+
+			// Clients don't send anything, but reading notices when they disconnect
+			disconnected := make(chan struct{})
+			go func() {
+				_, _ = io.Copy(ioutil.Discard, conn)
+				close(disconnected)
+			}()
+
+			for {
+				select {
+				case <-disconnected:
+					return
+				case event, ok := <-subscription.Events():
+					if !ok {
+						return
+					}
+
+					if err := websocket.JSON.Send(conn, event); err != nil {
+						return
+					}
+				}
+			}
+		},
+	}
+	websocketServer.ServeHTTP(w, r)
+}
+
+// queryList reads a query parameter that is repeated or a comma separated list
+func queryList(r *http.Request, key string) []string {
+	var values []string
+	for _, value := range r.URL.Query()[key] {
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/haveachin/infrared"
+	"github.com/haveachin/infrared/callback"
+	"golang.org/x/net/websocket"
+)
+
+func newEventServer() (*httptest.Server, *callback.Stream) {
+	stream := callback.NewStream(8)
+	server := httptest.NewServer(Server{
+		Gateway: &infrared.Gateway{},
+		Token:   testToken,
+		Events:  stream,
+	})
+	return server, stream
+}
+
+// publishUntil publishes the event until done is closed,
+// since the subscription starts after the request is sent
+func publishUntil(stream *callback.Stream, event callback.Event, done chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case <-time.After(10 * time.Millisecond):
+			stream.Publish(event)
+		}
+	}
+}
+
+func TestServer_EventSource(t *testing.T) {
+	server, stream := newEventServer()
+	defer server.Close()
+
+	stream.Publish(callback.ErrorEvent{Error: "received", ProxyUID: "a@:25565"})
+	stream.Publish(callback.ErrorEvent{Error: "missed", ProxyUID: "a@:25565"})
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/events?events=PlayerJoin,Error&proxyUids=a@:25565", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	// A reconnecting client gets the events that it missed
+	req.Header.Set("Last-Event-ID", "1")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if contentType := res.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Fatalf("got: %v, want: %v", contentType, "text/event-stream")
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go publishUntil(stream, callback.PlayerJoinEvent{Username: "Steve", ProxyUID: "a@:25565"}, done)
+	stream.Publish(callback.PlayerJoinEvent{Username: "Alex", ProxyUID: "b@:25565"})
+
+	var lines []string
+	scanner := bufio.NewScanner(res.Body)
+	for len(lines) < 3 && scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	if len(lines) != 3 || lines[0] != "id: 2" || lines[1] != "event: Error" || !strings.Contains(lines[2], `"error":"missed"`) {
+		t.Errorf("got: %v, want: the replayed error event", lines)
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.Contains(line, "Alex") {
+			t.Fatal("events of other proxies should be filtered out")
+		}
+		if strings.Contains(line, "Steve") {
+			return
+		}
+	}
+	t.Error("the player join event was not streamed")
+}
+
+func TestServer_EventWebSocket(t *testing.T) {
+	server, stream := newEventServer()
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/events/ws?events=ContainerStart&token=" + testToken
+	conn, err := websocket.Dial(url, "", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+	go publishUntil(stream, callback.ContainerStopEvent{ProxyUID: "a@:25565"}, done)
+	go publishUntil(stream, callback.ContainerStartEvent{ProxyUID: "a@:25565"}, done)
+
+	var event struct {
+		ID      uint64 `json:"id"`
+		Event   string `json:"event"`
+		Payload struct {
+			ProxyUID string `json:"proxyUid"`
+		} `json:"payload"`
+	}
+	if err := conn.SetReadDeadline(time.Now().Add(3 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if err := websocket.JSON.Receive(conn, &event); err != nil {
+		t.Fatal(err)
+	}
+
+	if event.ID == 0 || event.Event != callback.EventTypeContainerStart || event.Payload.ProxyUID != "a@:25565" {
+		t.Errorf("got: %+v, want: a container start event", event)
+	}
+}
+
+func TestServer_EventsDisabled(t *testing.T) {
+	server := Server{Gateway: &infrared.Gateway{}, Token: testToken}
+	r := httptest.NewRequest(http.MethodGet, "/events?token="+testToken, nil)
+	w := httptest.NewRecorder()
+
+	server.ServeHTTP(w, r)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("got: %v, want: %v", w.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,207 @@
+package infrared
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/haveachin/infrared/protocol"
+	"github.com/haveachin/infrared/protocol/cfb8"
+	"github.com/haveachin/infrared/protocol/login"
+)
+
+// DefaultSessionServerURL is the endpoint of Mojang's session server
+// that verifies that a player has joined with a valid account
+const DefaultSessionServerURL = "https://sessionserver.mojang.com/session/minecraft/hasJoined"
+
+const unverifiedUsernameMessage = "{\"translate\":\"multiplayer.disconnect.unverified_username\"}"
+
+var (
+	ErrInvalidVerifyToken   = errors.New("invalid verify token")
+	ErrInvalidSharedSecret  = errors.New("invalid shared secret")
+	ErrUnverifiedUsername   = errors.New("failed to verify username")
+	sessionServerHTTPClient = &http.Client{Timeout: 10 * time.Second}
+)
+
+// GameProfile is the profile of a player as it is returned by the session server
+type GameProfile struct {
+	ID         string                `json:"id"`
+	Name       string                `json:"name"`
+	Properties []GameProfileProperty `json:"properties"`
+}
+
+type GameProfileProperty struct {
+	Name      string `json:"name"`
+	Value     string `json:"value"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// GenerateAuthenticationKey generates a new RSA key pair that can be used by an Authenticator
+func GenerateAuthenticationKey() (*rsa.PrivateKey, error) {
+	return rsa.GenerateKey(rand.Reader, 1024)
+}
+
+// Authenticator terminates the online-mode login of a client
+type Authenticator struct {
+	SessionServerURL string
+	PrivateKey       *rsa.PrivateKey
+}
+
+// Authenticate sends an Encryption Request to the client, turns on encryption on the Conn
+// and verifies the client's session against the session server.
+// It has to be called right after the Login Start packet was read from the Conn.
+func (auth Authenticator) Authenticate(conn Conn, protocolVersion protocol.VarInt, loginStart login.ServerLoginStart) (GameProfile, error) {
+	publicKey, err := x509.MarshalPKIXPublicKey(&auth.PrivateKey.PublicKey)
+	if err != nil {
+		return GameProfile{}, err
+	}
+
+	verifyToken := make([]byte, 4)
+	if _, err := rand.Read(verifyToken); err != nil {
+		return GameProfile{}, err
+	}
+
+	if err := conn.WritePacket(login.ClientBoundEncryptionRequest{
+		ServerID:           "",
+		PublicKey:          publicKey,
+		VerifyToken:        verifyToken,
+		ShouldAuthenticate: true,
+	}.Marshal(protocolVersion)); err != nil {
+		return GameProfile{}, err
+	}
+
+	pk, err := conn.ReadPacket()
+	if err != nil {
+		return GameProfile{}, err
+	}
+
+	encryptionResponse, err := login.UnmarshalServerBoundEncryptionResponse(pk, protocolVersion)
+	if err != nil {
+		return GameProfile{}, err
+	}
+
+	sharedSecret, err := rsa.DecryptPKCS1v15(rand.Reader, auth.PrivateKey, encryptionResponse.SharedSecret)
+	if err != nil {
+		return GameProfile{}, err
+	}
+
+	if len(sharedSecret) != 16 {
+		return GameProfile{}, ErrInvalidSharedSecret
+	}
+
+	if encryptionResponse.HasVerifyToken() {
+		decryptedToken, err := rsa.DecryptPKCS1v15(rand.Reader, auth.PrivateKey, encryptionResponse.VerifyToken)
+		if err != nil {
+			return GameProfile{}, err
+		}
+
+		if !bytes.Equal(decryptedToken, verifyToken) {
+			return GameProfile{}, ErrInvalidVerifyToken
+		}
+	} else if err := verifySaltSignature(loginStart.PublicKey, verifyToken, encryptionResponse.Salt, encryptionResponse.MessageSignature); err != nil {
+		return GameProfile{}, err
+	}
+
+	block, err := aes.NewCipher(sharedSecret)
+	if err != nil {
+		return GameProfile{}, err
+	}
+	conn.SetCipher(cfb8.NewEncrypter(block, sharedSecret), cfb8.NewDecrypter(block, sharedSecret))
+
+	profile, err := auth.hasJoined(string(loginStart.Name), serverHash("", sharedSecret, publicKey))
+	if err != nil {
+		_ = conn.WritePacket(login.ClientBoundDisconnect{
+			Reason: protocol.Chat(unverifiedUsernameMessage),
+		}.Marshal())
+		return GameProfile{}, err
+	}
+
+	return profile, nil
+}
+
+// verifySaltSignature checks the signature that clients from 1.19 up to 1.19.2 send instead of
+// the encrypted verify token. They sign the verify token followed by the salt with the chat key
+// from their Login Start packet. Clients without a chat key can't sign the verify token.
+func verifySaltSignature(publicKeyDER, verifyToken []byte, salt protocol.Long, signature []byte) error {
+	if len(publicKeyDER) == 0 {
+		return ErrInvalidVerifyToken
+	}
+
+	key, err := x509.ParsePKIXPublicKey(publicKeyDER)
+	if err != nil {
+		return ErrInvalidVerifyToken
+	}
+
+	publicKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return ErrInvalidVerifyToken
+	}
+
+	saltBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(saltBytes, uint64(salt))
+
+	h := sha256.New()
+	h.Write(verifyToken)
+	h.Write(saltBytes)
+	if err := rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, h.Sum(nil), signature); err != nil {
+		return ErrInvalidVerifyToken
+	}
+	return nil
+}
+
+func (auth Authenticator) hasJoined(username, serverHash string) (GameProfile, error) {
+	query := url.Values{}
+	query.Set("username", username)
+	query.Set("serverId", serverHash)
+
+	response, err := sessionServerHTTPClient.Get(fmt.Sprintf("%s?%s", auth.SessionServerURL, query.Encode()))
+	if err != nil {
+		return GameProfile{}, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNoContent {
+		return GameProfile{}, ErrUnverifiedUsername
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return GameProfile{}, fmt.Errorf("session server responded with %s", response.Status)
+	}
+
+	var profile GameProfile
+	if err := json.NewDecoder(response.Body).Decode(&profile); err != nil {
+		return GameProfile{}, err
+	}
+
+	return profile, nil
+}
+
+// serverHash computes Minecraft's non-standard SHA-1 hex digest. The digest is
+// interpreted as a signed two's complement number and printed without leading zeros.
+func serverHash(serverID string, sharedSecret, publicKey []byte) string {
+	h := sha1.New()
+	h.Write([]byte(serverID))
+	h.Write(sharedSecret)
+	h.Write(publicKey)
+	sum := h.Sum(nil)
+
+	n := new(big.Int).SetBytes(sum)
+	if sum[0]&0x80 != 0 {
+		n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(len(sum)*8)))
+	}
+
+	return n.Text(16)
+}
@@ -0,0 +1,248 @@
+package infrared
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/haveachin/infrared/protocol"
+	"github.com/haveachin/infrared/protocol/cfb8"
+	"github.com/haveachin/infrared/protocol/login"
+)
+
+func TestServerHash(t *testing.T) {
+	tt := []struct {
+		name     string
+		expected string
+	}{
+		{
+			name:     "Notch",
+			expected: "4ed1f46bbe04bc756bcb17c0c7ce3e4632f06a48",
+		},
+		{
+			name:     "jeb_",
+			expected: "-7c9d5b0044c130109a5d7b5fb5c317c02b4e28c1",
+		},
+		{
+			name:     "simon",
+			expected: "88e16a1019277b15d58faf0541e11910eb756f6",
+		},
+	}
+
+	for _, tc := range tt {
+		if hash := serverHash(tc.name, nil, nil); hash != tc.expected {
+			t.Errorf("%s: got: %s; want: %s", tc.name, hash, tc.expected)
+		}
+	}
+}
+
+// authClient plays the client side of an online-mode login. With a chat key,
+// it signs the verify token like 1.19 up to 1.19.2 clients instead of encrypting it.
+func authClient(conn Conn, protocolVersion protocol.VarInt, sharedSecret []byte, chatKey *rsa.PrivateKey) (string, error) {
+	pk, err := conn.ReadPacket()
+	if err != nil {
+		return "", err
+	}
+
+	request, err := login.UnmarshalClientBoundEncryptionRequest(pk, protocolVersion)
+	if err != nil {
+		return "", err
+	}
+
+	key, err := x509.ParsePKIXPublicKey(request.PublicKey)
+	if err != nil {
+		return "", err
+	}
+	publicKey := key.(*rsa.PublicKey)
+
+	encryptedSecret, err := rsa.EncryptPKCS1v15(rand.Reader, publicKey, sharedSecret)
+	if err != nil {
+		return "", err
+	}
+
+	response := login.ServerBoundEncryptionResponse{SharedSecret: encryptedSecret}
+	if chatKey != nil {
+		response.Salt = 42
+		h := sha256.New()
+		h.Write(request.VerifyToken)
+		h.Write([]byte{0, 0, 0, 0, 0, 0, 0, 42})
+		response.MessageSignature, err = rsa.SignPKCS1v15(rand.Reader, chatKey, crypto.SHA256, h.Sum(nil))
+	} else {
+		response.VerifyToken, err = rsa.EncryptPKCS1v15(rand.Reader, publicKey, request.VerifyToken)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if err := conn.WritePacket(response.Marshal(protocolVersion)); err != nil {
+		return "", err
+	}
+
+	block, err := aes.NewCipher(sharedSecret)
+	if err != nil {
+		return "", err
+	}
+	conn.SetCipher(cfb8.NewEncrypter(block, sharedSecret), cfb8.NewDecrypter(block, sharedSecret))
+
+	return serverHash(string(request.ServerID), sharedSecret, request.PublicKey), nil
+}
+
+func TestAuthenticator_Authenticate(t *testing.T) {
+	chatKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		name            string
+		protocolVersion protocol.VarInt
+		verified        bool
+		// signingKey signs the verify token instead of encrypting it
+		signingKey *rsa.PrivateKey
+		err        error
+	}{
+		{
+			name:            "Verified",
+			protocolVersion: 754,
+			verified:        true,
+		},
+		{
+			name:            "VerifiedWithShouldAuthenticate",
+			protocolVersion: 766,
+			verified:        true,
+		},
+		{
+			name:            "Unverified",
+			protocolVersion: 754,
+			verified:        false,
+			err:             ErrUnverifiedUsername,
+		},
+		{
+			name:            "SignedVerifyToken",
+			protocolVersion: 759,
+			verified:        true,
+			signingKey:      chatKey,
+		},
+		{
+			name:            "ForgedVerifyTokenSignature",
+			protocolVersion: 759,
+			verified:        true,
+			signingKey:      otherKey,
+			err:             ErrInvalidVerifyToken,
+		},
+	}
+
+	privateKey, err := GenerateAuthenticationKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chatPublicKey, err := x509.MarshalPKIXPublicKey(&chatKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			sharedSecret := make([]byte, 16)
+			if _, err := rand.Read(sharedSecret); err != nil {
+				t.Fatal(err)
+			}
+
+			hashCh := make(chan string, 1)
+			sessionServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !tc.verified || r.URL.Query().Get("serverId") != <-hashCh {
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+
+				_ = json.NewEncoder(w).Encode(GameProfile{
+					ID:   "069a79f444e94726a5befca90e38aaf5",
+					Name: r.URL.Query().Get("username"),
+				})
+			}))
+			defer sessionServer.Close()
+
+			c1, c2 := net.Pipe()
+			serverConn, clientConn := wrapConn(c1), wrapConn(c2)
+			defer serverConn.Close()
+			defer clientConn.Close()
+
+			errCh := make(chan error, 1)
+			go func() {
+				hash, err := authClient(clientConn, tc.protocolVersion, sharedSecret, tc.signingKey)
+				hashCh <- hash
+				if err != nil {
+					errCh <- err
+					return
+				}
+
+				// The server doesn't respond to forged signatures
+				if tc.err == ErrInvalidVerifyToken {
+					errCh <- nil
+					return
+				}
+
+				pk, err := clientConn.ReadPacket()
+				if err != nil {
+					errCh <- err
+					return
+				}
+
+				if tc.verified && pk.ID != 0x7f {
+					t.Errorf("packet ID: got: %v; want: %v", pk.ID, 0x7f)
+				}
+
+				if !tc.verified && pk.ID != login.ClientBoundDisconnectPacketID {
+					t.Errorf("packet ID: got: %v; want: %v", pk.ID, login.ClientBoundDisconnectPacketID)
+				}
+				errCh <- nil
+			}()
+
+			auth := Authenticator{
+				SessionServerURL: sessionServer.URL,
+				PrivateKey:       privateKey,
+			}
+			loginStart := login.ServerLoginStart{Name: "Notch"}
+			if tc.signingKey != nil {
+				loginStart.HasSignature = true
+				loginStart.PublicKey = chatPublicKey
+			}
+
+			profile, err := auth.Authenticate(serverConn, tc.protocolVersion, loginStart)
+			if tc.err == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if profile.Name != "Notch" {
+					t.Errorf("profile name: got: %s; want: %s", profile.Name, "Notch")
+				}
+
+				// Send an encrypted packet that the client should be able to read
+				if err := serverConn.WritePacket(protocol.Packet{ID: 0x7f}); err != nil {
+					t.Fatal(err)
+				}
+			} else if err != tc.err {
+				t.Errorf("got: %v; want: %v", err, tc.err)
+			}
+
+			if err := <-errCh; err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
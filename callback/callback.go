@@ -2,8 +2,11 @@ package callback
 
 import (
 	"bytes"
-	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"strconv"
 	"time"
 )
 
@@ -33,6 +36,28 @@ type Logger struct {
 
 	URL    string
 	Events []string
+	// Format of the request body. If empty, FormatJSON is used.
+	Format string
+	// Template of the request body. It is used instead of the format if it is not empty.
+	Template string
+	// ContentType of the request body. If empty, it depends on the format.
+	ContentType string
+	// Headers are added to every request
+	Headers map[string]string
+	// Token is sent as a bearer token if it is not empty
+	Token string
+	// Secret signs every request if it is not empty
+	Secret string
+}
+
+func (logger Logger) target() target {
+	return target{
+		URL:         logger.URL,
+		ContentType: logger.contentType(),
+		Headers:     logger.Headers,
+		Token:       logger.Token,
+		Secret:      logger.Secret,
+	}
 }
 
 func (logger Logger) isValid() bool {
@@ -67,20 +92,71 @@ func (logger Logger) LogEvent(event Event) (*EventLog, error) {
 	}
 
 	eventLog := newEventLog(event)
-	bb, err := json.Marshal(eventLog)
+	bb, err := logger.encode(eventLog)
 	if err != nil {
 		return nil, err
 	}
 
-	request, err := http.NewRequest(http.MethodPost, logger.URL, bytes.NewReader(bb))
-	if err != nil {
+	if err := post(logger.client, logger.target(), bb); err != nil {
 		return nil, err
 	}
 
-	_, err = logger.client.Do(request)
+	return &eventLog, nil
+}
+
+// StatusError is returned if the callback server doesn't respond with a 2xx status code
+type StatusError struct {
+	StatusCode int
+}
+
+func (err StatusError) Error() string {
+	return fmt.Sprintf("callback server responded with status code %d", err.StatusCode)
+}
+
+// target is a callback server and how requests to it are authenticated
+type target struct {
+	URL         string            `json:"url"`
+	ContentType string            `json:"contentType,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Token       string            `json:"token,omitempty"`
+	Secret      string            `json:"secret,omitempty"`
+}
+
+func post(client HTTPClient, target target, body []byte) error {
+	request, err := http.NewRequest(http.MethodPost, target.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	for key, value := range target.Headers {
+		request.Header.Set(key, value)
+	}
+	contentType := target.ContentType
+	if contentType == "" {
+		contentType = contentTypeJSON
+	}
+	request.Header.Set("Content-Type", contentType)
+
+	if target.Token != "" {
+		request.Header.Set("Authorization", "Bearer "+target.Token)
+	}
+
+	// Requests are signed when they are sent, so that retries are within the replay window
+	if target.Secret != "" {
+		timestamp := time.Now()
+		request.Header.Set(TimestampHeader, strconv.FormatInt(timestamp.Unix(), 10))
+		request.Header.Set(SignatureHeader, Sign(target.Secret, timestamp, body))
+	}
+
+	response, err := client.Do(request)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	defer response.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, response.Body)
 
-	return &eventLog, nil
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return StatusError{StatusCode: response.StatusCode}
+	}
+	return nil
 }
@@ -3,6 +3,7 @@ package callback
 import (
 	"bytes"
 	"encoding/json"
+	"io/ioutil"
 	"net/http"
 	"testing"
 )
@@ -101,7 +102,10 @@ func (mock *mockHTTPClient) Do(req *http.Request) (*http.Response, error) {
 		mock.Error(err)
 	}
 
-	return nil, nil
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(&bytes.Buffer{}),
+	}, nil
 }
 
 func TestLogger_LogEvent(t *testing.T) {
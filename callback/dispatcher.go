@@ -0,0 +1,336 @@
+package callback
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var ErrQueueFull = errors.New("callback queue is full")
+
+// DispatcherConfig configures how a Dispatcher delivers events
+type DispatcherConfig struct {
+	// SpoolPath is the directory in which every event is stored until it is delivered,
+	// so that undelivered events survive restarts. If empty, events are only kept in memory.
+	SpoolPath string
+	// QueueSize limits how many events of a callback URL wait for their delivery
+	QueueSize int
+	// MinBackoff is the delay before the first retry. It doubles with every retry up to MaxBackoff.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// MaxAttempts is how often the delivery of an event is attempted before it is dropped.
+	// If it is 0, the delivery is attempted until it succeeds.
+	MaxAttempts int
+	// Timeout limits how long the callback server can take to respond
+	Timeout time.Duration
+
+	// OnError is called when an attempt to deliver an event fails
+	OnError func(url, event string, err error)
+	// OnDrop is called when an event is dropped
+	OnDrop func(url, event string, err error)
+}
+
+func DefaultDispatcherConfig() DispatcherConfig {
+	return DispatcherConfig{
+		QueueSize:   1000,
+		MinBackoff:  time.Second,
+		MaxBackoff:  5 * time.Minute,
+		MaxAttempts: 10,
+		Timeout:     10 * time.Second,
+	}
+}
+
+// Dispatcher delivers events asynchronously with a queue for every callback server,
+// so that a callback server that is down doesn't delay the events of others
+type Dispatcher struct {
+	cfg     DispatcherConfig
+	client  HTTPClient
+	dropped uint64
+
+	mu     sync.Mutex
+	queues map[string]*queue
+}
+
+// NewDispatcher creates a Dispatcher and resumes the delivery of the events in the spool,
+// even for callback servers that are not used anymore
+func NewDispatcher(cfg DispatcherConfig) (*Dispatcher, error) {
+	dispatcher := &Dispatcher{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		queues: map[string]*queue{},
+	}
+
+	if cfg.SpoolPath == "" {
+		return dispatcher, nil
+	}
+
+	if err := os.MkdirAll(cfg.SpoolPath, 0755); err != nil {
+		return nil, err
+	}
+
+	dirs, err := ioutil.ReadDir(cfg.SpoolPath)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+
+		bb, err := ioutil.ReadFile(filepath.Join(cfg.SpoolPath, dir.Name(), spoolTargetFileName))
+		if err != nil {
+			continue
+		}
+
+		var t target
+		if err := json.Unmarshal(bb, &t); err != nil {
+			continue
+		}
+
+		if _, err := dispatcher.queue(t); err != nil {
+			return nil, err
+		}
+	}
+
+	return dispatcher, nil
+}
+
+// LogEvent queues the event for the callback server of the logger
+// if the logger holds a valid URL and accepts the event's type
+func (dispatcher *Dispatcher) LogEvent(logger Logger, event Event) error {
+	if !logger.isValid() || !logger.hasEvent(event) {
+		return nil
+	}
+
+	bb, err := logger.encode(newEventLog(event))
+	if err != nil {
+		dispatcher.drop(logger.URL, event.EventType(), err)
+		return err
+	}
+
+	q, err := dispatcher.queue(logger.target())
+	if err != nil {
+		return err
+	}
+
+	return q.push(queueItem{event: event.EventType(), body: bb})
+}
+
+// Dropped returns how many events were dropped
+func (dispatcher *Dispatcher) Dropped() uint64 {
+	return atomic.LoadUint64(&dispatcher.dropped)
+}
+
+// Close stops the delivery of events. Events in the spool are delivered after a restart.
+func (dispatcher *Dispatcher) Close() {
+	dispatcher.mu.Lock()
+	defer dispatcher.mu.Unlock()
+	for key, q := range dispatcher.queues {
+		q.close()
+		delete(dispatcher.queues, key)
+	}
+}
+
+// queue returns the queue of the target. Targets with the same URL, but different
+// headers or credentials get their own queues.
+func (dispatcher *Dispatcher) queue(t target) (*queue, error) {
+	bb, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	key := string(bb)
+
+	dispatcher.mu.Lock()
+	defer dispatcher.mu.Unlock()
+	if q, ok := dispatcher.queues[key]; ok {
+		return q, nil
+	}
+
+	var store queueStore = &memoryStore{size: dispatcher.cfg.QueueSize}
+	if dispatcher.cfg.SpoolPath != "" {
+		dir := filepath.Join(dispatcher.cfg.SpoolPath, spoolDirName(key))
+		spool, err := newSpoolStore(dir, bb, dispatcher.cfg.QueueSize)
+		if err != nil {
+			return nil, err
+		}
+		store = spool
+	}
+
+	q := &queue{
+		target:     t,
+		dispatcher: dispatcher,
+		store:      store,
+		wake:       make(chan struct{}, 1),
+		done:       make(chan struct{}),
+		stopped:    make(chan struct{}),
+	}
+	dispatcher.queues[key] = q
+	go q.run()
+	return q, nil
+}
+
+func (dispatcher *Dispatcher) onError(url, event string, err error) {
+	if dispatcher.cfg.OnError != nil {
+		dispatcher.cfg.OnError(url, event, err)
+	}
+}
+
+func (dispatcher *Dispatcher) drop(url, event string, err error) {
+	atomic.AddUint64(&dispatcher.dropped, 1)
+	if dispatcher.cfg.OnDrop != nil {
+		dispatcher.cfg.OnDrop(url, event, err)
+	}
+}
+
+type queueItem struct {
+	event string
+	body  []byte
+}
+
+// queue delivers the events of a single target in order
+type queue struct {
+	target     target
+	dispatcher *Dispatcher
+	store      queueStore
+	wake       chan struct{}
+	done       chan struct{}
+	stopped    chan struct{}
+}
+
+func (q *queue) push(item queueItem) error {
+	if err := q.store.push(item); err != nil {
+		q.dispatcher.drop(q.target.URL, item.event, err)
+		return err
+	}
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (q *queue) run() {
+	defer close(q.stopped)
+
+	for {
+		item, ok, err := q.store.peek()
+		if err != nil {
+			q.dispatcher.drop(q.target.URL, item.event, err)
+			_ = q.store.pop()
+			continue
+		}
+
+		if !ok {
+			select {
+			case <-q.wake:
+				continue
+			case <-q.done:
+				return
+			}
+		}
+
+		if !q.deliver(item) {
+			return
+		}
+		_ = q.store.pop()
+	}
+}
+
+// deliver posts the item until it succeeds or the attempts run out.
+// It returns false if the queue was closed before.
+func (q *queue) deliver(item queueItem) bool {
+	cfg := q.dispatcher.cfg
+	backoff := cfg.MinBackoff
+
+	for attempt := 1; ; attempt++ {
+		err := post(q.dispatcher.client, q.target, item.body)
+		if err == nil {
+			return true
+		}
+		q.dispatcher.onError(q.target.URL, item.event, err)
+
+		if cfg.MaxAttempts > 0 && attempt >= cfg.MaxAttempts {
+			q.dispatcher.drop(q.target.URL, item.event, err)
+			return true
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-q.done:
+			return false
+		}
+
+		backoff *= 2
+		if backoff > cfg.MaxBackoff {
+			backoff = cfg.MaxBackoff
+		}
+	}
+}
+
+func (q *queue) close() {
+	close(q.done)
+	<-q.stopped
+}
+
+// queueStore holds the events of a queue that wait for their delivery
+type queueStore interface {
+	push(item queueItem) error
+	// peek returns the next event without removing it
+	peek() (queueItem, bool, error)
+	pop() error
+}
+
+type memoryStore struct {
+	mu    sync.Mutex
+	size  int
+	items []queueItem
+}
+
+func (store *memoryStore) push(item queueItem) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if len(store.items) >= store.size {
+		return ErrQueueFull
+	}
+	store.items = append(store.items, item)
+	return nil
+}
+
+func (store *memoryStore) peek() (queueItem, bool, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if len(store.items) == 0 {
+		return queueItem{}, false, nil
+	}
+	return store.items[0], true, nil
+}
+
+func (store *memoryStore) pop() error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if len(store.items) > 0 {
+		store.items = store.items[1:]
+	}
+	return nil
+}
+
+// spoolTargetFileName is the file in the spool directory of a target that holds the target.
+// It contains the credentials of the target, so only the owner can read it.
+const spoolTargetFileName = "target.json"
+
+// spoolDirName derives the spool directory of a target from its hash,
+// since URLs can contain characters that are not allowed in file names
+func spoolDirName(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:8])
+}
@@ -0,0 +1,186 @@
+package callback
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// callbackServer responds with the status code and sends every received body
+func callbackServer(statusCode *int32) (*httptest.Server, chan []byte) {
+	bodies := make(chan []byte, 16)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bb, _ := ioutil.ReadAll(r.Body)
+		code := int(atomic.LoadInt32(statusCode))
+		if code == http.StatusOK {
+			bodies <- bb
+		}
+		w.WriteHeader(code)
+	}))
+	return server, bodies
+}
+
+func testDispatcherConfig() DispatcherConfig {
+	cfg := DefaultDispatcherConfig()
+	cfg.MinBackoff = time.Millisecond
+	cfg.MaxBackoff = 5 * time.Millisecond
+	return cfg
+}
+
+func receiveBody(t *testing.T, bodies chan []byte) []byte {
+	select {
+	case bb := <-bodies:
+		return bb
+	case <-time.After(3 * time.Second):
+		t.Fatal("event was not delivered")
+		return nil
+	}
+}
+
+func TestDispatcher_Retry(t *testing.T) {
+	statusCode := int32(http.StatusServiceUnavailable)
+	server, bodies := callbackServer(&statusCode)
+	defer server.Close()
+
+	failures := int32(0)
+	cfg := testDispatcherConfig()
+	cfg.OnError = func(url, event string, err error) {
+		// The callback server recovers after two failed attempts
+		if atomic.AddInt32(&failures, 1) == 2 {
+			atomic.StoreInt32(&statusCode, http.StatusOK)
+		}
+	}
+
+	dispatcher, err := NewDispatcher(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dispatcher.Close()
+
+	logger := Logger{URL: server.URL, Events: []string{EventTypeError}}
+	if err := dispatcher.LogEvent(logger, ErrorEvent{Error: "retried"}); err != nil {
+		t.Fatal(err)
+	}
+
+	receiveBody(t, bodies)
+	if atomic.LoadInt32(&failures) != 2 {
+		t.Errorf("got: %v, want: %v", failures, 2)
+	}
+}
+
+func TestDispatcher_Drop(t *testing.T) {
+	statusCode := int32(http.StatusInternalServerError)
+	server, _ := callbackServer(&statusCode)
+	defer server.Close()
+
+	dropped := make(chan error, 4)
+	cfg := testDispatcherConfig()
+	cfg.QueueSize = 1
+	cfg.MaxAttempts = 2
+	cfg.MinBackoff = 50 * time.Millisecond
+	cfg.OnDrop = func(url, event string, err error) {
+		dropped <- err
+	}
+
+	dispatcher, err := NewDispatcher(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dispatcher.Close()
+
+	logger := Logger{URL: server.URL, Events: []string{EventTypeError}}
+	if err := dispatcher.LogEvent(logger, ErrorEvent{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first event waits for its retry, so there is no space for the second one
+	if err := dispatcher.LogEvent(logger, ErrorEvent{}); err != ErrQueueFull {
+		t.Errorf("got: %v, want: %v", err, ErrQueueFull)
+	}
+
+	// The first event is dropped after its attempts run out
+	for _, expectedErr := range []error{ErrQueueFull, StatusError{StatusCode: http.StatusInternalServerError}} {
+		select {
+		case err := <-dropped:
+			if err != expectedErr {
+				t.Errorf("got: %v, want: %v", err, expectedErr)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatal("event was not dropped")
+		}
+	}
+
+	if dispatcher.Dropped() != 2 {
+		t.Errorf("got: %v, want: %v", dispatcher.Dropped(), 2)
+	}
+}
+
+func TestDispatcher_Spool(t *testing.T) {
+	statusCode := int32(http.StatusServiceUnavailable)
+	server, bodies := callbackServer(&statusCode)
+	defer server.Close()
+
+	spoolPath, err := ioutil.TempDir("", "infrared-spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(spoolPath)
+
+	cfg := testDispatcherConfig()
+	cfg.SpoolPath = spoolPath
+	cfg.MaxAttempts = 0
+	cfg.MinBackoff = time.Hour
+
+	dispatcher, err := NewDispatcher(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := Logger{URL: server.URL, Events: []string{EventTypePlayerJoin}}
+	if err := dispatcher.LogEvent(logger, PlayerJoinEvent{Username: "Steve"}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	dispatcher.Close()
+
+	key, _ := json.Marshal(logger.target())
+	spoolDir := filepath.Join(spoolPath, spoolDirName(string(key)))
+	files, _ := filepath.Glob(filepath.Join(spoolDir, "[0-9]*"+spoolFileExt))
+	if len(files) != 1 {
+		t.Fatalf("got: %v, want: one spooled event", files)
+	}
+
+	bb, err := ioutil.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var expected spoolItem
+	if err := json.Unmarshal(bb, &expected); err != nil {
+		t.Fatal(err)
+	}
+
+	// The restarted dispatcher delivers the spooled event without a new event for the URL
+	atomic.StoreInt32(&statusCode, http.StatusOK)
+	dispatcher, err = NewDispatcher(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dispatcher.Close()
+
+	bb = receiveBody(t, bodies)
+	if string(bb) != expected.Body {
+		t.Errorf("got: %s, want: %s", bb, expected.Body)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if _, err := os.Stat(files[0]); !os.IsNotExist(err) {
+		t.Error("delivered events should be removed from the spool")
+	}
+}
@@ -6,6 +6,7 @@ const (
 	EventTypePlayerLeave    string = "PlayerLeave"
 	EventTypeContainerStart string = "ContainerStart"
 	EventTypeContainerStop  string = "ContainerStop"
+	EventTypeUnknownHost    string = "UnknownHost"
 )
 
 type Event interface {
@@ -23,6 +24,7 @@ func (event ErrorEvent) EventType() string {
 
 type PlayerJoinEvent struct {
 	Username      string `json:"username"`
+	UUID          string `json:"uuid,omitempty"`
 	RemoteAddress string `json:"remoteAddress"`
 	TargetAddress string `json:"targetAddress"`
 	ProxyUID      string `json:"proxyUid"`
@@ -34,6 +36,7 @@ func (event PlayerJoinEvent) EventType() string {
 
 type PlayerLeaveEvent struct {
 	Username      string `json:"username"`
+	UUID          string `json:"uuid,omitempty"`
 	RemoteAddress string `json:"remoteAddress"`
 	TargetAddress string `json:"targetAddress"`
 	ProxyUID      string `json:"proxyUid"`
@@ -58,3 +61,16 @@ type ContainerStopEvent struct {
 func (event ContainerStopEvent) EventType() string {
 	return EventTypeContainerStop
 }
+
+// UnknownHostEvent is logged by the default proxy of a listener
+// when it serves a client that requested an unknown domain
+type UnknownHostEvent struct {
+	RequestedDomain string `json:"requestedDomain"`
+	RemoteAddress   string `json:"remoteAddress"`
+	ListenTo        string `json:"listenTo"`
+	ProxyUID        string `json:"proxyUid"`
+}
+
+func (event UnknownHostEvent) EventType() string {
+	return EventTypeUnknownHost
+}
@@ -27,6 +27,10 @@ func TestErrorEvent_EventType(t *testing.T) {
 			event:     ContainerStopEvent{},
 			eventType: EventTypeContainerStop,
 		},
+		{
+			event:     UnknownHostEvent{},
+			eventType: EventTypeUnknownHost,
+		},
 	}
 
 	for _, tc := range tt {
@@ -0,0 +1,192 @@
+package callback
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fileSinkBackupLayout is the suffix of rotated files. It sorts in the order of the rotations.
+const fileSinkBackupLayout = "20060102T150405.000"
+
+var ErrMissingSinkPath = errors.New("file sink has no path")
+
+// FileSink writes every event as a line of JSON to a file. The file is rotated when it
+// gets too large or too old by renaming it to its path with the time of the rotation,
+// e.g. "events.log.20201018T120000.000". If the rotation fails, the event is still
+// written to the current file and the error of the rotation is returned.
+type FileSink struct {
+	path       string
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+	now        func() time.Time
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	closed   bool
+}
+
+// NewFileSink opens or creates the file at path. It is not rotated by size if maxSize is 0,
+// not rotated by age if maxAge is 0 and all rotated files are kept if maxBackups is 0.
+func NewFileSink(path string, maxSize int64, maxAge time.Duration, maxBackups int) (*FileSink, error) {
+	if path == "" {
+		return nil, ErrMissingSinkPath
+	}
+
+	sink := &FileSink{
+		path:       path,
+		maxSize:    maxSize,
+		maxAge:     maxAge,
+		maxBackups: maxBackups,
+		now:        time.Now,
+	}
+
+	if err := sink.open(); err != nil {
+		return nil, err
+	}
+	return sink, nil
+}
+
+func (sink *FileSink) WriteEvent(eventLog EventLog) error {
+	bb, err := json.Marshal(eventLog)
+	if err != nil {
+		return err
+	}
+	bb = append(bb, '\n')
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if sink.closed {
+		return os.ErrClosed
+	}
+
+	// The file might not have been opened again after a failed rotation
+	if sink.file == nil {
+		if err := sink.open(); err != nil {
+			return err
+		}
+	}
+
+	var rotateErr error
+	if sink.shouldRotate(len(bb)) {
+		rotateErr = sink.rotate()
+		if sink.file == nil {
+			return rotateErr
+		}
+	}
+
+	n, err := sink.file.Write(bb)
+	sink.size += int64(n)
+	if err != nil {
+		return err
+	}
+	return rotateErr
+}
+
+func (sink *FileSink) Close() error {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	sink.closed = true
+	if sink.file == nil {
+		return nil
+	}
+
+	err := sink.file.Close()
+	sink.file = nil
+	return err
+}
+
+func (sink *FileSink) open() error {
+	if err := os.MkdirAll(filepath.Dir(sink.path), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(sink.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	sink.file = file
+	sink.size = info.Size()
+	sink.openedAt = sink.now()
+	return nil
+}
+
+// shouldRotate checks if the file has to be rotated before n bytes are written.
+// Empty files are never rotated.
+func (sink *FileSink) shouldRotate(n int) bool {
+	if sink.size == 0 {
+		return false
+	}
+
+	if sink.maxSize > 0 && sink.size+int64(n) > sink.maxSize {
+		return true
+	}
+
+	return sink.maxAge > 0 && sink.now().Sub(sink.openedAt) >= sink.maxAge
+}
+
+// rotate renames the file and opens a new one at its path. If the file can't be renamed,
+// the path is opened again, so that the events are still appended to the current file.
+func (sink *FileSink) rotate() error {
+	err := sink.file.Close()
+	sink.file = nil
+	if err == nil {
+		backupPath := sink.path + "." + sink.now().UTC().Format(fileSinkBackupLayout)
+		err = os.Rename(sink.path, backupPath)
+	}
+
+	if openErr := sink.open(); openErr != nil {
+		return openErr
+	}
+
+	if err != nil {
+		return err
+	}
+	return sink.removeOldBackups()
+}
+
+func (sink *FileSink) removeOldBackups() error {
+	if sink.maxBackups <= 0 {
+		return nil
+	}
+
+	paths, err := filepath.Glob(sink.path + ".*")
+	if err != nil {
+		return err
+	}
+
+	var backups []string
+	for _, path := range paths {
+		suffix := strings.TrimPrefix(path, sink.path+".")
+		if _, err := time.Parse(fileSinkBackupLayout, suffix); err == nil {
+			backups = append(backups, path)
+		}
+	}
+
+	if len(backups) <= sink.maxBackups {
+		return nil
+	}
+
+	sort.Strings(backups)
+	for _, backup := range backups[:len(backups)-sink.maxBackups] {
+		if err := os.Remove(backup); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package callback
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const (
+	// FormatJSON sends the EventLog as JSON
+	FormatJSON = "json"
+	// FormatCloudEvents sends the event as a CloudEvent in the structured JSON mode
+	FormatCloudEvents = "cloudevents"
+)
+
+var ErrUnknownFormat = errors.New("unknown callback format")
+
+const (
+	contentTypeJSON        = "application/json"
+	contentTypeCloudEvents = "application/cloudevents+json"
+
+	cloudEventsSpecVersion = "1.0"
+	cloudEventsTypePrefix  = "io.github.haveachin.infrared."
+)
+
+// cloudEvent is a CloudEvent as specified by https://github.com/cloudevents/spec
+type cloudEvent struct {
+	SpecVersion     string      `json:"specversion"`
+	ID              string      `json:"id"`
+	Source          string      `json:"source"`
+	Type            string      `json:"type"`
+	Time            time.Time   `json:"time"`
+	DataContentType string      `json:"datacontenttype"`
+	Data            interface{} `json:"data"`
+}
+
+func newCloudEvent(eventLog EventLog) (cloudEvent, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return cloudEvent{}, err
+	}
+
+	source := "infrared"
+	if event, ok := eventLog.Payload.(Event); ok {
+		if proxyUID := eventProxyUID(event); proxyUID != "" {
+			source = "/proxies/" + proxyUID
+		}
+	}
+
+	return cloudEvent{
+		SpecVersion:     cloudEventsSpecVersion,
+		ID:              hex.EncodeToString(id),
+		Source:          source,
+		Type:            cloudEventsTypePrefix + eventLog.Event,
+		Time:            eventLog.Timestamp,
+		DataContentType: contentTypeJSON,
+		Data:            eventLog.Payload,
+	}, nil
+}
+
+// encode returns the body of the event log. The template of the logger
+// is used if it has one, otherwise its format.
+func (logger Logger) encode(eventLog EventLog) ([]byte, error) {
+	if logger.Template != "" {
+		return render(logger.Template, eventLog)
+	}
+
+	switch logger.Format {
+	case "", FormatJSON:
+		return json.Marshal(eventLog)
+	case FormatCloudEvents:
+		event, err := newCloudEvent(eventLog)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(event)
+	case FormatDiscord, FormatSlack:
+		return render(presetTemplates[logger.Format], eventLog)
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+// contentType returns the content type of the bodies of the logger
+func (logger Logger) contentType() string {
+	if logger.ContentType != "" {
+		return logger.ContentType
+	}
+
+	if logger.Template == "" && logger.Format == FormatCloudEvents {
+		return contentTypeCloudEvents
+	}
+	return contentTypeJSON
+}
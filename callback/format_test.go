@@ -0,0 +1,65 @@
+package callback
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestEncode(t *testing.T) {
+	eventLog := EventLog{
+		Event:     EventTypePlayerJoin,
+		Timestamp: time.Unix(1600000000, 0).UTC(),
+		Payload:   PlayerJoinEvent{Username: "Steve", ProxyUID: "a@:25565"},
+	}
+
+	tt := []struct {
+		format string
+		want   map[string]interface{}
+		err    error
+	}{
+		{
+			format: "",
+			want: map[string]interface{}{
+				"event":     EventTypePlayerJoin,
+				"timestamp": "2020-09-13T12:26:40Z",
+			},
+		},
+		{
+			format: FormatCloudEvents,
+			want: map[string]interface{}{
+				"specversion":     "1.0",
+				"source":          "/proxies/a@:25565",
+				"type":            "io.github.haveachin.infrared.PlayerJoin",
+				"time":            "2020-09-13T12:26:40Z",
+				"datacontenttype": "application/json",
+			},
+		},
+		{
+			format: "xml",
+			err:    ErrUnknownFormat,
+		},
+	}
+
+	for _, tc := range tt {
+		bb, err := Logger{Format: tc.format}.encode(eventLog)
+		if err != tc.err {
+			t.Errorf("%s: got: %v, want: %v", tc.format, err, tc.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		var got map[string]interface{}
+		if err := json.Unmarshal(bb, &got); err != nil {
+			t.Fatal(err)
+		}
+
+		for key, value := range tc.want {
+			if got[key] != value {
+				t.Errorf("%s: %s: got: %v, want: %v", tc.format, key, got[key], value)
+			}
+		}
+	}
+}
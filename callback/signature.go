@@ -0,0 +1,134 @@
+package callback
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// SignatureHeader holds the HMAC-SHA256 signature of the timestamp and the body
+	SignatureHeader = "X-Infrared-Signature"
+	// TimestampHeader holds the time of the request in seconds since the Unix epoch
+	TimestampHeader = "X-Infrared-Timestamp"
+
+	signaturePrefix = "sha256="
+)
+
+// DefaultTolerance is how far the timestamp of a request can be off by default
+const DefaultTolerance = 5 * time.Minute
+
+var (
+	ErrMissingSignature = errors.New("missing signature")
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrInvalidTimestamp = errors.New("invalid timestamp")
+	ErrExpiredTimestamp = errors.New("timestamp is outside of the replay window")
+	ErrReplayedRequest  = errors.New("request was replayed")
+)
+
+// Sign returns the signature of a request at the timestamp with the body.
+// It is the hex encoded HMAC-SHA256 of "<timestamp>.<body>" with a "sha256=" prefix.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verifier checks the signatures of callback requests for services that receive events.
+// Requests are rejected if their timestamp is outside of the replay window or if the same
+// signature was seen before within the window.
+type Verifier struct {
+	secret    string
+	tolerance time.Duration
+	now       func() time.Time
+
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+// NewVerifier creates a Verifier with the secret of the callback server.
+// If tolerance is 0, the DefaultTolerance is used.
+func NewVerifier(secret string, tolerance time.Duration) *Verifier {
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+
+	return &Verifier{
+		secret:    secret,
+		tolerance: tolerance,
+		now:       time.Now,
+		seen:      map[string]time.Time{},
+	}
+}
+
+// Verify checks the signature in the header against the body
+func (verifier *Verifier) Verify(header http.Header, body []byte) error {
+	signature := header.Get(SignatureHeader)
+	if signature == "" {
+		return ErrMissingSignature
+	}
+
+	unix, err := strconv.ParseInt(header.Get(TimestampHeader), 10, 64)
+	if err != nil {
+		return ErrInvalidTimestamp
+	}
+	timestamp := time.Unix(unix, 0)
+
+	now := verifier.now()
+	if timestamp.Before(now.Add(-verifier.tolerance)) || timestamp.After(now.Add(verifier.tolerance)) {
+		return ErrExpiredTimestamp
+	}
+
+	expected := Sign(verifier.secret, timestamp, body)
+	if !strings.HasPrefix(signature, signaturePrefix) || !hmac.Equal([]byte(signature), []byte(expected)) {
+		return ErrInvalidSignature
+	}
+
+	verifier.mu.Lock()
+	defer verifier.mu.Unlock()
+	for s, expiresAt := range verifier.seen {
+		if now.After(expiresAt) {
+			delete(verifier.seen, s)
+		}
+	}
+
+	if _, ok := verifier.seen[signature]; ok {
+		return ErrReplayedRequest
+	}
+	verifier.seen[signature] = timestamp.Add(verifier.tolerance)
+	return nil
+}
+
+// VerifyRequest reads the body of the request and checks its signature.
+// The body can be read again afterwards.
+func (verifier *Verifier) VerifyRequest(r *http.Request) ([]byte, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	return body, verifier.Verify(r.Header, body)
+}
+
+// Handler responds with 401 Unauthorized to requests without a valid signature
+// and passes the other requests on to next
+func (verifier *Verifier) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := verifier.VerifyRequest(r); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,124 @@
+package callback
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func signedHeader(secret string, timestamp time.Time, body []byte) http.Header {
+	header := http.Header{}
+	header.Set(TimestampHeader, strconv.FormatInt(timestamp.Unix(), 10))
+	header.Set(SignatureHeader, Sign(secret, timestamp, body))
+	return header
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	body := []byte(`{"event":"Error"}`)
+
+	tt := []struct {
+		name   string
+		header http.Header
+		body   []byte
+		err    error
+	}{
+		{
+			name:   "Valid",
+			header: signedHeader("secret", now, body),
+			body:   body,
+		},
+		{
+			name:   "TamperedBody",
+			header: signedHeader("secret", now, body),
+			body:   []byte(`{"event":"PlayerJoin"}`),
+			err:    ErrInvalidSignature,
+		},
+		{
+			name:   "WrongSecret",
+			header: signedHeader("other", now, body),
+			body:   body,
+			err:    ErrInvalidSignature,
+		},
+		{
+			name:   "ExpiredTimestamp",
+			header: signedHeader("secret", now.Add(-DefaultTolerance-time.Second), body),
+			body:   body,
+			err:    ErrExpiredTimestamp,
+		},
+		{
+			name:   "MissingSignature",
+			header: http.Header{TimestampHeader: []string{strconv.FormatInt(now.Unix(), 10)}},
+			body:   body,
+			err:    ErrMissingSignature,
+		},
+		{
+			name:   "InvalidTimestamp",
+			header: http.Header{SignatureHeader: []string{Sign("secret", now, body)}},
+			body:   body,
+			err:    ErrInvalidTimestamp,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			verifier := NewVerifier("secret", 0)
+			verifier.now = func() time.Time { return now }
+
+			if err := verifier.Verify(tc.header, tc.body); err != tc.err {
+				t.Errorf("got: %v, want: %v", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestVerifier_Replay(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	body := []byte(`{"event":"Error"}`)
+	header := signedHeader("secret", now, body)
+
+	verifier := NewVerifier("secret", time.Minute)
+	verifier.now = func() time.Time { return now }
+
+	if err := verifier.Verify(header, body); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := verifier.Verify(header, body); err != ErrReplayedRequest {
+		t.Errorf("got: %v, want: %v", err, ErrReplayedRequest)
+	}
+}
+
+func TestPost_Authentication(t *testing.T) {
+	verifier := NewVerifier("secret", 0)
+	requests := make(chan *http.Request, 1)
+	server := httptest.NewServer(verifier.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- r
+	})))
+	defer server.Close()
+
+	target := target{
+		URL:     server.URL,
+		Headers: map[string]string{"X-Custom": "value"},
+		Token:   "token",
+		Secret:  "secret",
+	}
+	if err := post(http.DefaultClient, target, []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	r := <-requests
+	if r.Header.Get("Authorization") != "Bearer token" {
+		t.Errorf("got: %v, want: %v", r.Header.Get("Authorization"), "Bearer token")
+	}
+	if r.Header.Get("X-Custom") != "value" {
+		t.Errorf("got: %v, want: %v", r.Header.Get("X-Custom"), "value")
+	}
+
+	target.Secret = "wrong"
+	if err := post(http.DefaultClient, target, []byte(`{}`)); err != (StatusError{StatusCode: http.StatusUnauthorized}) {
+		t.Errorf("got: %v, want: %v", err, StatusError{StatusCode: http.StatusUnauthorized})
+	}
+}
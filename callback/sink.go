@@ -0,0 +1,318 @@
+package callback
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	SinkTypeStdout = "stdout"
+	SinkTypeFile   = "file"
+	SinkTypeSyslog = "syslog"
+)
+
+var (
+	ErrUnknownSinkType = errors.New("unknown sink type")
+	ErrSinkQueueFull   = errors.New("sink queue is full")
+)
+
+// Sink writes events to a destination other than a callback server.
+// Sinks are used by multiple proxies at once, so they have to be safe for concurrent use.
+type Sink interface {
+	WriteEvent(eventLog EventLog) error
+	Close() error
+}
+
+// SinkConfig configures a Sink and the events that it receives
+type SinkConfig struct {
+	Type   string   `json:"type"`
+	Events []string `json:"events"`
+
+	// Path is the file of a file sink
+	Path string `json:"path,omitempty"`
+	// MaxSize is the size in bytes after which a file is rotated. It is not rotated by size if 0.
+	MaxSize int64 `json:"maxSize,omitempty"`
+	// MaxAge is the time in milliseconds after which a file is rotated. It is not rotated by age if 0.
+	MaxAge int `json:"maxAge,omitempty"`
+	// MaxBackups is how many rotated files are kept. All of them are kept if 0.
+	MaxBackups int `json:"maxBackups,omitempty"`
+
+	// Network of the syslog server is "udp", "tcp", "unix" or "unixgram"
+	Network string `json:"network,omitempty"`
+	// Address of the syslog server
+	Address string `json:"address,omitempty"`
+	// Facility of the syslog messages, e.g. "local0". If empty, "user" is used.
+	Facility string `json:"facility,omitempty"`
+	// AppName of the syslog messages. If empty, "infrared" is used.
+	AppName string `json:"appName,omitempty"`
+}
+
+func (cfg SinkConfig) hasEvent(event Event) bool {
+	for _, e := range cfg.Events {
+		if e == event.EventType() {
+			return true
+		}
+	}
+	return false
+}
+
+// NewSink creates the Sink of the config
+func NewSink(cfg SinkConfig) (Sink, error) {
+	var sink Sink
+	var err error
+	switch cfg.Type {
+	case SinkTypeStdout:
+		sink = NewWriterSink(os.Stdout)
+	case SinkTypeFile:
+		sink, err = NewFileSink(cfg.Path, cfg.MaxSize, time.Duration(cfg.MaxAge)*time.Millisecond, cfg.MaxBackups)
+	case SinkTypeSyslog:
+		sink, err = NewSyslogSink(cfg.Network, cfg.Address, cfg.Facility, cfg.AppName)
+	default:
+		err = ErrUnknownSinkType
+	}
+
+	// A typed nil pointer would not be a nil Sink
+	if err != nil {
+		return nil, err
+	}
+	return sink, nil
+}
+
+// WriterSink writes every event as a line of JSON
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+func (sink *WriterSink) WriteEvent(eventLog EventLog) error {
+	bb, err := json.Marshal(eventLog)
+	if err != nil {
+		return err
+	}
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	_, err = sink.w.Write(append(bb, '\n'))
+	return err
+}
+
+// Close does nothing, since the writer is owned by the caller
+func (sink *WriterSink) Close() error {
+	return nil
+}
+
+// SinkPoolConfig configures how a SinkPool writes events
+type SinkPoolConfig struct {
+	// QueueSize limits how many events wait to be written to a sink
+	QueueSize int
+
+	// OnError is called when an event could not be written to a sink
+	OnError func(sinkType, event string, err error)
+	// OnDrop is called when an event is dropped, since the queue of its sink is full
+	OnDrop func(sinkType, event string, err error)
+}
+
+func DefaultSinkPoolConfig() SinkPoolConfig {
+	return SinkPoolConfig{
+		QueueSize: 1000,
+	}
+}
+
+// SinkPool creates a Sink for every distinct SinkConfig, so that proxies
+// with the same config share the sink instead of opening the same file twice.
+// Every sink has its own queue, so that a slow sink doesn't delay the connections
+// that log events or the events of other sinks.
+type SinkPool struct {
+	cfg SinkPoolConfig
+
+	mu     sync.Mutex
+	queues map[string]*sinkQueue
+}
+
+func NewSinkPool(cfg SinkPoolConfig) *SinkPool {
+	return &SinkPool{
+		cfg:    cfg,
+		queues: map[string]*sinkQueue{},
+	}
+}
+
+// LogEvent queues the event for the sink of the config if the config accepts the event's type.
+// Failed and dropped events are also reported to OnError and OnDrop.
+func (pool *SinkPool) LogEvent(cfg SinkConfig, event Event) error {
+	if !cfg.hasEvent(event) {
+		return nil
+	}
+
+	q, err := pool.queue(cfg)
+	if err != nil {
+		pool.onError(cfg.Type, event.EventType(), err)
+		return err
+	}
+
+	return q.push(newEventLog(event))
+}
+
+// Close writes the queued events and closes all sinks of the pool
+func (pool *SinkPool) Close() error {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	var closeErr error
+	for key, q := range pool.queues {
+		if err := q.close(); err != nil {
+			closeErr = err
+		}
+		delete(pool.queues, key)
+	}
+	return closeErr
+}
+
+// Retain writes the queued events and closes the sinks that none of the configs uses,
+// so that sinks of removed or changed configs don't keep their files or connections open.
+// A sink that is closed is opened again by the next event of its config.
+func (pool *SinkPool) Retain(cfgs []SinkConfig) error {
+	keys := map[string]bool{}
+	for _, cfg := range cfgs {
+		key, err := sinkKey(cfg)
+		if err != nil {
+			return err
+		}
+		keys[key] = true
+	}
+
+	var unused []*sinkQueue
+	pool.mu.Lock()
+	for key, q := range pool.queues {
+		if !keys[key] {
+			unused = append(unused, q)
+			delete(pool.queues, key)
+		}
+	}
+	pool.mu.Unlock()
+
+	// The queues are closed without the lock, since writing their events might be slow
+	var closeErr error
+	for _, q := range unused {
+		if err := q.close(); err != nil {
+			closeErr = err
+		}
+	}
+	return closeErr
+}
+
+// sinkKey identifies the sink of the config in the pool
+func sinkKey(cfg SinkConfig) (string, error) {
+	// Files are rotated by the first sink, two sinks of the same file would rotate it twice
+	if cfg.Type == SinkTypeFile {
+		return SinkTypeFile + ":" + filepath.Clean(cfg.Path), nil
+	}
+
+	// The events are not part of the key, since they don't change the sink
+	cfg.Events = nil
+	bb, err := json.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+	return string(bb), nil
+}
+
+func (pool *SinkPool) queue(cfg SinkConfig) (*sinkQueue, error) {
+	key, err := sinkKey(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	if q, ok := pool.queues[key]; ok {
+		return q, nil
+	}
+
+	sink, err := NewSink(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &sinkQueue{
+		sinkType: cfg.Type,
+		sink:     sink,
+		pool:     pool,
+		events:   make(chan EventLog, pool.cfg.QueueSize),
+		stopped:  make(chan struct{}),
+	}
+	go q.run()
+
+	pool.queues[key] = q
+	return q, nil
+}
+
+func (pool *SinkPool) onError(sinkType, event string, err error) {
+	if pool.cfg.OnError != nil {
+		pool.cfg.OnError(sinkType, event, err)
+	}
+}
+
+func (pool *SinkPool) drop(sinkType, event string, err error) {
+	if pool.cfg.OnDrop != nil {
+		pool.cfg.OnDrop(sinkType, event, err)
+	}
+}
+
+// sinkQueue writes the events of a single sink in order
+type sinkQueue struct {
+	sinkType string
+	sink     Sink
+	pool     *SinkPool
+	stopped  chan struct{}
+
+	mu     sync.RWMutex
+	closed bool
+	events chan EventLog
+}
+
+// push queues the event without waiting for the sink. The event is dropped if the queue is full.
+func (q *sinkQueue) push(eventLog EventLog) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	err := os.ErrClosed
+	if !q.closed {
+		select {
+		case q.events <- eventLog:
+			return nil
+		default:
+			err = ErrSinkQueueFull
+		}
+	}
+
+	q.pool.drop(q.sinkType, eventLog.Event, err)
+	return err
+}
+
+func (q *sinkQueue) run() {
+	defer close(q.stopped)
+	for eventLog := range q.events {
+		if err := q.sink.WriteEvent(eventLog); err != nil {
+			q.pool.onError(q.sinkType, eventLog.Event, err)
+		}
+	}
+}
+
+func (q *sinkQueue) close() error {
+	q.mu.Lock()
+	q.closed = true
+	close(q.events)
+	q.mu.Unlock()
+
+	<-q.stopped
+	return q.sink.Close()
+}
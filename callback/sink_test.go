@@ -0,0 +1,465 @@
+package callback
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSinkPool_LogEvent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "infrared-sink")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "events.log")
+	pool := NewSinkPool(DefaultSinkPoolConfig())
+	defer pool.Close()
+
+	joins := SinkConfig{Type: SinkTypeFile, Events: []string{EventTypePlayerJoin}, Path: path}
+	leaves := SinkConfig{Type: SinkTypeFile, Events: []string{EventTypePlayerLeave}, Path: path}
+
+	for _, logEvent := range []struct {
+		cfg   SinkConfig
+		event Event
+	}{
+		{cfg: joins, event: PlayerJoinEvent{Username: "Steve"}},
+		{cfg: joins, event: PlayerLeaveEvent{Username: "Steve"}},
+		{cfg: leaves, event: PlayerLeaveEvent{Username: "Alex"}},
+	} {
+		if err := pool.LogEvent(logEvent.cfg, logEvent.event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Both configs share the sink of the file
+	if len(pool.queues) != 1 {
+		t.Errorf("got: %v, want: %v", len(pool.queues), 1)
+	}
+
+	// Closing the pool writes the queued events
+	if err := pool.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	bb, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(bb)), "\n")
+	expected := []string{EventTypePlayerJoin, EventTypePlayerLeave}
+	if len(lines) != len(expected) {
+		t.Fatalf("got: %v, want: %v lines", lines, len(expected))
+	}
+
+	for i, line := range lines {
+		var eventLog EventLog
+		if err := json.Unmarshal([]byte(line), &eventLog); err != nil {
+			t.Fatal(err)
+		}
+		if eventLog.Event != expected[i] {
+			t.Errorf("got: %v, want: %v", eventLog.Event, expected[i])
+		}
+	}
+}
+
+func TestSinkPool_Retain(t *testing.T) {
+	dir, err := ioutil.TempDir("", "infrared-sink")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	pool := NewSinkPool(DefaultSinkPoolConfig())
+	defer pool.Close()
+
+	events := []string{EventTypePlayerJoin}
+	kept := SinkConfig{Type: SinkTypeFile, Events: events, Path: filepath.Join(dir, "kept.log")}
+	removed := SinkConfig{Type: SinkTypeFile, Events: events, Path: filepath.Join(dir, "removed.log")}
+	for _, cfg := range []SinkConfig{kept, removed} {
+		if err := pool.LogEvent(cfg, PlayerJoinEvent{Username: "Steve"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The events of a config don't change its sink
+	kept.Events = []string{EventTypePlayerLeave}
+	if err := pool.Retain([]SinkConfig{kept}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pool.queues) != 1 {
+		t.Fatalf("got: %v, want: %v", len(pool.queues), 1)
+	}
+	key, _ := sinkKey(kept)
+	if _, ok := pool.queues[key]; !ok {
+		t.Error("the sink of a retained config should stay open")
+	}
+
+	// Closing the sink writes its queued events
+	bb, err := ioutil.ReadFile(removed.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(bb), EventTypePlayerJoin) {
+		t.Errorf("got: %q, want: the queued event", bb)
+	}
+
+	if err := pool.Retain(nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(pool.queues) != 0 {
+		t.Errorf("got: %v, want: %v", len(pool.queues), 0)
+	}
+}
+
+// blockingSink blocks every write until it is released
+type blockingSink struct {
+	started chan struct{}
+	release chan struct{}
+	written int
+}
+
+func (sink *blockingSink) WriteEvent(EventLog) error {
+	sink.started <- struct{}{}
+	<-sink.release
+	sink.written++
+	return nil
+}
+
+func (sink *blockingSink) Close() error {
+	return nil
+}
+
+func TestSinkQueue_Full(t *testing.T) {
+	var dropped []string
+	pool := NewSinkPool(SinkPoolConfig{
+		QueueSize: 1,
+		OnDrop: func(sinkType, event string, err error) {
+			dropped = append(dropped, event)
+		},
+	})
+
+	sink := &blockingSink{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	q := &sinkQueue{
+		sinkType: "blocking",
+		sink:     sink,
+		pool:     pool,
+		events:   make(chan EventLog, pool.cfg.QueueSize),
+		stopped:  make(chan struct{}),
+	}
+	go q.run()
+
+	// The first event blocks the sink, the second waits in the queue
+	if err := q.push(newEventLog(PlayerJoinEvent{})); err != nil {
+		t.Fatal(err)
+	}
+	<-sink.started
+	if err := q.push(newEventLog(PlayerLeaveEvent{})); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := q.push(newEventLog(ErrorEvent{})); err != ErrSinkQueueFull {
+		t.Errorf("got: %v, want: %v", err, ErrSinkQueueFull)
+	}
+	if len(dropped) != 1 || dropped[0] != EventTypeError {
+		t.Errorf("got: %v, want: [%s]", dropped, EventTypeError)
+	}
+
+	go func() {
+		for range sink.started {
+		}
+	}()
+	close(sink.release)
+	if err := q.close(); err != nil {
+		t.Fatal(err)
+	}
+	close(sink.started)
+
+	if sink.written != 2 {
+		t.Errorf("got: %v, want: %v", sink.written, 2)
+	}
+}
+
+func TestNewSink(t *testing.T) {
+	tt := []struct {
+		cfg SinkConfig
+		err error
+	}{
+		{
+			cfg: SinkConfig{Type: SinkTypeStdout},
+		},
+		{
+			cfg: SinkConfig{Type: SinkTypeFile},
+			err: ErrMissingSinkPath,
+		},
+		{
+			cfg: SinkConfig{Type: SinkTypeSyslog, Network: "udp", Address: "localhost:514", Facility: "local0"},
+		},
+		{
+			cfg: SinkConfig{Type: SinkTypeSyslog, Network: "http", Address: "localhost:514"},
+			err: ErrUnknownSyslogNetwork,
+		},
+		{
+			cfg: SinkConfig{Type: SinkTypeSyslog, Network: "udp", Address: "localhost:514", Facility: "local8"},
+			err: ErrUnknownSyslogFacility,
+		},
+		{
+			cfg: SinkConfig{Type: "kafka"},
+			err: ErrUnknownSinkType,
+		},
+	}
+
+	for _, tc := range tt {
+		sink, err := NewSink(tc.cfg)
+		if err != tc.err {
+			t.Errorf("%+v: got: %v, want: %v", tc.cfg, err, tc.err)
+		}
+		if sink != nil {
+			sink.Close()
+		}
+	}
+}
+
+func TestWriterSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewWriterSink(&buf)
+
+	if err := sink.WriteEvent(newEventLog(ContainerStartEvent{ProxyUID: "a@:25565"})); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `"event":"ContainerStart"`
+	if !strings.HasSuffix(buf.String(), "\n") || !strings.Contains(buf.String(), expected) {
+		t.Errorf("got: %q, want: a line with %s", buf.String(), expected)
+	}
+}
+
+func TestFileSink_Rotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "infrared-sink")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "events.log")
+	sink, err := NewFileSink(path, 0, time.Hour, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	now := time.Unix(1600000000, 0)
+	sink.now = func() time.Time { return now }
+	sink.openedAt = now
+
+	// Every event is written an hour after the last rotation
+	for i := 0; i < 4; i++ {
+		if err := sink.WriteEvent(newEventLog(ErrorEvent{Error: strconv.Itoa(i)})); err != nil {
+			t.Fatal(err)
+		}
+		now = now.Add(time.Hour)
+	}
+
+	backups, _ := filepath.Glob(path + ".*")
+	if len(backups) != 2 {
+		t.Fatalf("got: %v, want: 2 backups", backups)
+	}
+
+	// The oldest backup with the first event was removed
+	for i, backup := range append(backups, path) {
+		bb, err := ioutil.ReadFile(backup)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := `"error":"` + strconv.Itoa(i+1) + `"`
+		if !strings.Contains(string(bb), expected) {
+			t.Errorf("got: %s, want: %s in %s", bb, expected, backup)
+		}
+	}
+}
+
+func TestFileSink_RotateFails(t *testing.T) {
+	dir, err := ioutil.TempDir("", "infrared-sink")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "events.log")
+	sink, err := NewFileSink(path, 1, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	now := time.Unix(1600000000, 0)
+	sink.now = func() time.Time { return now }
+
+	// The file can't be renamed to the backup path, since a directory with a file is in the way
+	backupPath := path + "." + now.UTC().Format(fileSinkBackupLayout)
+	if err := os.MkdirAll(filepath.Join(backupPath, "blocked"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sink.WriteEvent(newEventLog(ErrorEvent{Error: "0"})); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.WriteEvent(newEventLog(ErrorEvent{Error: "1"})); err == nil {
+		t.Error("got: nil, want: the error of the rename")
+	}
+
+	// Once the rename works again, the sink rotates as before
+	now = now.Add(time.Second)
+	if err := sink.WriteEvent(newEventLog(ErrorEvent{Error: "2"})); err != nil {
+		t.Fatal(err)
+	}
+
+	backup, err := ioutil.ReadFile(path + "." + now.UTC().Format(fileSinkBackupLayout))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{`"error":"0"`, `"error":"1"`} {
+		if !strings.Contains(string(backup), expected) {
+			t.Errorf("got: %s, want: %s", backup, expected)
+		}
+	}
+}
+
+func TestFileSink_MaxSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "infrared-sink")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "events.log")
+	sink, err := NewFileSink(path, 1, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	now := time.Unix(1600000000, 0)
+	sink.now = func() time.Time { return now }
+
+	// A single event is larger than the max size, but empty files are never rotated
+	for i := 0; i < 3; i++ {
+		if err := sink.WriteEvent(newEventLog(ErrorEvent{})); err != nil {
+			t.Fatal(err)
+		}
+		now = now.Add(time.Second)
+	}
+
+	backups, _ := filepath.Glob(path + ".*")
+	if len(backups) != 2 {
+		t.Errorf("got: %v, want: 2 backups", backups)
+	}
+}
+
+func TestSyslogSink_UDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	sink, err := NewSyslogSink("udp", conn.LocalAddr().String(), "local0", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	sink.hostname = "mc.example.com"
+	sink.procID = "42"
+
+	eventLog := EventLog{
+		Event:     EventTypeError,
+		Timestamp: time.Date(2020, 9, 13, 12, 26, 40, 123000000, time.UTC),
+		Payload:   ErrorEvent{Error: "failed"},
+	}
+	if err := sink.WriteEvent(eventLog); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 1024)
+	if err := conn.SetReadDeadline(time.Now().Add(3 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// local0 (16) * 8 + err (3) = 131
+	expected := "<131>1 2020-09-13T12:26:40.123000Z mc.example.com infrared 42 Error - " + utf8BOM + `{"event":"Error",`
+	if !strings.HasPrefix(string(buf[:n]), expected) {
+		t.Errorf("got: %q, want prefix: %q", buf[:n], expected)
+	}
+}
+
+func TestSyslogSink_TCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	sink, err := NewSyslogSink("tcp", l.Addr().String(), "", "proxy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	for _, username := range []string{"Steve", "Alex"} {
+		if err := sink.WriteEvent(newEventLog(PlayerJoinEvent{Username: username})); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if err := conn.SetReadDeadline(time.Now().Add(3 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Messages are framed by their length
+	r := bufio.NewReader(conn)
+	for _, username := range []string{"Steve", "Alex"} {
+		length, err := r.ReadString(' ')
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(length))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		msg := make([]byte, n)
+		if _, err := io.ReadFull(r, msg); err != nil {
+			t.Fatal(err)
+		}
+
+		if !strings.HasPrefix(string(msg), "<14>1 ") || !strings.Contains(string(msg), " proxy ") || !strings.Contains(string(msg), username) {
+			t.Errorf("got: %q, want: a user.info message of %s", msg, username)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package callback
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const spoolFileExt = ".json"
+
+// spoolStore keeps every event in its own file until it is delivered.
+// The files are named by a sequence number, so that they are delivered in order.
+// A file holds the spoolItem of an event, since the bodies of some formats don't
+// contain the type of the event.
+type spoolStore struct {
+	mu      sync.Mutex
+	dir     string
+	size    int
+	names   []string
+	nextSeq uint64
+}
+
+type spoolItem struct {
+	Event string `json:"event"`
+	Body  string `json:"body"`
+}
+
+func newSpoolStore(dir string, targetJSON []byte, size int) (*spoolStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, spoolTargetFileName), targetJSON, 0600); err != nil {
+		return nil, err
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	store := &spoolStore{
+		dir:  dir,
+		size: size,
+	}
+
+	// ReadDir sorts the files by name
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !strings.HasSuffix(name, spoolFileExt) {
+			continue
+		}
+
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, spoolFileExt), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		store.names = append(store.names, name)
+		store.nextSeq = seq + 1
+	}
+
+	return store, nil
+}
+
+func (store *spoolStore) push(item queueItem) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if len(store.names) >= store.size {
+		return ErrQueueFull
+	}
+
+	name := fmt.Sprintf("%020d%s", store.nextSeq, spoolFileExt)
+	path := filepath.Join(store.dir, name)
+
+	bb, err := json.Marshal(spoolItem{Event: item.event, Body: string(item.body)})
+	if err != nil {
+		return err
+	}
+
+	// The event is renamed after it is written, so that no partial events are delivered
+	if err := ioutil.WriteFile(path+".tmp", bb, 0600); err != nil {
+		return err
+	}
+
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return err
+	}
+
+	store.nextSeq++
+	store.names = append(store.names, name)
+	return nil
+}
+
+func (store *spoolStore) peek() (queueItem, bool, error) {
+	store.mu.Lock()
+	if len(store.names) == 0 {
+		store.mu.Unlock()
+		return queueItem{}, false, nil
+	}
+	name := store.names[0]
+	store.mu.Unlock()
+
+	bb, err := ioutil.ReadFile(filepath.Join(store.dir, name))
+	if err != nil {
+		return queueItem{}, true, err
+	}
+
+	var item spoolItem
+	if err := json.Unmarshal(bb, &item); err != nil {
+		return queueItem{}, true, err
+	}
+
+	return queueItem{event: item.Event, body: []byte(item.Body)}, true, nil
+}
+
+func (store *spoolStore) pop() error {
+	store.mu.Lock()
+	if len(store.names) == 0 {
+		store.mu.Unlock()
+		return nil
+	}
+	name := store.names[0]
+	store.names = store.names[1:]
+	store.mu.Unlock()
+
+	return os.Remove(filepath.Join(store.dir, name))
+}
@@ -0,0 +1,176 @@
+package callback
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// subscriptionBufferSize is how many events a subscriber can fall behind
+// before it is unsubscribed
+const subscriptionBufferSize = 64
+
+// StreamEvent is an EventLog with an ID that increases with every event,
+// so that subscribers can resume after the last event they received
+type StreamEvent struct {
+	ID uint64 `json:"id"`
+	EventLog
+
+	proxyUID   string
+	patternUID string
+}
+
+// StreamFilter selects the events of a subscription.
+// Empty lists match every event type or proxy UID.
+// Events of domain proxies also match the UID of their domain pattern.
+type StreamFilter struct {
+	Events    []string
+	ProxyUIDs []string
+}
+
+func (filter StreamFilter) matches(event StreamEvent) bool {
+	if !matchesAny(filter.Events, event.Event) {
+		return false
+	}
+	if event.patternUID != "" && matchesAny(filter.ProxyUIDs, event.patternUID) {
+		return true
+	}
+	return matchesAny(filter.ProxyUIDs, event.proxyUID)
+}
+
+func matchesAny(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Stream broadcasts events to its subscribers and keeps the latest events
+// in a replay buffer for subscribers that reconnect
+type Stream struct {
+	mu          sync.Mutex
+	size        int
+	lastID      uint64
+	buffer      []StreamEvent
+	subscribers map[*Subscription]bool
+}
+
+// NewStream creates a Stream that keeps the latest size events for replays
+func NewStream(size int) *Stream {
+	return &Stream{
+		size:        size,
+		subscribers: map[*Subscription]bool{},
+	}
+}
+
+// Publish sends the event to every subscriber with a matching filter. Subscribers
+// that can't keep up are unsubscribed, so that they don't block the others.
+func (stream *Stream) Publish(event Event) {
+	stream.PublishPattern(event, "")
+}
+
+// PublishPattern publishes the event of a proxy that serves a domain of the domain
+// pattern with patternUID, so that it also matches filters with the pattern UID
+func (stream *Stream) PublishPattern(event Event, patternUID string) {
+	streamEvent := StreamEvent{
+		EventLog:   newEventLog(event),
+		proxyUID:   eventProxyUID(event),
+		patternUID: patternUID,
+	}
+
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+
+	stream.lastID++
+	streamEvent.ID = stream.lastID
+
+	if stream.size > 0 {
+		if len(stream.buffer) >= stream.size {
+			stream.buffer = stream.buffer[1:]
+		}
+		stream.buffer = append(stream.buffer, streamEvent)
+	}
+
+	for subscription := range stream.subscribers {
+		if !subscription.filter.matches(streamEvent) {
+			continue
+		}
+
+		select {
+		case subscription.events <- streamEvent:
+		default:
+			stream.unsubscribe(subscription)
+		}
+	}
+}
+
+// Subscribe creates a subscription for the events that match the filter. If lastID
+// is not 0, the buffered events after lastID are replayed first.
+func (stream *Stream) Subscribe(filter StreamFilter, lastID uint64) *Subscription {
+	subscription := &Subscription{
+		stream: stream,
+		filter: filter,
+		events: make(chan StreamEvent, subscriptionBufferSize+stream.size),
+	}
+
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+
+	if lastID != 0 {
+		for _, event := range stream.buffer {
+			if event.ID > lastID && filter.matches(event) {
+				subscription.events <- event
+			}
+		}
+	}
+
+	stream.subscribers[subscription] = true
+	return subscription
+}
+
+func (stream *Stream) unsubscribe(subscription *Subscription) {
+	if !stream.subscribers[subscription] {
+		return
+	}
+	delete(stream.subscribers, subscription)
+	close(subscription.events)
+}
+
+// Subscription receives the events of a Stream until it is unsubscribed
+type Subscription struct {
+	stream *Stream
+	filter StreamFilter
+	events chan StreamEvent
+}
+
+// Events is closed when the subscription ends
+func (subscription *Subscription) Events() <-chan StreamEvent {
+	return subscription.events
+}
+
+func (subscription *Subscription) Unsubscribe() {
+	subscription.stream.mu.Lock()
+	defer subscription.stream.mu.Unlock()
+	subscription.stream.unsubscribe(subscription)
+}
+
+// eventProxyUID reads the proxy UID of the event, since every event has one
+func eventProxyUID(event Event) string {
+	bb, err := json.Marshal(event)
+	if err != nil {
+		return ""
+	}
+
+	var v struct {
+		ProxyUID string `json:"proxyUid"`
+	}
+	if err := json.Unmarshal(bb, &v); err != nil {
+		return ""
+	}
+	return v.ProxyUID
+}
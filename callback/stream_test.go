@@ -0,0 +1,151 @@
+package callback
+
+import (
+	"testing"
+)
+
+func receiveIDs(subscription *Subscription) []uint64 {
+	var ids []uint64
+	for {
+		select {
+		case event, ok := <-subscription.Events():
+			if !ok {
+				return ids
+			}
+			ids = append(ids, event.ID)
+		default:
+			return ids
+		}
+	}
+}
+
+func equalIDs(a, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestStream_Subscribe(t *testing.T) {
+	tt := []struct {
+		name        string
+		filter      StreamFilter
+		lastID      uint64
+		expectedIDs []uint64
+	}{
+		{
+			name:        "new events",
+			expectedIDs: []uint64{4, 5},
+		},
+		{
+			name:        "replay",
+			lastID:      2,
+			expectedIDs: []uint64{3, 4, 5},
+		},
+		{
+			name:        "unknown id",
+			lastID:      0xffff,
+			expectedIDs: []uint64{4, 5},
+		},
+		{
+			name:        "event type",
+			filter:      StreamFilter{Events: []string{EventTypePlayerJoin}},
+			lastID:      1,
+			expectedIDs: []uint64{2, 4},
+		},
+		{
+			name:        "proxy uid",
+			filter:      StreamFilter{ProxyUIDs: []string{"b@:25565"}},
+			lastID:      1,
+			expectedIDs: []uint64{3, 5},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			stream := NewStream(2)
+			stream.Publish(ErrorEvent{ProxyUID: "a@:25565"})
+			stream.Publish(PlayerJoinEvent{ProxyUID: "a@:25565"})
+			stream.Publish(ContainerStartEvent{ProxyUID: "b@:25565"})
+
+			subscription := stream.Subscribe(tc.filter, tc.lastID)
+			defer subscription.Unsubscribe()
+
+			stream.Publish(PlayerJoinEvent{ProxyUID: "a@:25565"})
+			stream.Publish(ContainerStopEvent{ProxyUID: "b@:25565"})
+
+			ids := receiveIDs(subscription)
+			if !equalIDs(ids, tc.expectedIDs) {
+				t.Errorf("got: %v, want: %v", ids, tc.expectedIDs)
+			}
+		})
+	}
+}
+
+func TestStream_PublishPattern(t *testing.T) {
+	tt := []struct {
+		name        string
+		filter      StreamFilter
+		expectedIDs []uint64
+	}{
+		{
+			name:        "domain uid",
+			filter:      StreamFilter{ProxyUIDs: []string{"a.example.com@:25565"}},
+			expectedIDs: []uint64{1},
+		},
+		{
+			name:        "pattern uid",
+			filter:      StreamFilter{ProxyUIDs: []string{"*.example.com@:25565"}},
+			expectedIDs: []uint64{1, 2},
+		},
+		{
+			name:        "pattern uid and event type",
+			filter:      StreamFilter{Events: []string{EventTypeContainerStop}, ProxyUIDs: []string{"*.example.com@:25565"}},
+			expectedIDs: []uint64{2},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			stream := NewStream(0)
+			subscription := stream.Subscribe(tc.filter, 0)
+			defer subscription.Unsubscribe()
+
+			stream.PublishPattern(ContainerStartEvent{ProxyUID: "a.example.com@:25565"}, "*.example.com@:25565")
+			stream.PublishPattern(ContainerStopEvent{ProxyUID: "b.example.com@:25565"}, "*.example.com@:25565")
+			stream.Publish(ContainerStopEvent{ProxyUID: "c.example.com@:25565"})
+
+			ids := receiveIDs(subscription)
+			if !equalIDs(ids, tc.expectedIDs) {
+				t.Errorf("got: %v, want: %v", ids, tc.expectedIDs)
+			}
+		})
+	}
+}
+
+func TestStream_SlowSubscriber(t *testing.T) {
+	stream := NewStream(0)
+	subscription := stream.Subscribe(StreamFilter{}, 0)
+
+	for i := 0; i < subscriptionBufferSize+1; i++ {
+		stream.Publish(ErrorEvent{})
+	}
+
+	ids := receiveIDs(subscription)
+	if len(ids) != subscriptionBufferSize {
+		t.Errorf("got: %v events, want: %v", len(ids), subscriptionBufferSize)
+	}
+
+	if _, ok := <-subscription.Events(); ok {
+		t.Error("a subscriber that can't keep up should be unsubscribed")
+	}
+
+	// Unsubscribing twice must not panic
+	subscription.Unsubscribe()
+}
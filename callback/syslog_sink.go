@@ -0,0 +1,193 @@
+package callback
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	syslogVersion         = 1
+	syslogTimestampLayout = "2006-01-02T15:04:05.000000Z07:00"
+	syslogNilValue        = "-"
+	syslogDefaultAppName  = "infrared"
+	syslogTimeout         = 5 * time.Second
+	// utf8BOM marks the message as UTF-8 as required by RFC 5424
+	utf8BOM = "\xef\xbb\xbf"
+
+	syslogSeverityError = 3
+	syslogSeverityInfo  = 6
+)
+
+var (
+	ErrUnknownSyslogNetwork  = errors.New("unknown syslog network")
+	ErrUnknownSyslogFacility = errors.New("unknown syslog facility")
+)
+
+var syslogFacilities = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+// SyslogSink sends every event as an RFC 5424 message to a syslog server.
+// The message ID is the event type and the message is the EventLog as JSON.
+// Messages over stream connections are framed by octet counting as in RFC 6587.
+type SyslogSink struct {
+	network  string
+	address  string
+	facility int
+	appName  string
+	hostname string
+	procID   string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewSyslogSink creates a sink for the syslog server at address. The network is
+// "udp", "tcp", "unix" or "unixgram". The connection is established with the first event.
+func NewSyslogSink(network, address, facility, appName string) (*SyslogSink, error) {
+	switch network {
+	case "udp", "udp4", "udp6", "tcp", "tcp4", "tcp6", "unix", "unixgram":
+	default:
+		return nil, ErrUnknownSyslogNetwork
+	}
+
+	if facility == "" {
+		facility = "user"
+	}
+	facilityCode, ok := syslogFacilities[facility]
+	if !ok {
+		return nil, ErrUnknownSyslogFacility
+	}
+
+	if appName == "" {
+		appName = syslogDefaultAppName
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = syslogNilValue
+	}
+
+	return &SyslogSink{
+		network:  network,
+		address:  address,
+		facility: facilityCode,
+		appName:  appName,
+		hostname: hostname,
+		procID:   strconv.Itoa(os.Getpid()),
+	}, nil
+}
+
+func (sink *SyslogSink) WriteEvent(eventLog EventLog) error {
+	msg, err := sink.format(eventLog)
+	if err != nil {
+		return err
+	}
+
+	if sink.isStream() {
+		msg = append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+	}
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+
+	if err := sink.write(msg); err == nil {
+		return nil
+	}
+
+	// The connection is dialed again once, since the syslog server might have been restarted
+	return sink.write(msg)
+}
+
+func (sink *SyslogSink) Close() error {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if sink.conn == nil {
+		return nil
+	}
+
+	err := sink.conn.Close()
+	sink.conn = nil
+	return err
+}
+
+func (sink *SyslogSink) isStream() bool {
+	switch sink.network {
+	case "tcp", "tcp4", "tcp6", "unix":
+		return true
+	default:
+		return false
+	}
+}
+
+func (sink *SyslogSink) write(msg []byte) error {
+	if sink.conn == nil {
+		conn, err := net.DialTimeout(sink.network, sink.address, syslogTimeout)
+		if err != nil {
+			return err
+		}
+		sink.conn = conn
+	}
+
+	if err := sink.conn.SetWriteDeadline(time.Now().Add(syslogTimeout)); err != nil {
+		return err
+	}
+
+	if _, err := sink.conn.Write(msg); err != nil {
+		sink.conn.Close()
+		sink.conn = nil
+		return err
+	}
+	return nil
+}
+
+// format returns the RFC 5424 message of the event log without structured data
+func (sink *SyslogSink) format(eventLog EventLog) ([]byte, error) {
+	bb, err := json.Marshal(eventLog)
+	if err != nil {
+		return nil, err
+	}
+
+	severity := syslogSeverityInfo
+	if eventLog.Event == EventTypeError {
+		severity = syslogSeverityError
+	}
+
+	header := fmt.Sprintf("<%d>%d %s %s %s %s %s %s ",
+		sink.facility*8+severity,
+		syslogVersion,
+		eventLog.Timestamp.Format(syslogTimestampLayout),
+		sink.hostname,
+		sink.appName,
+		sink.procID,
+		eventLog.Event,
+		syslogNilValue,
+	)
+
+	return append([]byte(header+utf8BOM), bb...), nil
+}
@@ -0,0 +1,120 @@
+package callback
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+const (
+	// FormatDiscord sends a message for Discord webhooks
+	FormatDiscord = "discord"
+	// FormatSlack sends a message for Slack incoming webhooks
+	FormatSlack = "slack"
+)
+
+// presetMessage sets $message to a line that announces the event
+const presetMessage = `
+{{- $domain := domain .Payload.proxyUid -}}
+{{- $message := printf "%s on %s" .Event $domain -}}
+{{- if eq .Event "PlayerJoin" -}}
+	{{- $message = printf "🟢 %s joined %s" (code .Payload.username) $domain -}}
+{{- else if eq .Event "PlayerLeave" -}}
+	{{- $message = printf "🔴 %s left %s" (code .Payload.username) $domain -}}
+{{- else if eq .Event "ContainerStart" -}}
+	{{- $message = printf "▶️ The server of %s was started" $domain -}}
+{{- else if eq .Event "ContainerStop" -}}
+	{{- $message = printf "⏹️ The server of %s was stopped" $domain -}}
+{{- end -}}
+`
+
+// presetTemplates are the templates of the formats for chat platforms
+var presetTemplates = map[string]string{
+	// Discord must not ping anyone that is mentioned in a message
+	FormatDiscord: presetMessage + `{"username":"Infrared","content":{{json $message}},"allowed_mentions":{"parse":[]}}`,
+	// Slack parses mentions and links in the whole message
+	FormatSlack: presetMessage + `{"text":{{json (slackEscape $message)}}}`,
+}
+
+// codeReplacer keeps values from ending their code span. Backticks are replaced
+// with a look-alike, since Markdown has no escape for them in code spans.
+var codeReplacer = strings.NewReplacer("`", "ˋ")
+
+// slackReplacer escapes the characters that Slack's mrkdwn uses for mentions and links.
+// Other platforms like Discord show the entities literally.
+var slackReplacer = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+)
+
+// templateFuncs can be used in every template
+var templateFuncs = template.FuncMap{
+	// json encodes the value as JSON, so that it can be used in JSON bodies
+	"json": func(v interface{}) (string, error) {
+		bb, err := json.Marshal(v)
+		return string(bb), err
+	},
+	// domain returns the domain name of a proxy UID
+	"domain": func(proxyUID interface{}) string {
+		s, _ := proxyUID.(string)
+		if i := strings.LastIndex(s, "@"); i >= 0 {
+			return s[:i]
+		}
+		return s
+	},
+	// code formats the value as inline code in Markdown
+	"code": func(v interface{}) string {
+		return "`" + codeReplacer.Replace(fmt.Sprint(v)) + "`"
+	},
+	// slackEscape escapes the value for the text of a Slack message
+	"slackEscape": func(v interface{}) string {
+		return slackReplacer.Replace(fmt.Sprint(v))
+	},
+}
+
+// templates caches the parsed templates by their text
+var templates sync.Map
+
+func parseTemplate(text string) (*template.Template, error) {
+	if tmpl, ok := templates.Load(text); ok {
+		return tmpl.(*template.Template), nil
+	}
+
+	tmpl, err := template.New("callback").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	templates.Store(text, tmpl)
+	return tmpl, nil
+}
+
+// render executes the template with the event log. The payload is passed as a map,
+// so that its fields have the same names as in the JSON, e.g. {{.Payload.username}}.
+func render(text string, eventLog EventLog) ([]byte, error) {
+	tmpl, err := parseTemplate(text)
+	if err != nil {
+		return nil, err
+	}
+
+	bb, err := json.Marshal(eventLog.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(bb, &payload); err != nil {
+		return nil, err
+	}
+	eventLog.Payload = payload
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, eventLog); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
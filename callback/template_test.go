@@ -0,0 +1,170 @@
+package callback
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestLogger_encodeTemplate(t *testing.T) {
+	timestamp := time.Unix(1600000000, 0).UTC()
+
+	tt := []struct {
+		name   string
+		logger Logger
+		event  Event
+		want   map[string]string
+	}{
+		{
+			name:   "DiscordPlayerJoin",
+			logger: Logger{Format: FormatDiscord},
+			event:  PlayerJoinEvent{Username: "Steve_1", ProxyUID: "mc.example.com@:25565"},
+			want: map[string]string{
+				"username": "Infrared",
+				"content":  "🟢 `Steve_1` joined mc.example.com",
+			},
+		},
+		{
+			name:   "DiscordHostileUsername",
+			logger: Logger{Format: FormatDiscord},
+			event:  PlayerJoinEvent{Username: "a`@everyone", ProxyUID: "mc.example.com@:25565"},
+			want: map[string]string{
+				"content": "🟢 `aˋ@everyone` joined mc.example.com",
+			},
+		},
+		{
+			name:   "DiscordSpecialCharacters",
+			logger: Logger{Format: FormatDiscord},
+			event:  PlayerJoinEvent{Username: "<Steve> & Alex", ProxyUID: "mc.example.com@:25565"},
+			want: map[string]string{
+				"content": "🟢 `<Steve> & Alex` joined mc.example.com",
+			},
+		},
+		{
+			name:   "DiscordContainerStop",
+			logger: Logger{Format: FormatDiscord},
+			event:  ContainerStopEvent{ProxyUID: "mc.example.com@:25565"},
+			want: map[string]string{
+				"content": "⏹️ The server of mc.example.com was stopped",
+			},
+		},
+		{
+			name:   "SlackPlayerLeave",
+			logger: Logger{Format: FormatSlack},
+			event:  PlayerLeaveEvent{Username: "Alex", ProxyUID: "mc.example.com@:25565"},
+			want: map[string]string{
+				"text": "🔴 `Alex` left mc.example.com",
+			},
+		},
+		{
+			name:   "SlackHostileUsername",
+			logger: Logger{Format: FormatSlack},
+			event:  PlayerJoinEvent{Username: "<!channel> & `x`", ProxyUID: "mc.example.com@:25565"},
+			want: map[string]string{
+				"text": "🟢 `&lt;!channel&gt; &amp; ˋxˋ` joined mc.example.com",
+			},
+		},
+		{
+			name:   "SlackUnknownHost",
+			logger: Logger{Format: FormatSlack},
+			event:  UnknownHostEvent{RequestedDomain: `"}`, ProxyUID: "fallback@:25565"},
+			want: map[string]string{
+				"text": "UnknownHost on fallback",
+			},
+		},
+		{
+			name: "Template",
+			logger: Logger{
+				Format:   FormatCloudEvents,
+				Template: `{"name":{{json .Payload.username}},"at":{{.Timestamp.Unix}},"event":"{{.Event}}"}`,
+			},
+			event: PlayerJoinEvent{Username: `"quoted"`},
+			want: map[string]string{
+				"name":  `"quoted"`,
+				"event": EventTypePlayerJoin,
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			bb, err := tc.logger.encode(EventLog{
+				Event:     tc.event.EventType(),
+				Timestamp: timestamp,
+				Payload:   tc.event,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(bb, &got); err != nil {
+				t.Fatalf("got: %s, want: valid JSON; error: %v", bb, err)
+			}
+
+			for key, value := range tc.want {
+				if got[key] != value {
+					t.Errorf("%s: got: %v, want: %v", key, got[key], value)
+				}
+			}
+		})
+	}
+}
+
+func TestLogger_encodeDiscordMentions(t *testing.T) {
+	logger := Logger{Format: FormatDiscord}
+	bb, err := logger.encode(newEventLog(PlayerJoinEvent{Username: "<@&123>"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var body struct {
+		AllowedMentions *struct {
+			Parse []string `json:"parse"`
+		} `json:"allowed_mentions"`
+	}
+	if err := json.Unmarshal(bb, &body); err != nil {
+		t.Fatal(err)
+	}
+
+	if body.AllowedMentions == nil || body.AllowedMentions.Parse == nil || len(body.AllowedMentions.Parse) != 0 {
+		t.Errorf("got: %s, want: no allowed mentions", bb)
+	}
+}
+
+func TestLogger_encodeInvalidTemplate(t *testing.T) {
+	logger := Logger{Template: "{{.Payload.username"}
+	if _, err := logger.encode(newEventLog(PlayerJoinEvent{})); err == nil {
+		t.Error("got: nil, want: a parse error")
+	}
+}
+
+func TestLogger_contentType(t *testing.T) {
+	tt := []struct {
+		logger Logger
+		want   string
+	}{
+		{
+			logger: Logger{},
+			want:   "application/json",
+		},
+		{
+			logger: Logger{Format: FormatCloudEvents},
+			want:   "application/cloudevents+json",
+		},
+		{
+			logger: Logger{Format: FormatCloudEvents, Template: "{{.Event}}"},
+			want:   "application/json",
+		},
+		{
+			logger: Logger{Template: "{{.Event}}", ContentType: "text/plain"},
+			want:   "text/plain",
+		},
+	}
+
+	for _, tc := range tt {
+		if got := tc.logger.contentType(); got != tc.want {
+			t.Errorf("got: %v, want: %v", got, tc.want)
+		}
+	}
+}
@@ -3,23 +3,43 @@ package main
 import (
 	"flag"
 	"log"
+	"net/http"
 	"os"
 	"strconv"
 
 	"github.com/haveachin/infrared"
+	"github.com/haveachin/infrared/api"
 )
 
 const (
-	envPrefix     = "INFRARED_"
-	envConfigPath = envPrefix + "CONFIG_PATH"
+	envPrefix          = "INFRARED_"
+	envConfigPath      = envPrefix + "CONFIG_PATH"
+	envGlobalConfig    = envPrefix + "GLOBAL_CONFIG_PATH"
+	envStatusCachePath = envPrefix + "STATUS_CACHE_PATH"
+	envMetricsListen   = envPrefix + "METRICS_LISTEN"
+	envAPIListen       = envPrefix + "API_LISTEN"
+	envAPIToken        = envPrefix + "API_TOKEN"
+	envCallbackSpool   = envPrefix + "CALLBACK_SPOOL_PATH"
 )
 
 const (
-	clfConfigPath = "config-path"
+	clfConfigPath      = "config-path"
+	clfGlobalConfig    = "global-config-path"
+	clfStatusCachePath = "status-cache-path"
+	clfMetricsListen   = "metrics-listen"
+	clfAPIListen       = "api-listen"
+	clfAPIToken        = "api-token"
+	clfCallbackSpool   = "callback-spool-path"
 )
 
 var (
-	configPath = "./configs"
+	configPath      = "./configs"
+	globalConfig    = ""
+	statusCachePath = "./status-cache"
+	metricsListen   = ""
+	apiListen       = ""
+	apiToken        = ""
+	callbackSpool   = ""
 )
 
 func envBool(name string, value bool) bool {
@@ -47,10 +67,22 @@ func envString(name string, value string) string {
 
 func initEnv() {
 	configPath = envString(envConfigPath, configPath)
+	globalConfig = envString(envGlobalConfig, globalConfig)
+	statusCachePath = envString(envStatusCachePath, statusCachePath)
+	metricsListen = envString(envMetricsListen, metricsListen)
+	apiListen = envString(envAPIListen, apiListen)
+	apiToken = envString(envAPIToken, apiToken)
+	callbackSpool = envString(envCallbackSpool, callbackSpool)
 }
 
 func initFlags() {
 	flag.StringVar(&configPath, clfConfigPath, configPath, "path of all proxy configs")
+	flag.StringVar(&globalConfig, clfGlobalConfig, globalConfig, "path of the global config; disabled if empty")
+	flag.StringVar(&statusCachePath, clfStatusCachePath, statusCachePath, "path of the cached server statuses")
+	flag.StringVar(&metricsListen, clfMetricsListen, metricsListen, "address of the Prometheus metrics endpoint; disabled if empty")
+	flag.StringVar(&apiListen, clfAPIListen, apiListen, "address of the admin API; disabled if empty")
+	flag.StringVar(&apiToken, clfAPIToken, apiToken, "bearer token of the admin API")
+	flag.StringVar(&callbackSpool, clfCallbackSpool, callbackSpool, "path where undelivered callback events are stored; disabled if empty")
 	flag.Parse()
 }
 
@@ -59,7 +91,49 @@ func init() {
 	initFlags()
 }
 
+func serveMetrics(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", infrared.Metrics)
+
+	log.Println("Serving metrics on", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		log.Printf("Failed serving metrics on %s; error: %s", addr, err)
+	}
+}
+
+func serveAPI(addr string, gateway *infrared.Gateway) {
+	if apiToken == "" {
+		log.Println("Not serving the API on", addr, "without an API token")
+		return
+	}
+
+	log.Println("Serving API on", addr)
+	server := api.Server{
+		Gateway: gateway,
+		Token:   apiToken,
+		Events:  infrared.EventStream,
+	}
+	if err := http.ListenAndServe(addr, server); err != nil {
+		log.Printf("Failed serving API on %s; error: %s", addr, err)
+	}
+}
+
 func main() {
+	if metricsListen != "" {
+		go serveMetrics(metricsListen)
+	}
+
+	var global infrared.GlobalConfig
+	if globalConfig != "" {
+		log.Println("Loading global config")
+		var err error
+		global, err = infrared.LoadGlobalConfigFromPath(globalConfig)
+		if err != nil {
+			log.Printf("Failed loading global config from %s; error: %s", globalConfig, err)
+			return
+		}
+	}
+
 	log.Println("Loading proxy configs")
 
 	cfgs, err := infrared.LoadProxyConfigsFromPath(configPath, false)
@@ -83,7 +157,23 @@ func main() {
 		}
 	}()
 
-	gateway := infrared.Gateway{}
+	callbacks, err := infrared.NewCallbackDispatcher(callbackSpool)
+	if err != nil {
+		log.Printf("Failed loading callback spool from %s; error: %s", callbackSpool, err)
+		return
+	}
+
+	gateway := infrared.Gateway{
+		StatusCache: infrared.NewStatusCache(statusCachePath),
+		Callbacks:   callbacks,
+
+		CallbackServers: global.CallbackServers,
+		Sinks:           global.Sinks,
+	}
+	if apiListen != "" {
+		go serveAPI(apiListen, &gateway)
+	}
+
 	go func() {
 		for {
 			cfg, ok := <-outCfgs
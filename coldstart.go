@@ -0,0 +1,102 @@
+package infrared
+
+import (
+	"errors"
+	"log"
+	"net"
+	"time"
+
+	"github.com/haveachin/infrared/protocol/handshaking"
+	"github.com/haveachin/infrared/protocol/status"
+)
+
+const (
+	// ColdStartModeHold keeps the login of a client open while the server starts
+	ColdStartModeHold = "hold"
+
+	// maxColdStartWait stays below the 30 seconds that clients wait for
+	// an answer to their Login Start before they give up on their own
+	maxColdStartWait = 28 * time.Second
+	// minColdStartPollInterval keeps the server from being dialed in a busy loop
+	minColdStartPollInterval = 50 * time.Millisecond
+)
+
+var (
+	ErrColdStartTimeout  = errors.New("server did not start in time")
+	ErrColdStartCanceled = errors.New("stopped waiting for the server")
+)
+
+// waitForServer starts the server process and polls the server until it accepts
+// connections, until maxWait is over or until done is closed. In the hold mode the
+// caller has already read the handshake to replay it to the server afterwards, but
+// the Login Start stays unread in the connection of the client until then.
+func (proxy *Proxy) waitForServer(hs handshaking.ServerBoundHandshake, connRemoteAddr net.Addr, maxWait time.Duration, done <-chan struct{}) (Conn, string, error) {
+	if err := proxy.startProcessIfNotRunning(); err != nil {
+		return nil, "", err
+	}
+
+	deadline := time.Now().Add(maxWait)
+	log.Printf("[i] Holding %s until %s is online", connRemoteAddr, proxy.ProxyTo())
+
+	for time.Now().Before(deadline) {
+		select {
+		case <-done:
+			return nil, "", ErrColdStartCanceled
+		case <-time.After(proxy.ColdStartPollInterval()):
+		}
+
+		// The health checker might not have noticed yet that the server is up
+		rconn, backend, err := proxy.dialFirst(proxy.backendOrder(""))
+		if err != nil {
+			continue
+		}
+
+		if proxy.ColdStartPingStatus() {
+			err = proxy.pingServer(rconn, hs, connRemoteAddr)
+			rconn.Close()
+			if err != nil {
+				continue
+			}
+
+			rconn, err = DialTimeout(backend, proxy.Timeout())
+			if err != nil {
+				return nil, "", err
+			}
+		}
+
+		proxy.markBackendUp(backend)
+		return rconn, backend, nil
+	}
+
+	return nil, "", ErrColdStartTimeout
+}
+
+// pingServer checks that the server answers a status request
+func (proxy *Proxy) pingServer(rconn Conn, hs handshaking.ServerBoundHandshake, connRemoteAddr net.Addr) error {
+	if err := rconn.SetDeadline(time.Now().Add(proxy.Timeout())); err != nil {
+		return err
+	}
+
+	if proxy.ProxyProtocol() {
+		if err := writeProxyProtocolHeader(rconn, connRemoteAddr); err != nil {
+			return err
+		}
+	}
+
+	hs.NextState = handshaking.ServerBoundHandshakeStatusState
+	if err := rconn.WritePacket(hs.Marshal()); err != nil {
+		return err
+	}
+
+	if err := rconn.WritePacket(status.ServerBoundRequest{}.Marshal()); err != nil {
+		return err
+	}
+
+	pk, err := rconn.ReadPacket()
+	if err != nil {
+		return err
+	}
+
+	_, err = status.UnmarshalClientBoundResponse(pk)
+	return err
+}
@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/fsnotify/fsnotify"
+	"github.com/haveachin/infrared/callback"
 	"github.com/haveachin/infrared/process"
 	"github.com/haveachin/infrared/protocol"
 	"github.com/haveachin/infrared/protocol/status"
@@ -27,17 +28,62 @@ type ProxyConfig struct {
 	changeCallback func()
 	process        process.Process
 
-	DomainName        string               `json:"domainName"`
-	ListenTo          string               `json:"listenTo"`
-	ProxyTo           string               `json:"proxyTo"`
-	ProxyProtocol     bool                 `json:"proxyProtocol"`
-	RealIP            bool                 `json:"realIp"`
-	Timeout           int                  `json:"timeout"`
-	DisconnectMessage string               `json:"disconnectMessage"`
-	Docker            DockerConfig         `json:"docker"`
-	OnlineStatus      StatusConfig         `json:"onlineStatus"`
-	OfflineStatus     StatusConfig         `json:"offlineStatus"`
-	CallbackServer    CallbackServerConfig `json:"callbackServer"`
+	DomainName        string                 `json:"domainName"`
+	DomainNames       StringList             `json:"domainNames"`
+	ListenTo          StringList             `json:"listenTo"`
+	ProxyTo           StringList             `json:"proxyTo"`
+	LoadBalancer      LoadBalancerConfig     `json:"loadBalancer"`
+	HealthCheck       HealthCheckConfig      `json:"healthCheck"`
+	Default           bool                   `json:"default"`
+	ProxyProtocol     bool                   `json:"proxyProtocol"`
+	RealIP            bool                   `json:"realIp"`
+	OnlineMode        bool                   `json:"onlineMode"`
+	SessionServerURL  string                 `json:"sessionServerUrl"`
+	Timeout           int                    `json:"timeout"`
+	DisconnectMessage ChatMessage            `json:"disconnectMessage"`
+	Forwarding        ForwardingConfig       `json:"forwarding"`
+	Docker            DockerConfig           `json:"docker"`
+	OnlineStatus      StatusConfig           `json:"onlineStatus"`
+	OfflineStatus     StatusConfig           `json:"offlineStatus"`
+	OverrideStatus    StatusConfig           `json:"overrideStatus"`
+	StatusCache       StatusCacheConfig      `json:"statusCache"`
+	ColdStart         ColdStartConfig        `json:"coldStart"`
+	CallbackServer    CallbackServerConfig   `json:"callbackServer"`
+	CallbackServers   []CallbackServerConfig `json:"callbackServers"`
+	Sinks             []callback.SinkConfig  `json:"sinks"`
+}
+
+type ForwardingConfig struct {
+	Mode   string `json:"mode"`
+	Secret string `json:"secret"`
+}
+
+type LoadBalancerConfig struct {
+	Strategy string         `json:"strategy"`
+	Weights  map[string]int `json:"weights"`
+	Sticky   string         `json:"sticky"`
+}
+
+type HealthCheckConfig struct {
+	Enabled  bool `json:"enabled"`
+	Interval int  `json:"interval"`
+	Rise     int  `json:"rise"`
+	Fall     int  `json:"fall"`
+}
+
+type StatusCacheConfig struct {
+	Enabled      bool        `json:"enabled"`
+	TTL          int         `json:"ttl"`
+	SleepingMOTD ChatMessage `json:"sleepingMotd"`
+}
+
+type ColdStartConfig struct {
+	Mode         string      `json:"mode"`
+	MaxWait      int         `json:"maxWait"`
+	PollInterval int         `json:"pollInterval"`
+	PingStatus   bool        `json:"pingStatus"`
+	LimboMessage ChatMessage `json:"limboMessage"`
+	ReadyMessage ChatMessage `json:"readyMessage"`
 }
 
 type DockerConfig struct {
@@ -62,6 +108,71 @@ func (docker DockerConfig) IsPortainer() bool {
 		docker.Portainer.EndpointID != ""
 }
 
+// StringList is a list of strings that can also be configured as a single string
+type StringList []string
+
+// UnmarshalJSON accepts a single string as a list with one element
+func (list *StringList) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*list = nil
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*list = StringList{s}
+		return nil
+	}
+
+	var ss []string
+	if err := json.Unmarshal(data, &ss); err != nil {
+		return err
+	}
+
+	*list = ss
+	return nil
+}
+
+// domainNames returns domainName and domainNames without duplicates
+func (cfg *ProxyConfig) domainNames() []string {
+	var domainNames []string
+	seen := map[string]bool{}
+	for _, domainName := range append([]string{cfg.DomainName}, cfg.DomainNames...) {
+		if domainName == "" || seen[domainName] {
+			continue
+		}
+		seen[domainName] = true
+		domainNames = append(domainNames, domainName)
+	}
+	return domainNames
+}
+
+// ChatMessage is a message that is either configured as text with legacy
+// formatting codes like "&cOffline" or as a chat component JSON object
+type ChatMessage string
+
+// UnmarshalJSON keeps chat component objects as their raw JSON text
+func (msg *ChatMessage) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*msg = ChatMessage(text)
+		return nil
+	}
+
+	var component protocol.ChatComponent
+	if err := json.Unmarshal(data, &component); err != nil {
+		return err
+	}
+
+	*msg = ChatMessage(component.String())
+	return nil
+}
+
+// Component parses the message into a chat component
+func (msg ChatMessage) Component() protocol.ChatComponent {
+	return protocol.ParseChatComponent(string(msg))
+}
+
 type PlayerSample struct {
 	Name string `json:"name"`
 	UUID string `json:"uuid"`
@@ -76,7 +187,7 @@ type StatusConfig struct {
 	PlayersOnline  int            `json:"playersOnline"`
 	PlayerSamples  []PlayerSample `json:"playerSamples"`
 	IconPath       string         `json:"iconPath"`
-	MOTD           string         `json:"motd"`
+	MOTD           ChatMessage    `json:"motd"`
 }
 
 func (cfg StatusConfig) StatusResponsePacket() (protocol.Packet, error) {
@@ -102,9 +213,7 @@ func (cfg StatusConfig) StatusResponsePacket() (protocol.Packet, error) {
 			Online: cfg.PlayersOnline,
 			Sample: samples,
 		},
-		Description: status.DescriptionJSON{
-			Text: cfg.MOTD,
-		},
+		Description: cfg.MOTD.Component(),
 	}
 
 	if cfg.IconPath != "" {
@@ -128,6 +237,99 @@ func (cfg StatusConfig) StatusResponsePacket() (protocol.Packet, error) {
 	return packet, nil
 }
 
+// HasOverrides reports whether any field that can override a status response is set
+func (cfg StatusConfig) HasOverrides() bool {
+	return cfg.VersionName != "" ||
+		cfg.ProtocolNumber != 0 ||
+		cfg.MaxPlayers != 0 ||
+		cfg.IconPath != "" ||
+		cfg.MOTD != ""
+}
+
+// OverrideResponseJSON replaces the fields of a status response that are set in the config.
+// All other fields of the response, like the player count and samples, are left untouched.
+func (cfg StatusConfig) OverrideResponseJSON(responseJSON []byte) ([]byte, error) {
+	var response map[string]json.RawMessage
+	if err := json.Unmarshal(responseJSON, &response); err != nil {
+		return nil, err
+	}
+
+	if cfg.VersionName != "" || cfg.ProtocolNumber != 0 {
+		var version status.VersionJSON
+		if raw, ok := response["version"]; ok {
+			if err := json.Unmarshal(raw, &version); err != nil {
+				return nil, err
+			}
+		}
+
+		if cfg.VersionName != "" {
+			version.Name = cfg.VersionName
+		}
+
+		if cfg.ProtocolNumber != 0 {
+			version.Protocol = cfg.ProtocolNumber
+		}
+
+		if err := setRawJSON(response, "version", version); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.MaxPlayers != 0 {
+		players := map[string]json.RawMessage{}
+		if raw, ok := response["players"]; ok {
+			if err := json.Unmarshal(raw, &players); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := setRawJSON(players, "max", cfg.MaxPlayers); err != nil {
+			return nil, err
+		}
+
+		if err := setRawJSON(response, "players", players); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.MOTD != "" {
+		response["description"] = json.RawMessage(cfg.MOTD.Component().String())
+	}
+
+	if cfg.IconPath != "" {
+		img64, err := loadImageAndEncodeToBase64String(cfg.IconPath)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := setRawJSON(response, "favicon", fmt.Sprintf("data:image/png;base64,%s", img64)); err != nil {
+			return nil, err
+		}
+	}
+
+	return json.Marshal(response)
+}
+
+func setRawJSON(m map[string]json.RawMessage, key string, v interface{}) error {
+	bb, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	m[key] = bb
+	return nil
+}
+
+// LegacyStatusResponse builds the answer to a legacy server list ping
+func (cfg StatusConfig) LegacyStatusResponse() status.LegacyServerListPong {
+	return status.LegacyServerListPong{
+		ProtocolVersion: cfg.ProtocolNumber,
+		VersionName:     cfg.VersionName,
+		MOTD:            cfg.MOTD.Component().LegacyText(),
+		PlayersOnline:   cfg.PlayersOnline,
+		MaxPlayers:      cfg.MaxPlayers,
+	}
+}
+
 func loadImageAndEncodeToBase64String(path string) (string, error) {
 	if path == "" {
 		return "", nil
@@ -155,16 +357,59 @@ func loadImageAndEncodeToBase64String(path string) (string, error) {
 }
 
 type CallbackServerConfig struct {
-	URL    string   `json:"url"`
-	Events []string `json:"events"`
+	URL         string            `json:"url"`
+	Events      []string          `json:"events"`
+	Format      string            `json:"format"`
+	Template    string            `json:"template"`
+	ContentType string            `json:"contentType"`
+	Headers     map[string]string `json:"headers"`
+	Token       string            `json:"token"`
+	Secret      string            `json:"secret"`
+}
+
+// GlobalConfig configures Infrared itself instead of a single proxy
+type GlobalConfig struct {
+	// CallbackServers get the events of every proxy
+	CallbackServers []CallbackServerConfig `json:"callbackServers"`
+	// Sinks get the events of every proxy
+	Sinks []callback.SinkConfig `json:"sinks"`
+}
+
+// LoadGlobalConfigFromPath loads the GlobalConfig from a file
+func LoadGlobalConfigFromPath(path string) (GlobalConfig, error) {
+	var cfg GlobalConfig
+	bb, err := ioutil.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+
+	if err := json.Unmarshal(bb, &cfg); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
 }
 
 func DefaultProxyConfig() ProxyConfig {
 	return ProxyConfig{
 		DomainName:        "localhost",
-		ListenTo:          ":25565",
+		ListenTo:          StringList{":25565"},
+		SessionServerURL:  DefaultSessionServerURL,
 		Timeout:           1000,
 		DisconnectMessage: "Sorry {{username}}, but the server is offline.",
+		HealthCheck: HealthCheckConfig{
+			Interval: 5000,
+			Rise:     2,
+			Fall:     3,
+		},
+		StatusCache: StatusCacheConfig{
+			TTL: 86400000,
+		},
+		ColdStart: ColdStartConfig{
+			MaxWait:      25000,
+			PollInterval: 1000,
+			LimboMessage: "Starting the server, please wait...",
+			ReadyMessage: "The server is ready, please reconnect.",
+		},
 		Docker: DockerConfig{
 			DNSServer: "127.0.0.11",
 			Timeout:   300000,
@@ -318,23 +563,39 @@ func (cfg *ProxyConfig) onConfigWrite(event fsnotify.Event) {
 	cfg.changeCallback()
 }
 
+// NewProxyConfigFromJSON creates a ProxyConfig from JSON with the same
+// defaults as a config file. The config is not watched for changes.
+func NewProxyConfigFromJSON(bb []byte) (*ProxyConfig, error) {
+	var cfg ProxyConfig
+	if err := cfg.LoadFromJSON(bb); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
 // LoadFromPath loads the ProxyConfig from a file
 func (cfg *ProxyConfig) LoadFromPath(path string) error {
+	bb, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return cfg.LoadFromJSON(bb)
+}
+
+// LoadFromJSON loads the ProxyConfig from JSON. Fields that are
+// missing in the JSON are set to their default values.
+func (cfg *ProxyConfig) LoadFromJSON(bb []byte) error {
 	cfg.Lock()
 	defer cfg.Unlock()
 
 	var defaultCfg map[string]interface{}
-	bb, err := json.Marshal(DefaultProxyConfig())
+	defaultBB, err := json.Marshal(DefaultProxyConfig())
 	if err != nil {
 		return err
 	}
 
-	if err := json.Unmarshal(bb, &defaultCfg); err != nil {
-		return err
-	}
-
-	bb, err = ioutil.ReadFile(path)
-	if err != nil {
+	if err := json.Unmarshal(defaultBB, &defaultCfg); err != nil {
 		return err
 	}
 
@@ -344,6 +605,11 @@ func (cfg *ProxyConfig) LoadFromPath(path string) error {
 		return err
 	}
 
+	// The default domain name would be added to the configured domain names
+	if _, ok := loadedCfg["domainNames"]; ok {
+		delete(defaultCfg, "domainName")
+	}
+
 	for k, v := range loadedCfg {
 		defaultCfg[k] = v
 	}
@@ -0,0 +1,118 @@
+package infrared
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/haveachin/infrared/protocol/status"
+)
+
+func TestStatusConfig_OverrideResponseJSON(t *testing.T) {
+	backendJSON := `{
+		"version": {"name": "Paper 1.16.5", "protocol": 754},
+		"players": {"max": 100, "online": 3, "sample": [{"name": "Notch", "id": "069a79f4-44e9-4726-a5be-fca90e38aaf5"}]},
+		"description": {"text": "A Minecraft Server"},
+		"enforcesSecureChat": true
+	}`
+
+	cfg := StatusConfig{
+		VersionName: "Infrared",
+		MaxPlayers:  20,
+		MOTD:        "&cPowered by Infrared",
+	}
+
+	bb, err := cfg.OverrideResponseJSON([]byte(backendJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var response status.ResponseJSON
+	if err := json.Unmarshal(bb, &response); err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Version.Name != "Infrared" || response.Version.Protocol != 754 {
+		t.Errorf("version: got: %v; want: {Infrared 754}", response.Version)
+	}
+
+	if response.Players.Max != 20 || response.Players.Online != 3 || len(response.Players.Sample) != 1 {
+		t.Errorf("players: got: %v; want: max 20, online 3 and one sample", response.Players)
+	}
+
+	if response.Description.Text != "Powered by Infrared" || response.Description.Color != "red" {
+		t.Errorf("description: got: %v; want: red Powered by Infrared", response.Description)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(bb, &fields); err != nil {
+		t.Fatal(err)
+	}
+
+	if string(fields["enforcesSecureChat"]) != "true" {
+		t.Errorf("unknown fields were not passed through: %s", bb)
+	}
+}
+
+func TestProxyConfig_LoadFromPath_Lists(t *testing.T) {
+	tt := []struct {
+		name                string
+		json                string
+		expectedDomainNames []string
+		expectedListenTo    []string
+	}{
+		{
+			name:                "Defaults",
+			json:                `{"proxyTo": ":8080"}`,
+			expectedDomainNames: []string{"localhost"},
+			expectedListenTo:    []string{":25565"},
+		},
+		{
+			name:                "SingleValues",
+			json:                `{"domainName": "mc.example.com", "listenTo": ":25566"}`,
+			expectedDomainNames: []string{"mc.example.com"},
+			expectedListenTo:    []string{":25566"},
+		},
+		{
+			name:                "Lists",
+			json:                `{"domainNames": ["mc.example.com", "www.mc.example.com"], "listenTo": [":25565", "[::1]:25565"]}`,
+			expectedDomainNames: []string{"mc.example.com", "www.mc.example.com"},
+			expectedListenTo:    []string{":25565", "[::1]:25565"},
+		},
+		{
+			name:                "Both",
+			json:                `{"domainName": "mc.example.com", "domainNames": ["www.mc.example.com", "mc.example.com"]}`,
+			expectedDomainNames: []string{"mc.example.com", "www.mc.example.com"},
+			expectedListenTo:    []string{":25565"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			file, err := ioutil.TempFile("", "infrared-config")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(file.Name())
+
+			if _, err := file.WriteString(tc.json); err != nil {
+				t.Fatal(err)
+			}
+			file.Close()
+
+			var cfg ProxyConfig
+			if err := cfg.LoadFromPath(file.Name()); err != nil {
+				t.Fatal(err)
+			}
+
+			if domainNames := cfg.domainNames(); !equalStrings(domainNames, tc.expectedDomainNames) {
+				t.Errorf("domain names: got: %v; want: %v", domainNames, tc.expectedDomainNames)
+			}
+
+			if !equalStrings(cfg.ListenTo, tc.expectedListenTo) {
+				t.Errorf("listen to: got: %v; want: %v", cfg.ListenTo, tc.expectedListenTo)
+			}
+		})
+	}
+}
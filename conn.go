@@ -25,8 +25,9 @@ type PacketPeeker interface {
 type conn struct {
 	net.Conn
 
-	r *bufio.Reader
-	w io.Writer
+	r         *bufio.Reader
+	w         io.Writer
+	threshold int
 }
 
 type Listener struct {
@@ -54,14 +55,17 @@ type Conn interface {
 	PacketPeeker
 
 	Reader() *bufio.Reader
+	SetCompressionThreshold(threshold int)
+	SetCipher(ecoStream, decoStream cipher.Stream)
 }
 
 // wrapConn warp an net.Conn to infared.conn
 func wrapConn(c net.Conn) *conn {
 	return &conn{
-		Conn: c,
-		r:    bufio.NewReader(c),
-		w:    c,
+		Conn:      c,
+		r:         bufio.NewReader(c),
+		w:         c,
+		threshold: -1,
 	}
 }
 
@@ -95,17 +99,23 @@ func (c *conn) Write(b []byte) (int, error) {
 
 // ReadPacket read a Packet from Conn.
 func (c *conn) ReadPacket() (protocol.Packet, error) {
+	if c.threshold >= 0 {
+		return protocol.ReadCompressedPacket(c.r, c.threshold)
+	}
 	return protocol.ReadPacket(c.r)
 }
 
 // PeekPacket peeks a Packet from Conn.
 func (c *conn) PeekPacket() (protocol.Packet, error) {
+	if c.threshold >= 0 {
+		return protocol.PeekCompressedPacket(c.r, c.threshold)
+	}
 	return protocol.PeekPacket(c.r)
 }
 
 //WritePacket write a Packet to Conn.
 func (c *conn) WritePacket(p protocol.Packet) error {
-	pk, err := p.Marshal()
+	pk, err := p.MarshalCompressed(c.threshold)
 	if err != nil {
 		return err
 	}
@@ -113,18 +123,26 @@ func (c *conn) WritePacket(p protocol.Packet) error {
 	return err
 }
 
-// SetCipher sets the decode/encode stream for this Conn
+// SetCipher sets the decode/encode stream for this Conn.
+// Data that is already buffered will also be decoded.
 func (c *conn) SetCipher(ecoStream, decoStream cipher.Stream) {
 	c.r = bufio.NewReader(cipher.StreamReader{
 		S: decoStream,
-		R: c.Conn,
+		R: c.r,
 	})
 	c.w = cipher.StreamWriter{
 		S: ecoStream,
-		W: c.Conn,
+		W: c.w,
 	}
 }
 
+// SetCompressionThreshold switches the Conn to the compressed packet format.
+// This should be called right after a Set Compression packet was sent or received.
+// A negative threshold switches the Conn back to the uncompressed packet format.
+func (c *conn) SetCompressionThreshold(threshold int) {
+	c.threshold = threshold
+}
+
 func (c *conn) Reader() *bufio.Reader {
 	return c.r
 }
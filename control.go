@@ -0,0 +1,189 @@
+package infrared
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrNoProcess = errors.New("proxy has no process")
+
+// Player is a player that is connected through a proxy
+type Player struct {
+	Username      string    `json:"username"`
+	Backend       string    `json:"backend"`
+	RemoteAddress string    `json:"remoteAddress"`
+	JoinedAt      time.Time `json:"joinedAt"`
+}
+
+// Proxies returns every registered proxy once, sorted by UID
+func (gateway *Gateway) Proxies() []*Proxy {
+	seen := map[*Proxy]bool{}
+	var proxies []*Proxy
+	gateway.proxies.Range(func(k, v interface{}) bool {
+		proxy := v.(*Proxy)
+		if !seen[proxy] {
+			seen[proxy] = true
+			proxies = append(proxies, proxy)
+		}
+		return true
+	})
+
+	sort.Slice(proxies, func(i, j int) bool {
+		return proxies[i].UID() < proxies[j].UID()
+	})
+	return proxies
+}
+
+// Proxy returns the proxy that is registered with the UID
+func (gateway *Gateway) Proxy(proxyUID string) (*Proxy, bool) {
+	v, ok := gateway.proxies.Load(proxyUID)
+	if !ok {
+		return nil, false
+	}
+	return v.(*Proxy), true
+}
+
+// UnregisterProxy closes every UID that the proxy is registered with
+func (gateway *Gateway) UnregisterProxy(proxy *Proxy) {
+	var proxyUIDs []string
+	gateway.proxies.Range(func(k, v interface{}) bool {
+		if v.(*Proxy) == proxy {
+			proxyUIDs = append(proxyUIDs, k.(string))
+		}
+		return true
+	})
+
+	for _, proxyUID := range proxyUIDs {
+		gateway.CloseProxy(proxyUID)
+	}
+	proxy.stopHealthCheck()
+	gateway.closeUnusedSinks(proxy.sinkPool)
+}
+
+// IsListening checks if the gateway has at least one open listener
+func (gateway *Gateway) IsListening() bool {
+	listening := false
+	gateway.listeners.Range(func(k, v interface{}) bool {
+		listening = true
+		return false
+	})
+	return listening
+}
+
+// Players returns the players of the proxy and of the proxies of its matched domains,
+// sorted by username
+func (proxy *Proxy) Players() []Player {
+	var players []Player
+	for _, p := range proxy.withDomainProxies() {
+		p.mu.Lock()
+		for _, player := range p.players {
+			players = append(players, Player{
+				Username:      player.username,
+				Backend:       player.backend,
+				RemoteAddress: player.remoteAddress,
+				JoinedAt:      player.joinedAt,
+			})
+		}
+		p.mu.Unlock()
+	}
+
+	sort.Slice(players, func(i, j int) bool {
+		return players[i].Username < players[j].Username
+	})
+	return players
+}
+
+// KickPlayer closes the connections of the players with the username
+// and returns how many connections were closed
+func (proxy *Proxy) KickPlayer(username string) int {
+	var conns []Conn
+	for _, p := range proxy.withDomainProxies() {
+		p.mu.Lock()
+		for conn, player := range p.players {
+			if strings.EqualFold(player.username, username) {
+				conns = append(conns, conn)
+			}
+		}
+		p.mu.Unlock()
+	}
+
+	for _, conn := range conns {
+		log.Printf("[i] Kicking %s from %s", username, proxy.UID())
+		_ = conn.Close()
+	}
+	return len(conns)
+}
+
+func (proxy *Proxy) withDomainProxies() []*Proxy {
+	proxy.mu.Lock()
+	defer proxy.mu.Unlock()
+	proxies := []*Proxy{proxy}
+	for _, entry := range proxy.domainProxies {
+		proxies = append(proxies, entry.proxy)
+	}
+	return proxies
+}
+
+func (proxy *Proxy) playerCount() int {
+	proxy.mu.Lock()
+	defer proxy.mu.Unlock()
+	return len(proxy.players)
+}
+
+// StartProcess starts the process of the proxy. Unless the process is pinned,
+// it is stopped after the Docker timeout again if no player joins.
+func (proxy *Proxy) StartProcess() error {
+	if proxy.Process() == nil {
+		return ErrNoProcess
+	}
+
+	if err := proxy.startProcessIfNotRunning(); err != nil {
+		return err
+	}
+
+	if proxy.playerCount() <= 0 {
+		proxy.timeoutProcess()
+	}
+	return nil
+}
+
+// StopProcess stops the process of the proxy, even if players are still connected
+func (proxy *Proxy) StopProcess() error {
+	if proxy.Process() == nil {
+		return ErrNoProcess
+	}
+
+	proxy.cancelProcessTimeout()
+	return proxy.stopProcess()
+}
+
+// PinProcess keeps the process of the proxy awake. A pinned process is started
+// and is not stopped after the Docker timeout until it is unpinned.
+func (proxy *Proxy) PinProcess(pinned bool) error {
+	if proxy.Process() == nil {
+		return ErrNoProcess
+	}
+
+	proxy.mu.Lock()
+	proxy.processPinned = pinned
+	proxy.mu.Unlock()
+
+	if !pinned {
+		if proxy.playerCount() <= 0 {
+			proxy.timeoutProcess()
+		}
+		return nil
+	}
+
+	proxy.cancelProcessTimeout()
+	return proxy.startProcessIfNotRunning()
+}
+
+func (proxy *Proxy) IsProcessPinned() bool {
+	proxy.mu.Lock()
+	defer proxy.mu.Unlock()
+	return proxy.processPinned
+}
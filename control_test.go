@@ -0,0 +1,49 @@
+package infrared
+
+import (
+	"net"
+	"testing"
+)
+
+func TestProxy_KickPlayer(t *testing.T) {
+	proxy := &Proxy{Config: &ProxyConfig{
+		DomainNames: StringList{"control.example.com"},
+		ListenTo:    StringList{":25565"},
+	}}
+
+	steve, steveRemote := net.Pipe()
+	defer steveRemote.Close()
+	alex, alexRemote := net.Pipe()
+	defer alex.Close()
+	defer alexRemote.Close()
+
+	proxy.addPlayer(wrapConn(steve), &net.TCPAddr{}, "Steve", "localhost:25566")
+	proxy.addPlayer(wrapConn(alex), &net.TCPAddr{}, "Alex", "localhost:25566")
+
+	players := proxy.Players()
+	if len(players) != 2 || players[0].Username != "Alex" || players[1].Username != "Steve" {
+		t.Errorf("got: %v, want: Alex and Steve", players)
+	}
+
+	if n := proxy.KickPlayer("steve"); n != 1 {
+		t.Errorf("got: %v, want: %v", n, 1)
+	}
+
+	if _, err := steveRemote.Write([]byte{0}); err == nil {
+		t.Error("the connection of a kicked player should be closed")
+	}
+
+	if n := proxy.KickPlayer("Herobrine"); n != 0 {
+		t.Errorf("got: %v, want: %v", n, 0)
+	}
+}
+
+func TestProxy_ProcessWithoutDocker(t *testing.T) {
+	proxy := &Proxy{Config: &ProxyConfig{}}
+
+	for _, err := range []error{proxy.StartProcess(), proxy.StopProcess(), proxy.PinProcess(true)} {
+		if err != ErrNoProcess {
+			t.Errorf("got: %v, want: %v", err, ErrNoProcess)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package infrared
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// domainWildcard matches a single label of a domain name
+	domainWildcard = "*"
+	// domainRegexpPrefix marks a domain name as a regular expression
+	domainRegexpPrefix = "~"
+
+	// maxDomainProxies limits how many matched domains of a proxy have their own proxy
+	maxDomainProxies = 1024
+	// domainProxyTTL is how long the proxy of a domain without players is kept after its last use
+	domainProxyTTL = 10 * time.Minute
+)
+
+var (
+	ErrInvalidDomainCapture = errors.New("domain capture is not a valid domain label")
+	ErrTooManyDomainProxies = errors.New("too many domains with players")
+)
+
+var (
+	// domainLabelPattern matches the captures that may be templated into backend addresses,
+	// load balancer weights and container names
+	domainLabelPattern = regexp.MustCompile(`^[a-z0-9-]{1,63}$`)
+	// domainNamePattern matches the whole domain that is templated as {0}
+	domainNamePattern = regexp.MustCompile(`^[a-z0-9-]{1,63}(\.[a-z0-9-]{1,63})*$`)
+)
+
+// domainProxyEntry is a proxy of a matched domain and when it was last used
+type domainProxyEntry struct {
+	proxy    *Proxy
+	lastUsed time.Time
+}
+
+// isDomainPattern checks if the domain name matches more than one domain
+func isDomainPattern(domainName string) bool {
+	return isDomainRegexp(domainName) || strings.Contains(domainName, domainWildcard)
+}
+
+func isDomainRegexp(domainName string) bool {
+	return strings.HasPrefix(domainName, domainRegexpPrefix)
+}
+
+// compileDomainPattern compiles a domain name with wildcards or a regular expression
+// into a case insensitive regexp that has to match the whole domain. Every wildcard
+// is a capture group.
+func compileDomainPattern(domainName string) (*regexp.Regexp, error) {
+	if isDomainRegexp(domainName) {
+		return regexp.Compile(fmt.Sprintf("(?i)^(?:%s)$", strings.TrimPrefix(domainName, domainRegexpPrefix)))
+	}
+
+	labels := strings.Split(domainName, domainWildcard)
+	for i, label := range labels {
+		labels[i] = regexp.QuoteMeta(label)
+	}
+	return regexp.Compile(fmt.Sprintf("(?i)^%s$", strings.Join(labels, "([^.]+)")))
+}
+
+// validateDomainCaptures makes sure that the captures can't inject anything but domain labels
+// into backend addresses and container names, since the domain is chosen by the client
+func validateDomainCaptures(captures []string) error {
+	for i, capture := range captures {
+		pattern := domainLabelPattern
+		if i == 0 {
+			pattern = domainNamePattern
+		}
+
+		if len(capture) > 253 || !pattern.MatchString(capture) {
+			return ErrInvalidDomainCapture
+		}
+	}
+	return nil
+}
+
+// templateDomainCaptures replaces {0} with the whole domain and {1}, {2}, ... with
+// the captures of the domain pattern
+func templateDomainCaptures(s string, captures []string) string {
+	for i, capture := range captures {
+		s = strings.Replace(s, fmt.Sprintf("{%d}", i), capture, -1)
+	}
+	return s
+}
+
+// matchDomain returns the domain pattern of the proxy that matches the domain and its captures.
+// If multiple domain patterns of the proxy match, the one with priority is returned.
+func (proxy *Proxy) matchDomain(domain string) (string, []string, bool) {
+	var matchedPattern string
+	var matchedCaptures []string
+	for _, domainName := range proxy.DomainNames() {
+		if !isDomainPattern(domainName) {
+			continue
+		}
+
+		pattern, err := proxy.compiledDomainPattern(domainName)
+		if err != nil {
+			continue
+		}
+
+		captures := pattern.FindStringSubmatch(domain)
+		if captures == nil {
+			continue
+		}
+
+		if matchedCaptures == nil || domainPatternHasPriority(domainName, matchedPattern) {
+			matchedPattern = domainName
+			matchedCaptures = captures
+		}
+	}
+
+	return matchedPattern, matchedCaptures, matchedCaptures != nil
+}
+
+// compiledDomainPattern compiles every domain pattern only once
+func (proxy *Proxy) compiledDomainPattern(domainName string) (*regexp.Regexp, error) {
+	proxy.mu.Lock()
+	defer proxy.mu.Unlock()
+	if pattern, ok := proxy.domainPatterns[domainName]; ok {
+		return pattern, nil
+	}
+
+	pattern, err := compileDomainPattern(domainName)
+	if err != nil {
+		return nil, err
+	}
+
+	if proxy.domainPatterns == nil {
+		proxy.domainPatterns = map[string]*regexp.Regexp{}
+	}
+	proxy.domainPatterns[domainName] = pattern
+	return pattern, nil
+}
+
+// domainProxy returns the proxy for a domain that matches the domain pattern of the proxy.
+// Every domain gets its own proxy, so that players and processes of the domains are kept apart.
+// At most maxDomainProxies are kept; idle proxies are dropped after domainProxyTTL
+// or, if there are too many, in the order of their last use.
+func (proxy *Proxy) domainProxy(domain string) (*Proxy, error) {
+	domain = strings.ToLower(domain)
+	_, captures, ok := proxy.matchDomain(domain)
+	if !ok {
+		return nil, fmt.Errorf("domain %s does not match any domain pattern of %s", domain, proxy.UID())
+	}
+
+	if err := validateDomainCaptures(captures); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	proxy.mu.Lock()
+	defer proxy.mu.Unlock()
+	if entry, ok := proxy.domainProxies[domain]; ok {
+		entry.lastUsed = now
+		return entry.proxy, nil
+	}
+
+	if !proxy.evictDomainProxies(now) {
+		return nil, ErrTooManyDomainProxies
+	}
+
+	domainProxy, err := proxy.newDomainProxy(domain, captures)
+	if err != nil {
+		return nil, err
+	}
+
+	if proxy.domainProxies == nil {
+		proxy.domainProxies = map[string]*domainProxyEntry{}
+	}
+	proxy.domainProxies[domain] = &domainProxyEntry{
+		proxy:    domainProxy,
+		lastUsed: now,
+	}
+	return domainProxy, nil
+}
+
+// evictDomainProxies drops the expired proxies of idle domains and, if there is
+// still no room for another one, the least recently used of them. It reports whether there
+// is room. Callers have to hold the lock of the proxy.
+func (proxy *Proxy) evictDomainProxies(now time.Time) bool {
+	var oldestDomain string
+	var oldest *domainProxyEntry
+	for domain, entry := range proxy.domainProxies {
+		if !entry.proxy.isIdle() {
+			continue
+		}
+
+		if now.Sub(entry.lastUsed) >= domainProxyTTL {
+			delete(proxy.domainProxies, domain)
+			continue
+		}
+
+		if oldest == nil || entry.lastUsed.Before(oldest.lastUsed) {
+			oldestDomain = domain
+			oldest = entry
+		}
+	}
+
+	if len(proxy.domainProxies) < maxDomainProxies {
+		return true
+	}
+
+	if oldest == nil {
+		return false
+	}
+
+	delete(proxy.domainProxies, oldestDomain)
+	return true
+}
+
+// newDomainProxy copies the config of the proxy for a domain and templates
+// the captures of the domain into the backend addresses and container name
+func (proxy *Proxy) newDomainProxy(domain string, captures []string) (*Proxy, error) {
+	proxy.Config.RLock()
+	bb, err := json.Marshal(proxy.Config)
+	proxy.Config.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg ProxyConfig
+	if err := json.Unmarshal(bb, &cfg); err != nil {
+		return nil, err
+	}
+
+	cfg.DomainName = domain
+	cfg.DomainNames = nil
+	backendTemplates := map[string]string{}
+	for i, backend := range cfg.ProxyTo {
+		cfg.ProxyTo[i] = templateDomainCaptures(backend, captures)
+		backendTemplates[cfg.ProxyTo[i]] = backend
+	}
+	weights := map[string]int{}
+	for backend, weight := range cfg.LoadBalancer.Weights {
+		weights[templateDomainCaptures(backend, captures)] = weight
+	}
+	cfg.LoadBalancer.Weights = weights
+	cfg.Docker.ContainerName = templateDomainCaptures(cfg.Docker.ContainerName, captures)
+	cfg.removeCallback = func() {}
+	cfg.changeCallback = func() {}
+
+	return &Proxy{
+		Config:      &cfg,
+		statusCache: proxy.statusCache,
+		callbacks:   proxy.callbacks,
+		patternUID:  proxy.UID(),
+
+		globalCallbacks:  proxy.globalCallbacks,
+		sinkPool:         proxy.sinkPool,
+		globalSinks:      proxy.globalSinks,
+		backendTemplates: backendTemplates,
+	}, nil
+}
+
+// resetDomainProxies drops the proxies of matched domains, so that
+// they are created again from the current config
+func (proxy *Proxy) resetDomainProxies() {
+	proxy.mu.Lock()
+	domainProxies := proxy.domainProxies
+	proxy.domainProxies = nil
+	proxy.mu.Unlock()
+
+	// The proxies of the new config would not know about the timers,
+	// which could stop the container while they have players
+	for _, entry := range domainProxies {
+		entry.proxy.cancelProcessTimeout()
+	}
+}
+
+// isIdle checks if the proxy has no players and no container that waits to be stopped.
+// The proxy of an idle domain can be dropped without affecting the players of a new one.
+func (proxy *Proxy) isIdle() bool {
+	proxy.mu.Lock()
+	defer proxy.mu.Unlock()
+	return len(proxy.players) == 0 && proxy.processTimer == nil
+}
+
+// domainPatternHasPriority decides which of two matching domain patterns is used.
+// Wildcards are preferred over regular expressions and longer patterns
+// over shorter ones, since they are usually more specific.
+func domainPatternHasPriority(domainName, otherDomainName string) bool {
+	if isDomainRegexp(domainName) != isDomainRegexp(otherDomainName) {
+		return !isDomainRegexp(domainName)
+	}
+
+	if len(domainName) != len(otherDomainName) {
+		return len(domainName) > len(otherDomainName)
+	}
+
+	return domainName < otherDomainName
+}
@@ -0,0 +1,220 @@
+package infrared
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestCompileDomainPattern(t *testing.T) {
+	tt := []struct {
+		domainName       string
+		domain           string
+		expectedCaptures []string
+	}{
+		{
+			domainName:       "*.play.example.com",
+			domain:           "alice.play.example.com",
+			expectedCaptures: []string{"alice.play.example.com", "alice"},
+		},
+		{
+			domainName:       "*.play.example.com",
+			domain:           "ALICE.Play.Example.com",
+			expectedCaptures: []string{"ALICE.Play.Example.com", "ALICE"},
+		},
+		{
+			domainName: "*.play.example.com",
+			domain:     "a.b.play.example.com",
+		},
+		{
+			domainName: "*.play.example.com",
+			domain:     "playxexample.com",
+		},
+		{
+			domainName:       "*-*.example.com",
+			domain:           "alice-survival.example.com",
+			expectedCaptures: []string{"alice-survival.example.com", "alice", "survival"},
+		},
+		{
+			domainName:       `~(\w+)\.(eu|us)\.example\.com`,
+			domain:           "alice.eu.example.com",
+			expectedCaptures: []string{"alice.eu.example.com", "alice", "eu"},
+		},
+		{
+			domainName: `~(\w+)\.(eu|us)\.example\.com`,
+			domain:     "alice.eu.example.com.evil.com",
+		},
+	}
+
+	for _, tc := range tt {
+		pattern, err := compileDomainPattern(tc.domainName)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.domainName, err)
+		}
+
+		captures := pattern.FindStringSubmatch(tc.domain)
+		if len(captures) != len(tc.expectedCaptures) {
+			t.Errorf("%s on %s: got: %v; want: %v", tc.domainName, tc.domain, captures, tc.expectedCaptures)
+			continue
+		}
+
+		for i := range captures {
+			if captures[i] != tc.expectedCaptures[i] {
+				t.Errorf("%s on %s: got: %v; want: %v", tc.domainName, tc.domain, captures, tc.expectedCaptures)
+				break
+			}
+		}
+	}
+}
+
+func TestTemplateDomainCaptures(t *testing.T) {
+	captures := []string{"alice.eu.example.com", "alice", "eu"}
+	tt := []struct {
+		template string
+		expected string
+	}{
+		{
+			template: "{1}.internal:25565",
+			expected: "alice.internal:25565",
+		},
+		{
+			template: "mc-{2}-{1}",
+			expected: "mc-eu-alice",
+		},
+		{
+			template: "{0}:25565",
+			expected: "alice.eu.example.com:25565",
+		},
+		{
+			template: "mc-{3}",
+			expected: "mc-{3}",
+		},
+	}
+
+	for _, tc := range tt {
+		if actual := templateDomainCaptures(tc.template, captures); actual != tc.expected {
+			t.Errorf("%s: got: %s; want: %s", tc.template, actual, tc.expected)
+		}
+	}
+}
+
+func TestProxy_domainProxy_Captures(t *testing.T) {
+	proxy := &Proxy{Config: &ProxyConfig{
+		DomainNames: StringList{"*.example.com", `~(.+)\.example\.net`},
+		ProxyTo:     StringList{"{1}.internal:25565"},
+	}}
+
+	tt := []struct {
+		domain      string
+		expectedErr error
+	}{
+		{
+			domain: "Alice-1.example.com",
+		},
+		{
+			domain:      "alice_1.example.com",
+			expectedErr: ErrInvalidDomainCapture,
+		},
+		{
+			domain:      "evil:1@.example.com",
+			expectedErr: ErrInvalidDomainCapture,
+		},
+		{
+			domain:      "evil.internal:25566#.example.net",
+			expectedErr: ErrInvalidDomainCapture,
+		},
+		{
+			domain:      "a.b.example.net",
+			expectedErr: ErrInvalidDomainCapture,
+		},
+	}
+
+	for _, tc := range tt {
+		if _, err := proxy.domainProxy(tc.domain); err != tc.expectedErr {
+			t.Errorf("%s: got: %v; want: %v", tc.domain, err, tc.expectedErr)
+		}
+	}
+}
+
+func TestProxy_domainProxy_Eviction(t *testing.T) {
+	proxy := &Proxy{Config: &ProxyConfig{
+		DomainName: "*.example.com",
+		ProxyTo:    StringList{"{1}.internal:25565"},
+	}}
+
+	busy, err := proxy.domainProxy("busy.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, remote := net.Pipe()
+	defer conn.Close()
+	defer remote.Close()
+	busy.addPlayer(wrapConn(conn), &net.TCPAddr{}, "Notch", "busy.internal:25565")
+
+	// The container of a domain that waits to be stopped keeps its proxy
+	stopping, err := proxy.domainProxy("stopping.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stopping.processTimer = time.AfterFunc(time.Hour, func() {})
+	defer stopping.cancelProcessTimeout()
+	proxy.domainProxies["stopping.example.com"].lastUsed = time.Now().Add(-domainProxyTTL)
+
+	expired, err := proxy.domainProxy("expired.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxy.domainProxies["expired.example.com"].lastUsed = time.Now().Add(-domainProxyTTL)
+	proxy.domainProxies["busy.example.com"].lastUsed = time.Now().Add(-domainProxyTTL)
+
+	start := time.Now()
+	for i := 0; i < maxDomainProxies+10; i++ {
+		domain := fmt.Sprintf("domain%d.example.com", i)
+		if _, err := proxy.domainProxy(domain); err != nil {
+			t.Fatal(err)
+		}
+		proxy.domainProxies[domain].lastUsed = start.Add(time.Duration(i) * time.Millisecond)
+	}
+
+	if len(proxy.domainProxies) > maxDomainProxies {
+		t.Errorf("got: %d domain proxies; want: at most %d", len(proxy.domainProxies), maxDomainProxies)
+	}
+
+	if p, _ := proxy.domainProxy("busy.example.com"); p != busy {
+		t.Error("proxy of a domain with players was dropped")
+	}
+
+	if p, _ := proxy.domainProxy("stopping.example.com"); p != stopping {
+		t.Error("proxy of a domain with a pending container stop was dropped")
+	}
+
+	if p, _ := proxy.domainProxy("expired.example.com"); p == expired {
+		t.Error("expired proxy was not dropped")
+	}
+
+	if _, ok := proxy.domainProxies["domain0.example.com"]; ok {
+		t.Error("least recently used proxy was not dropped")
+	}
+}
+
+func TestProxy_resetDomainProxies(t *testing.T) {
+	proxy := &Proxy{Config: &ProxyConfig{
+		DomainName: "*.example.com",
+		ProxyTo:    StringList{"{1}.internal:25565"},
+	}}
+
+	domainProxy, err := proxy.domainProxy("alice.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	timer := time.AfterFunc(time.Hour, func() {})
+	domainProxy.processTimer = timer
+
+	proxy.resetDomainProxies()
+
+	// The timer must not stop the container of the proxy that replaces the dropped one
+	if timer.Stop() {
+		t.Error("container stop of a dropped proxy is still pending")
+	}
+}
@@ -0,0 +1,134 @@
+package infrared
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net"
+
+	"github.com/gofrs/uuid"
+	"github.com/haveachin/infrared/protocol"
+	"github.com/haveachin/infrared/protocol/handshaking"
+	"github.com/haveachin/infrared/protocol/login"
+)
+
+const (
+	// ForwardingModeVelocity answers the backend's player info request with
+	// Velocity's modern forwarding format
+	ForwardingModeVelocity = "velocity"
+
+	// ForwardingModeBungeeCord rewrites the handshake with BungeeCord's legacy IP forwarding format
+	ForwardingModeBungeeCord = "bungeecord"
+
+	VelocityPlayerInfoChannel = "velocity:player_info"
+
+	// velocityModernForwardingVersion is the MODERN_DEFAULT forwarding version
+	// which carries the address, UUID, name and properties of a player
+	velocityModernForwardingVersion = 1
+)
+
+var ErrMissingForwardingSecret = errors.New("forwarding secret is missing")
+
+// OfflinePlayerUUID computes the UUID that an offline-mode server assigns to a
+// player, which is a name based UUID (version 3) of "OfflinePlayer:<username>"
+func OfflinePlayerUUID(username string) uuid.UUID {
+	u := uuid.UUID(md5.Sum([]byte("OfflinePlayer:" + username)))
+	u.SetVersion(uuid.V3)
+	u.SetVariant(uuid.VariantRFC4122)
+	return u
+}
+
+// OfflineGameProfile returns the profile of a player that joins an offline-mode server
+func OfflineGameProfile(username string) GameProfile {
+	return GameProfile{
+		ID:   OfflinePlayerUUID(username).String(),
+		Name: username,
+	}
+}
+
+// VelocityForwardingData builds the signed payload of a
+// Login Plugin Response to Velocity's player info request
+func VelocityForwardingData(secret []byte, remoteAddr net.Addr, profile GameProfile) ([]byte, error) {
+	if len(secret) == 0 {
+		return nil, ErrMissingForwardingSecret
+	}
+
+	id, err := uuid.FromString(profile.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	host, _, err := net.SplitHostPort(remoteAddr.String())
+	if err != nil {
+		host = remoteAddr.String()
+	}
+
+	var data []byte
+	data = append(data, protocol.VarInt(velocityModernForwardingVersion).Encode()...)
+	data = append(data, protocol.String(host).Encode()...)
+	data = append(data, protocol.UUID(id).Encode()...)
+	data = append(data, protocol.String(profile.Name).Encode()...)
+	data = append(data, protocol.VarInt(len(profile.Properties)).Encode()...)
+	for _, property := range profile.Properties {
+		data = append(data, protocol.String(property.Name).Encode()...)
+		data = append(data, protocol.String(property.Value).Encode()...)
+		hasSignature := property.Signature != ""
+		data = append(data, protocol.Boolean(hasSignature).Encode()...)
+		if hasSignature {
+			data = append(data, protocol.String(property.Signature).Encode()...)
+		}
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(data)
+
+	return append(mac.Sum(nil), data...), nil
+}
+
+// upgradeToBungeeCord rewrites the handshake with the address and profile of the player
+func upgradeToBungeeCord(hs *handshaking.ServerBoundHandshake, remoteAddr net.Addr, profile GameProfile) error {
+	id, err := uuid.FromString(profile.ID)
+	if err != nil {
+		return err
+	}
+
+	var properties string
+	if len(profile.Properties) > 0 {
+		bb, err := json.Marshal(profile.Properties)
+		if err != nil {
+			return err
+		}
+		properties = string(bb)
+	}
+
+	hs.UpgradeToBungeeCord(remoteAddr, hex.EncodeToString(id.Bytes()), properties)
+	return nil
+}
+
+// forwardVelocity waits for the backend's player info request and answers it.
+// If the backend sends any other packet it is passed on to the client.
+func (proxy *Proxy) forwardVelocity(conn, rconn Conn, remoteAddr net.Addr, profile GameProfile) error {
+	pk, err := rconn.ReadPacket()
+	if err != nil {
+		return err
+	}
+
+	request, err := login.UnmarshalClientBoundLoginPluginRequest(pk)
+	if err != nil || request.Channel != VelocityPlayerInfoChannel {
+		return conn.WritePacket(pk)
+	}
+
+	data, err := VelocityForwardingData([]byte(proxy.ForwardingSecret()), remoteAddr, profile)
+	if err != nil {
+		return err
+	}
+
+	return rconn.WritePacket(login.ServerBoundLoginPluginResponse{
+		MessageID:  request.MessageID,
+		Successful: true,
+		Data:       data,
+	}.Marshal())
+}
@@ -0,0 +1,165 @@
+package infrared
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"net"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/haveachin/infrared/protocol"
+	"github.com/haveachin/infrared/protocol/handshaking"
+	"github.com/haveachin/infrared/protocol/login"
+)
+
+func TestOfflinePlayerUUID(t *testing.T) {
+	tt := []struct {
+		username string
+		expected string
+	}{
+		{
+			username: "Notch",
+			expected: "b50ad385-829d-3141-a216-7e7d7539ba7f",
+		},
+	}
+
+	for _, tc := range tt {
+		if actual := OfflinePlayerUUID(tc.username).String(); actual != tc.expected {
+			t.Errorf("%s: got: %s; want: %s", tc.username, actual, tc.expected)
+		}
+	}
+}
+
+func TestVelocityForwardingData(t *testing.T) {
+	secret := []byte("super-secret")
+	remoteAddr := &net.TCPAddr{IP: net.IPv4(109, 226, 143, 210), Port: 52341}
+	profile := GameProfile{
+		ID:   "069a79f444e94726a5befca90e38aaf5",
+		Name: "Notch",
+		Properties: []GameProfileProperty{
+			{
+				Name:      "textures",
+				Value:     "e30=",
+				Signature: "c2ln",
+			},
+		},
+	}
+
+	data, err := VelocityForwardingData(secret, remoteAddr, profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signature, payload := data[:sha256.Size], data[sha256.Size:]
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		t.Error("invalid signature")
+	}
+
+	var (
+		version                     protocol.VarInt
+		address, name               protocol.String
+		id                          protocol.UUID
+		propertyCount               protocol.VarInt
+		propertyName, propertyValue protocol.String
+		hasSignature                protocol.Boolean
+		propertySignature           protocol.String
+	)
+	if err := protocol.ScanFields(
+		bytes.NewReader(payload),
+		&version,
+		&address,
+		&id,
+		&name,
+		&propertyCount,
+		&propertyName,
+		&propertyValue,
+		&hasSignature,
+		&propertySignature,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	if version != velocityModernForwardingVersion {
+		t.Errorf("version: got: %d; want: %d", version, velocityModernForwardingVersion)
+	}
+
+	if address != "109.226.143.210" {
+		t.Errorf("address: got: %s; want: %s", address, "109.226.143.210")
+	}
+
+	if uuid.UUID(id).String() != "069a79f4-44e9-4726-a5be-fca90e38aaf5" {
+		t.Errorf("uuid: got: %s; want: %s", uuid.UUID(id), "069a79f4-44e9-4726-a5be-fca90e38aaf5")
+	}
+
+	if name != "Notch" || propertyCount != 1 || propertyName != "textures" ||
+		propertyValue != "e30=" || !hasSignature || propertySignature != "c2ln" {
+		t.Errorf("profile does not match: %v %v %v %v %v %v", name, propertyCount,
+			propertyName, propertyValue, hasSignature, propertySignature)
+	}
+
+	if _, err := VelocityForwardingData(nil, remoteAddr, profile); err != ErrMissingForwardingSecret {
+		t.Errorf("got: %v; want: %v", err, ErrMissingForwardingSecret)
+	}
+}
+
+func TestProxy_ForwardVelocity(t *testing.T) {
+	proxy := &Proxy{Config: &ProxyConfig{
+		Forwarding: ForwardingConfig{
+			Mode:   ForwardingModeVelocity,
+			Secret: "super-secret",
+		},
+	}}
+
+	c1, c2 := net.Pipe()
+	proxyConn, backendConn := wrapConn(c1), wrapConn(c2)
+	defer proxyConn.Close()
+	defer backendConn.Close()
+
+	errCh := make(chan error, 1)
+	go func() {
+		remoteAddr := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1337}
+		errCh <- proxy.forwardVelocity(nil, proxyConn, remoteAddr, OfflineGameProfile("Notch"))
+	}()
+
+	if err := backendConn.WritePacket(login.ClientBoundLoginPluginRequest{
+		MessageID: 42,
+		Channel:   VelocityPlayerInfoChannel,
+	}.Marshal()); err != nil {
+		t.Fatal(err)
+	}
+
+	pk, err := backendConn.ReadPacket()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := login.UnmarshalServerBoundLoginPluginResponse(pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.MessageID != 42 || !response.Successful || len(response.Data) <= sha256.Size {
+		t.Errorf("unexpected response: %v", response)
+	}
+
+	if err := <-errCh; err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUpgradeToBungeeCord(t *testing.T) {
+	hs := handshaking.ServerBoundHandshake{ServerAddress: "example.com"}
+	remoteAddr := &net.TCPAddr{IP: net.IPv4(109, 226, 143, 210), Port: 52341}
+
+	if err := upgradeToBungeeCord(&hs, remoteAddr, OfflineGameProfile("Notch")); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "example.com\x00109.226.143.210\x00b50ad385829d3141a2167e7d7539ba7f"
+	if string(hs.ServerAddress) != expected {
+		t.Errorf("got: %q; want: %q", hs.ServerAddress, expected)
+	}
+}
@@ -1,20 +1,78 @@
 package infrared
 
 import (
+	"bufio"
+	"bytes"
 	"errors"
+	"io"
 	"log"
+	"net"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/haveachin/infrared/callback"
 	"github.com/haveachin/infrared/protocol/handshaking"
+	"github.com/haveachin/infrared/protocol/status"
 	"github.com/pires/go-proxyproto"
 )
 
+// legacyServerListPingTimeout is how long to wait for the remaining bytes of a legacy server list ping
+const legacyServerListPingTimeout = 500 * time.Millisecond
+
 type Gateway struct {
-	listeners sync.Map
-	proxies   sync.Map
-	closed    chan bool
-	wg        sync.WaitGroup
+	// StatusCache is shared by all proxies of the gateway.
+	// If nil, statuses are only cached in memory.
+	StatusCache *StatusCache
+	// Callbacks delivers the events of all proxies to their callback servers.
+	// If nil, events are only queued in memory.
+	Callbacks *callback.Dispatcher
+	// CallbackServers get the events of every proxy in addition to
+	// the callback servers of the proxy
+	CallbackServers []CallbackServerConfig
+	// SinkPool holds the sinks of all proxies. If nil, it is created with the first proxy.
+	SinkPool *callback.SinkPool
+	// Sinks get the events of every proxy in addition to the sinks of the proxy
+	Sinks []callback.SinkConfig
+
+	listeners       sync.Map
+	proxies         sync.Map
+	closed          chan bool
+	wg              sync.WaitGroup
+	statusCacheOnce sync.Once
+	callbacksOnce   sync.Once
+	sinkPoolOnce    sync.Once
+}
+
+// NewCallbackDispatcher creates a dispatcher that reports failed and dropped
+// events of callback servers in the log and the metrics
+func NewCallbackDispatcher(spoolPath string) (*callback.Dispatcher, error) {
+	cfg := callback.DefaultDispatcherConfig()
+	cfg.SpoolPath = spoolPath
+	cfg.OnError = func(url, event string, err error) {
+		metricCallbackFailures.With(event).Inc()
+		log.Printf("[w] Failed delivering %s event to %s; error: %s", event, url, err)
+	}
+	cfg.OnDrop = func(url, event string, err error) {
+		metricCallbackDropped.With(event).Inc()
+		log.Printf("[w] Dropped %s event for %s; error: %s", event, url, err)
+	}
+	return callback.NewDispatcher(cfg)
+}
+
+// NewSinkPool creates a sink pool that reports failed and dropped events of sinks
+// in the log and the metrics
+func NewSinkPool() *callback.SinkPool {
+	cfg := callback.DefaultSinkPoolConfig()
+	cfg.OnError = func(sinkType, event string, err error) {
+		metricSinkFailures.With(sinkType, event).Inc()
+		log.Printf("[w] Failed writing %s event to %s sink; error: %s", event, sinkType, err)
+	}
+	cfg.OnDrop = func(sinkType, event string, err error) {
+		metricSinkDropped.With(sinkType, event).Inc()
+		log.Printf("[w] Dropped %s event for %s sink; error: %s", event, sinkType, err)
+	}
+	return callback.NewSinkPool(cfg)
 }
 
 func (gateway *Gateway) ListenAndServe(proxies []*Proxy) error {
@@ -39,27 +97,31 @@ func (gateway *Gateway) KeepProcessActive() {
 	gateway.wg.Wait()
 }
 
-// Close closes all listeners
+// Close closes all listeners and stops the health checks of all proxies
 func (gateway *Gateway) Close() {
 	gateway.listeners.Range(func(k, v interface{}) bool {
 		gateway.closed <- true
 		_ = v.(Listener).Close()
 		return false
 	})
+
+	gateway.proxies.Range(func(k, v interface{}) bool {
+		v.(*Proxy).stopHealthCheck()
+		return true
+	})
 }
 
 func (gateway *Gateway) CloseProxy(proxyUID string) {
 	log.Println("Closing proxy with UID", proxyUID)
-	v, ok := gateway.proxies.LoadAndDelete(proxyUID)
-	if !ok {
+	if _, ok := gateway.proxies.LoadAndDelete(proxyUID); !ok {
 		return
 	}
-	proxy := v.(*Proxy)
 
+	// The listener stays open as long as another proxy UID uses it
+	addr := proxyUID[strings.LastIndex(proxyUID, "@")+1:]
 	closeListener := true
 	gateway.proxies.Range(func(k, v interface{}) bool {
-		otherProxy := v.(*Proxy)
-		if proxy.ListenTo() == otherProxy.ListenTo() {
+		if strings.HasSuffix(k.(string), "@"+addr) {
 			closeListener = false
 			return false
 		}
@@ -70,35 +132,121 @@ func (gateway *Gateway) CloseProxy(proxyUID string) {
 		return
 	}
 
-	v, ok = gateway.listeners.Load(proxy.ListenTo())
+	v, ok := gateway.listeners.Load(addr)
 	if !ok {
 		return
 	}
 	v.(Listener).Close()
 }
 
+// RegisterProxy registers the proxy under every pair of its domain names
+// and listen addresses and creates the listeners that don't exist yet
 func (gateway *Gateway) RegisterProxy(proxy *Proxy) error {
+	for _, domainName := range proxy.DomainNames() {
+		if !isDomainPattern(domainName) {
+			continue
+		}
+
+		if _, err := compileDomainPattern(domainName); err != nil {
+			return err
+		}
+	}
+
 	// Register new Proxy
-	proxyUID := proxy.UID()
-	log.Println("Registering proxy with UID", proxyUID)
-	gateway.proxies.Store(proxyUID, proxy)
+	proxyUIDs := proxy.UIDs()
+	for _, proxyUID := range proxyUIDs {
+		log.Println("Registering proxy with UID", proxyUID)
+		gateway.proxies.Store(proxyUID, proxy)
+	}
+
+	gateway.statusCacheOnce.Do(func() {
+		if gateway.StatusCache == nil {
+			gateway.StatusCache = NewStatusCache("")
+		}
+	})
+	proxy.statusCache = gateway.StatusCache
+
+	gateway.callbacksOnce.Do(func() {
+		if gateway.Callbacks != nil {
+			return
+		}
+
+		callbacks, err := NewCallbackDispatcher("")
+		if err != nil {
+			log.Println("Failed creating callback dispatcher; error:", err)
+			return
+		}
+		gateway.Callbacks = callbacks
+	})
+	proxy.callbacks = gateway.Callbacks
+	proxy.globalCallbacks = gateway.CallbackServers
+
+	gateway.sinkPoolOnce.Do(func() {
+		if gateway.SinkPool == nil {
+			gateway.SinkPool = NewSinkPool()
+		}
+	})
+	proxy.sinkPool = gateway.SinkPool
+	proxy.globalSinks = gateway.Sinks
 
 	proxy.Config.removeCallback = func() {
-		gateway.CloseProxy(proxyUID)
+		gateway.UnregisterProxy(proxy)
 	}
 
 	proxy.Config.changeCallback = func() {
-		if proxyUID == proxy.UID() {
+		proxy.resetDomainProxies()
+		gateway.closeUnusedSinks(proxy.sinkPool)
+		if !proxy.IsHealthCheckEnabled() {
+			proxy.stopHealthCheck()
+		}
+		proxy.startHealthCheck()
+		newProxyUIDs := proxy.UIDs()
+		if equalStrings(proxyUIDs, newProxyUIDs) {
 			return
 		}
-		gateway.CloseProxy(proxyUID)
+
 		if err := gateway.RegisterProxy(proxy); err != nil {
 			log.Println(err)
 		}
+
+		for _, proxyUID := range proxyUIDs {
+			if !containsString(newProxyUIDs, proxyUID) {
+				gateway.CloseProxy(proxyUID)
+			}
+		}
 	}
 
-	// Check if a gate is already listening to the Proxy address
-	addr := proxy.ListenTo()
+	proxy.startHealthCheck()
+
+	for _, addr := range proxy.ListenAddrs() {
+		if err := gateway.listen(addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// closeUnusedSinks closes the sinks of the pool that neither the gateway
+// nor a registered proxy uses anymore, e.g. after a config changed
+func (gateway *Gateway) closeUnusedSinks(sinkPool *callback.SinkPool) {
+	if sinkPool == nil {
+		return
+	}
+
+	cfgs := append([]callback.SinkConfig{}, gateway.Sinks...)
+	for _, proxy := range gateway.Proxies() {
+		proxy.Config.RLock()
+		cfgs = append(cfgs, proxy.Config.Sinks...)
+		proxy.Config.RUnlock()
+	}
+
+	if err := sinkPool.Retain(cfgs); err != nil {
+		log.Println("Failed closing unused sinks; error:", err)
+	}
+}
+
+// listen creates a listener on addr if there is none yet
+func (gateway *Gateway) listen(addr string) error {
 	if _, ok := gateway.listeners.Load(addr); ok {
 		return nil
 	}
@@ -113,7 +261,7 @@ func (gateway *Gateway) RegisterProxy(proxy *Proxy) error {
 	gateway.wg.Add(1)
 	go func() {
 		if err := gateway.listenAndServe(listener, addr); err != nil {
-			log.Printf("Failed to listen on %s; error: %s", proxy.ListenTo(), err)
+			log.Printf("Failed to listen on %s; error: %s", addr, err)
 		}
 	}()
 	return nil
@@ -126,7 +274,8 @@ func (gateway *Gateway) listenAndServe(listener Listener, addr string) error {
 		conn, err := listener.Accept()
 		if err != nil {
 			// TODO: Refactor this; it feels hacky
-			if err.Error() == "use of closed network connection" {
+			// The error is wrapped like "accept tcp [::]:25565: use of closed network connection"
+			if strings.HasSuffix(err.Error(), "use of closed network connection") {
 				log.Println("Closing listener on", addr)
 				gateway.listeners.Delete(addr)
 				return nil
@@ -135,6 +284,7 @@ func (gateway *Gateway) listenAndServe(listener Listener, addr string) error {
 			continue
 		}
 
+		metricConnections.With(addr).Inc()
 		go func() {
 			log.Printf("[>] Incoming %s on listener %s", conn.RemoteAddr(), addr)
 			defer conn.Close()
@@ -148,12 +298,16 @@ func (gateway *Gateway) listenAndServe(listener Listener, addr string) error {
 }
 
 func (gateway *Gateway) serve(conn Conn, addr string) error {
+	connRemoteAddr := conn.RemoteAddr()
+	if isLegacyServerListPing(conn.Reader()) {
+		return gateway.serveLegacyServerListPing(conn, addr, connRemoteAddr)
+	}
+
 	pk, err := conn.PeekPacket()
 	if err != nil {
 		return err
 	}
 
-	connRemoteAddr := conn.RemoteAddr()
 	hs, err := handshaking.UnmarshalServerBoundHandshake(pk)
 	if err != nil {
 		header, err := proxyproto.Read(conn.Reader())
@@ -161,6 +315,9 @@ func (gateway *Gateway) serve(conn Conn, addr string) error {
 			return err
 		}
 		connRemoteAddr = header.SourceAddr
+		if isLegacyServerListPing(conn.Reader()) {
+			return gateway.serveLegacyServerListPing(conn, addr, connRemoteAddr)
+		}
 		pk, err := conn.PeekPacket()
 		if err != nil {
 			return err
@@ -171,18 +328,18 @@ func (gateway *Gateway) serve(conn Conn, addr string) error {
 		}
 	}
 
+	metricHandshakes.With(nextStateLabel(hs)).Inc()
 	proxyUID := proxyUID(hs.ParseServerAddress(), addr)
 
 	log.Printf("[i] %s requests proxy with UID %s", connRemoteAddr, proxyUID)
-	v, ok := gateway.proxies.Load(proxyUID)
-	if !ok {
-		// Client send an invalid address/port; we don't have a v for that address
-		return errors.New("no proxy with uid " + proxyUID)
+	proxy, err := gateway.findProxyOrDefault(hs.ParseServerAddress(), addr, connRemoteAddr)
+	if err != nil {
+		// Client send an invalid address/port; we don't have a proxy for that address
+		return err
 	}
-	proxy := v.(*Proxy)
 
 	if err := proxy.handleConn(conn, connRemoteAddr); err != nil {
-		proxy.CallbackLogger().LogEvent(callback.ErrorEvent{
+		proxy.logEvent(callback.ErrorEvent{
 			Error:    err.Error(),
 			ProxyUID: proxyUID,
 		})
@@ -190,3 +347,155 @@ func (gateway *Gateway) serve(conn Conn, addr string) error {
 	}
 	return nil
 }
+
+// isLegacyServerListPing checks if the next bytes are a server list ping of a pre-1.7 client
+func isLegacyServerListPing(r *bufio.Reader) bool {
+	bb, err := r.Peek(1)
+	if err != nil || bb[0] != status.LegacyServerListPingPacketID {
+		return false
+	}
+
+	// A handshake with a length of 254 also starts with 0xFE 0x01, but is followed by its packet ID
+	if r.Buffered() >= 3 {
+		bb, _ = r.Peek(3)
+		return bb[1] != 0x01 || bb[2] != byte(handshaking.ServerBoundHandshakePacketID)
+	}
+
+	return true
+}
+
+func (gateway *Gateway) serveLegacyServerListPing(conn Conn, addr string, connRemoteAddr net.Addr) error {
+	// Older clients don't tell us when the ping is over, so we just wait a moment for the rest
+	if err := conn.SetReadDeadline(time.Now().Add(legacyServerListPingTimeout)); err != nil {
+		return err
+	}
+
+	var rawPing bytes.Buffer
+	ping, err := status.ReadLegacyServerListPing(io.TeeReader(conn.Reader(), &rawPing))
+	if err != nil {
+		return err
+	}
+
+	if err := conn.SetReadDeadline(time.Time{}); err != nil {
+		return err
+	}
+
+	metricHandshakes.With("legacy_status").Inc()
+	var proxy *Proxy
+	if ping.HasServerAddress() {
+		proxyUID := proxyUID(ping.ParseServerAddress(), addr)
+		log.Printf("[i] %s requests proxy with UID %s via legacy ping", connRemoteAddr, proxyUID)
+		proxy, err = gateway.findProxyOrDefault(ping.ParseServerAddress(), addr, connRemoteAddr)
+		if err != nil {
+			return err
+		}
+	} else {
+		log.Printf("[i] %s requests the default proxy on %s via legacy ping", connRemoteAddr, addr)
+		var ok bool
+		proxy, ok = gateway.defaultProxy(addr)
+		if !ok {
+			return errors.New("no default proxy on " + addr)
+		}
+	}
+
+	return proxy.handleLegacyServerListPing(conn, rawPing.Bytes())
+}
+
+// findProxy returns the proxy for the domain on the listener on addr. A proxy with exactly
+// that domain name is preferred over proxies with a domain pattern that matches the domain.
+func (gateway *Gateway) findProxy(domain, addr string) (*Proxy, error) {
+	proxyUID := proxyUID(domain, addr)
+	// Proxies are also stored by the UIDs of their domain patterns, but a client that sends
+	// a pattern as its domain must not get the proxy with the untemplated config
+	if !isDomainPattern(domain) {
+		if v, ok := gateway.proxies.Load(proxyUID); ok {
+			return v.(*Proxy), nil
+		}
+	}
+
+	var patternProxy *Proxy
+	var matchedPattern string
+	gateway.proxies.Range(func(k, v interface{}) bool {
+		proxy := v.(*Proxy)
+		if !proxy.IsListeningTo(addr) {
+			return true
+		}
+
+		pattern, _, ok := proxy.matchDomain(domain)
+		if !ok {
+			return true
+		}
+
+		if patternProxy == nil || domainPatternHasPriority(pattern, matchedPattern) {
+			patternProxy = proxy
+			matchedPattern = pattern
+		}
+		return true
+	})
+
+	if patternProxy == nil {
+		return nil, errors.New("no proxy with uid " + proxyUID)
+	}
+
+	return patternProxy.domainProxy(domain)
+}
+
+// findProxyOrDefault falls back to the default proxy of the listener on addr
+// if there is no proxy for the domain
+func (gateway *Gateway) findProxyOrDefault(domain, addr string, connRemoteAddr net.Addr) (*Proxy, error) {
+	proxy, err := gateway.findProxy(domain, addr)
+	if err == nil {
+		return proxy, nil
+	}
+
+	metricUnknownHosts.With(addr).Inc()
+	defaultProxy, ok := gateway.defaultProxy(addr)
+	if !ok {
+		return nil, err
+	}
+
+	log.Printf("[i] %s requests unknown domain %s; falling back to %s", connRemoteAddr, domain, defaultProxy.UID())
+	defaultProxy.logEvent(callback.UnknownHostEvent{
+		RequestedDomain: domain,
+		RemoteAddress:   connRemoteAddr.String(),
+		ListenTo:        addr,
+		ProxyUID:        defaultProxy.UID(),
+	})
+	return defaultProxy, nil
+}
+
+// defaultProxy returns the proxy that is marked as the default of the listener on addr
+func (gateway *Gateway) defaultProxy(addr string) (*Proxy, bool) {
+	var defaultProxy *Proxy
+	gateway.proxies.Range(func(k, v interface{}) bool {
+		proxy := v.(*Proxy)
+		if proxy.IsListeningTo(addr) && proxy.IsDefault() {
+			defaultProxy = proxy
+			return false
+		}
+		return true
+	})
+	return defaultProxy, defaultProxy != nil
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -1,15 +1,25 @@
 package infrared
 
 import (
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
+	"time"
+	"unicode/utf16"
 
+	"github.com/haveachin/infrared/callback"
 	"github.com/haveachin/infrared/protocol"
 	"github.com/haveachin/infrared/protocol/handshaking"
+	"github.com/haveachin/infrared/protocol/login"
 	"github.com/haveachin/infrared/protocol/status"
 	"github.com/pires/go-proxyproto"
 )
@@ -62,8 +72,8 @@ func proxyConfigWithPortEnd(portEnd int) *ProxyConfig {
 func createBasicProxyConfig(serverDomain, gatewayAddr, serverAddr string) *ProxyConfig {
 	return &ProxyConfig{
 		DomainName: serverDomain,
-		ListenTo:   gatewayAddr,
-		ProxyTo:    serverAddr,
+		ListenTo:   StringList{gatewayAddr},
+		ProxyTo:    StringList{serverAddr},
 	}
 }
 
@@ -279,6 +289,7 @@ func TestStatusRequest(t *testing.T) {
 		portEnd         int
 		onlineStatus    StatusConfig
 		offlineStatus   StatusConfig
+		overrideStatus  StatusConfig
 		activeServer    bool
 		expectedVersion string
 	}{
@@ -310,6 +321,13 @@ func TestStatusRequest(t *testing.T) {
 			activeServer:    false,
 			expectedVersion: offlineStatus.VersionName,
 		},
+		{
+			name:            "ServerOnlineWithOverride",
+			portEnd:         574,
+			overrideStatus:  StatusConfig{VersionName: "Infrared override"},
+			activeServer:    true,
+			expectedVersion: "Infrared override",
+		},
 	}
 
 	for _, tc := range tt {
@@ -322,6 +340,7 @@ func TestStatusRequest(t *testing.T) {
 				config := proxyConfigWithPortEnd(tc.portEnd)
 				config.OnlineStatus = tc.onlineStatus
 				config.OfflineStatus = tc.offlineStatus
+				config.OverrideStatus = tc.overrideStatus
 
 				gateway := Gateway{}
 				proxies := configToProxies(config)
@@ -553,8 +572,8 @@ func TestRouting(t *testing.T) {
 		serverC := statusListenerConfig{}
 
 		serverAddr := serverAddr(port)
-		proxyC.ListenTo = gatewayAddr(server.portEnd)
-		proxyC.ProxyTo = serverAddr
+		proxyC.ListenTo = StringList{gatewayAddr(server.portEnd)}
+		proxyC.ProxyTo = StringList{serverAddr}
 		proxyC.DomainName = server.domain
 		routingConfig = append(routingConfig, proxyC)
 
@@ -625,3 +644,696 @@ func TestRouting(t *testing.T) {
 		})
 	}
 }
+
+func legacyServerListPing(domain string, port int) []byte {
+	encodeString := func(s string) []byte {
+		chars := utf16.Encode([]rune(s))
+		bb := make([]byte, 2+len(chars)*2)
+		binary.BigEndian.PutUint16(bb, uint16(len(chars)))
+		for i, c := range chars {
+			binary.BigEndian.PutUint16(bb[2+i*2:], c)
+		}
+		return bb
+	}
+
+	data := []byte{74}
+	data = append(data, encodeString(domain)...)
+	data = append(data, byte(port>>24), byte(port>>16), byte(port>>8), byte(port))
+
+	ping := []byte{0xfe, 0x01, 0xfa}
+	ping = append(ping, encodeString("MC|PingHost")...)
+	ping = append(ping, byte(len(data)>>8), byte(len(data)))
+	return append(ping, data...)
+}
+
+func TestLegacyServerListPing(t *testing.T) {
+	tt := []struct {
+		name            string
+		portEnd         int
+		ping            []byte
+		defaultProxy    bool
+		expectedVersion string
+		expectError     bool
+	}{
+		{
+			name:            "PingWithHostname",
+			portEnd:         590,
+			ping:            legacyServerListPing(serverDomain, gatewayPort(590)),
+			expectedVersion: offlineStatus.VersionName,
+		},
+		{
+			name:            "PingWithoutHostnameToDefault",
+			portEnd:         591,
+			ping:            []byte{0xfe, 0x01},
+			defaultProxy:    true,
+			expectedVersion: offlineStatus.VersionName,
+		},
+		{
+			name:        "PingWithoutHostnameWithoutDefault",
+			portEnd:     592,
+			ping:        []byte{0xfe, 0x01},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			config := proxyConfigWithPortEnd(tc.portEnd)
+			config.OfflineStatus = offlineStatus
+			config.Default = tc.defaultProxy
+
+			gateway := Gateway{}
+			if err := gateway.ListenAndServe(configToProxies(config)); err != nil {
+				t.Fatalf("Can't start gateway: %v", err)
+			}
+			defer gateway.Close()
+
+			conn, err := Dial(gatewayAddr(tc.portEnd))
+			if err != nil {
+				t.Fatalf("Can't make a connection with gateway: %v", err)
+			}
+			defer conn.Close()
+
+			if _, err := conn.Write(tc.ping); err != nil {
+				t.Fatalf("Can't write legacy ping: %v", err)
+			}
+
+			response, err := ioutil.ReadAll(conn)
+			if tc.expectError {
+				if len(response) > 0 {
+					t.Errorf("expected no response; got: %v", response)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Can't read legacy ping response: %v", err)
+			}
+
+			if len(response) < 3 || response[0] != 0xff {
+				t.Fatalf("invalid response: %v", response)
+			}
+
+			chars := make([]uint16, (len(response)-3)/2)
+			for i := range chars {
+				chars[i] = binary.BigEndian.Uint16(response[3+i*2:])
+			}
+			fields := strings.Split(string(utf16.Decode(chars)), "\x00")
+
+			if len(fields) != 6 || fields[0] != "§1" {
+				t.Fatalf("invalid response: %q", fields)
+			}
+
+			if fields[2] != tc.expectedVersion {
+				t.Errorf("got: %v; want: %v", fields[2], tc.expectedVersion)
+			}
+		})
+	}
+}
+
+func TestColdStartHold(t *testing.T) {
+	tt := []struct {
+		name        string
+		portEnd     int
+		startServer bool
+		expectedID  protocol.VarInt
+	}{
+		{
+			name:        "ServerStartsInTime",
+			portEnd:     600,
+			startServer: true,
+			expectedID:  login.ClientBoundLoginSuccessPacketID,
+		},
+		{
+			name:       "ServerDoesNotStartInTime",
+			portEnd:    601,
+			expectedID: login.ClientBoundDisconnectPacketID,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			config := proxyConfigWithPortEnd(tc.portEnd)
+			config.ColdStart = ColdStartConfig{
+				Mode:         ColdStartModeHold,
+				MaxWait:      1000,
+				PollInterval: 50,
+			}
+
+			gateway := Gateway{}
+			if err := gateway.ListenAndServe(configToProxies(config)); err != nil {
+				t.Fatalf("Can't start gateway: %v", err)
+			}
+			defer gateway.Close()
+
+			conn, err := Dial(gatewayAddr(tc.portEnd))
+			if err != nil {
+				t.Fatalf("Can't make a connection with gateway: %v", err)
+			}
+			defer conn.Close()
+
+			hs := handshaking.ServerBoundHandshake{
+				ProtocolVersion: 754,
+				ServerAddress:   protocol.String(serverDomain),
+				ServerPort:      protocol.UnsignedShort(gatewayPort(tc.portEnd)),
+				NextState:       handshaking.ServerBoundHandshakeLoginState,
+			}
+			if err := conn.WritePacket(hs.Marshal()); err != nil {
+				t.Fatalf("Can't write handshake: %v", err)
+			}
+
+			loginStart := login.ServerLoginStart{Name: "Notch"}
+			if err := conn.WritePacket(loginStart.Marshal(hs.ProtocolVersion)); err != nil {
+				t.Fatalf("Can't write login start: %v", err)
+			}
+
+			if tc.startServer {
+				// The server starts after Infrared gave up on the first dial
+				time.Sleep(200 * time.Millisecond)
+				listener, err := Listen(serverAddr(tc.portEnd))
+				if err != nil {
+					t.Fatalf("Can't listen to %v: %v", serverAddr(tc.portEnd), err)
+				}
+				defer listener.Close()
+
+				go func() {
+					rconn, err := listener.Accept()
+					if err != nil {
+						return
+					}
+					defer rconn.Close()
+
+					if _, err := rconn.ReadPacket(); err != nil {
+						return
+					}
+
+					pk, err := rconn.ReadPacket()
+					if err != nil {
+						return
+					}
+
+					ls, err := login.UnmarshalServerBoundLoginStart(pk, hs.ProtocolVersion)
+					if err != nil || ls.Name != loginStart.Name {
+						return
+					}
+
+					_ = rconn.WritePacket(protocol.Packet{ID: login.ClientBoundLoginSuccessPacketID})
+					_, _ = ioutil.ReadAll(rconn)
+				}()
+			}
+
+			if err := conn.SetReadDeadline(time.Now().Add(3 * time.Second)); err != nil {
+				t.Fatal(err)
+			}
+
+			pk, err := conn.ReadPacket()
+			if err != nil {
+				t.Fatalf("Can't read login response: %v", err)
+			}
+
+			if pk.ID != tc.expectedID {
+				t.Errorf("got: %v; want: %v", pk.ID, tc.expectedID)
+			}
+		})
+	}
+}
+
+// loginPacketNames names the clientbound login packets, which have the same IDs in every protocol version
+var loginPacketNames = map[protocol.VarInt]string{
+	login.ClientBoundDisconnectPacketID:         "LoginDisconnect",
+	login.ClientBoundLoginSuccessPacketID:       "LoginSuccess",
+	login.ClientBoundLoginPluginRequestPacketID: "LoginPluginRequest",
+}
+
+func TestColdStartLimbo(t *testing.T) {
+	tt := []struct {
+		name            string
+		portEnd         int
+		protocolVersion protocol.VarInt
+		startServer     bool
+		expectedPackets []string
+	}{
+		{
+			name:            "ConfigurationTransfer",
+			portEnd:         610,
+			protocolVersion: protocol.Version1_20_5,
+			startServer:     true,
+			expectedPackets: []string{
+				"LoginSuccess",
+				protocol.PacketKeepAlive,
+				protocol.PacketTransfer,
+			},
+		},
+		{
+			name:            "LoginDisconnect",
+			portEnd:         611,
+			protocolVersion: protocol.Version1_16,
+			expectedPackets: []string{
+				"LoginPluginRequest",
+				"LoginDisconnect",
+			},
+		},
+		{
+			name:            "PlayDisconnect",
+			portEnd:         612,
+			protocolVersion: protocol.Version1_12_2,
+			expectedPackets: []string{
+				"LoginSuccess",
+				protocol.PacketJoinGame,
+				protocol.PacketPlayerPositionAndLook,
+				protocol.PacketTitle,
+				protocol.PacketTitle,
+				protocol.PacketTitle,
+				protocol.PacketBossBar,
+				protocol.PacketDisconnect,
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			config := proxyConfigWithPortEnd(tc.portEnd)
+			config.ColdStart = ColdStartConfig{
+				Mode:         ColdStartModeLimbo,
+				MaxWait:      500,
+				PollInterval: 50,
+			}
+
+			gateway := Gateway{}
+			if err := gateway.ListenAndServe(configToProxies(config)); err != nil {
+				t.Fatalf("Can't start gateway: %v", err)
+			}
+			defer gateway.Close()
+
+			conn, err := Dial(gatewayAddr(tc.portEnd))
+			if err != nil {
+				t.Fatalf("Can't make a connection with gateway: %v", err)
+			}
+			defer conn.Close()
+
+			hs := handshaking.ServerBoundHandshake{
+				ProtocolVersion: tc.protocolVersion,
+				ServerAddress:   protocol.String(serverDomain),
+				ServerPort:      protocol.UnsignedShort(gatewayPort(tc.portEnd)),
+				NextState:       handshaking.ServerBoundHandshakeLoginState,
+			}
+			if err := conn.WritePacket(hs.Marshal()); err != nil {
+				t.Fatalf("Can't write handshake: %v", err)
+			}
+
+			loginStart := login.ServerLoginStart{Name: "Notch"}
+			if err := conn.WritePacket(loginStart.Marshal(hs.ProtocolVersion)); err != nil {
+				t.Fatalf("Can't write login start: %v", err)
+			}
+
+			if err := conn.SetReadDeadline(time.Now().Add(3 * time.Second)); err != nil {
+				t.Fatal(err)
+			}
+
+			state := protocol.StateLogin
+			var packets []string
+			for {
+				pk, err := conn.ReadPacket()
+				if err != nil {
+					break
+				}
+
+				name := loginPacketNames[pk.ID]
+				if state != protocol.StateLogin {
+					name, _ = protocol.DefaultRegistry.PacketName(hs.ProtocolVersion, state, protocol.ClientBound, pk.ID)
+				}
+				packets = append(packets, name)
+
+				if state != protocol.StateLogin || pk.ID != login.ClientBoundLoginSuccessPacketID {
+					continue
+				}
+
+				if hs.ProtocolVersion < protocol.Version1_20_2 {
+					state = protocol.StatePlay
+					continue
+				}
+
+				state = protocol.StateConfiguration
+				if err := conn.WritePacket(login.ServerBoundLoginAcknowledged{}.Marshal()); err != nil {
+					t.Fatalf("Can't write login acknowledged: %v", err)
+				}
+
+				if tc.startServer {
+					listener, err := Listen(serverAddr(tc.portEnd))
+					if err != nil {
+						t.Fatalf("Can't listen to %v: %v", serverAddr(tc.portEnd), err)
+					}
+					defer listener.Close()
+				}
+			}
+
+			if strings.Join(packets, ",") != strings.Join(tc.expectedPackets, ",") {
+				t.Errorf("got: %v; want: %v", packets, tc.expectedPackets)
+			}
+		})
+	}
+}
+
+func TestGateway_findProxy(t *testing.T) {
+	portEnd := 620
+	exact := proxyConfigWithPortEnd(portEnd)
+	exact.DomainName = "lobby.play.example.com"
+
+	wildcard := proxyConfigWithPortEnd(portEnd)
+	wildcard.DomainName = "*.play.example.com"
+	wildcard.ProxyTo = StringList{"{1}.internal:25565"}
+	wildcard.Docker.ContainerName = "mc-{1}"
+
+	regex := proxyConfigWithPortEnd(portEnd)
+	regex.DomainName = `~(\w+)\.(eu|us)\.example\.com`
+	regex.ProxyTo = StringList{"{1}.{2}.internal:25565"}
+
+	gateway := Gateway{}
+	if err := gateway.ListenAndServe(configsToProxies([]*ProxyConfig{exact, wildcard, regex})); err != nil {
+		t.Fatalf("Can't start gateway: %v", err)
+	}
+	defer gateway.Close()
+
+	tt := []struct {
+		domain                string
+		expectedProxyTo       string
+		expectedContainerName string
+		expectErr             bool
+	}{
+		{
+			domain:          "lobby.play.example.com",
+			expectedProxyTo: exact.ProxyTo[0],
+		},
+		{
+			domain:                "Alice.play.example.com",
+			expectedProxyTo:       "alice.internal:25565",
+			expectedContainerName: "mc-alice",
+		},
+		{
+			domain:          "bob.us.example.com",
+			expectedProxyTo: "bob.us.internal:25565",
+		},
+		{
+			domain:    "bob.example.com",
+			expectErr: true,
+		},
+		{
+			domain:    "*.play.example.com",
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		proxy, err := gateway.findProxy(tc.domain, gatewayAddr(portEnd))
+		if tc.expectErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", tc.domain)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: %v", tc.domain, err)
+			continue
+		}
+
+		if proxy.ProxyTo() != tc.expectedProxyTo {
+			t.Errorf("%s: got: %s; want: %s", tc.domain, proxy.ProxyTo(), tc.expectedProxyTo)
+		}
+
+		if proxy.Config.Docker.ContainerName != tc.expectedContainerName {
+			t.Errorf("%s: got: %s; want: %s", tc.domain, proxy.Config.Docker.ContainerName, tc.expectedContainerName)
+		}
+	}
+
+	first, _ := gateway.findProxy("alice.play.example.com", gatewayAddr(portEnd))
+	second, _ := gateway.findProxy("alice.play.example.com", gatewayAddr(portEnd))
+	if first != second {
+		t.Error("a matched domain has to be served by the same proxy")
+	}
+}
+
+func TestGateway_RegisterProxy_Lists(t *testing.T) {
+	config := proxyConfigWithPortEnd(630)
+	config.DomainNames = StringList{"www." + serverDomain}
+	config.ListenTo = StringList{gatewayAddr(630), gatewayAddr(631)}
+	proxy := &Proxy{Config: config}
+
+	gateway := Gateway{}
+	if err := gateway.ListenAndServe([]*Proxy{proxy}); err != nil {
+		t.Fatalf("Can't start gateway: %v", err)
+	}
+	defer gateway.Close()
+
+	expectedUIDs := []string{
+		proxyUID(serverDomain, gatewayAddr(630)),
+		proxyUID("www."+serverDomain, gatewayAddr(630)),
+		proxyUID(serverDomain, gatewayAddr(631)),
+		proxyUID("www."+serverDomain, gatewayAddr(631)),
+	}
+
+	if uids := proxy.UIDs(); !equalStrings(uids, expectedUIDs) {
+		t.Errorf("got: %v; want: %v", uids, expectedUIDs)
+	}
+
+	for _, uid := range expectedUIDs {
+		if _, ok := gateway.proxies.Load(uid); !ok {
+			t.Errorf("proxy is not registered as %s", uid)
+		}
+	}
+
+	for _, portEnd := range []int{630, 631} {
+		if _, ok := gateway.listeners.Load(gatewayAddr(portEnd)); !ok {
+			t.Errorf("gateway does not listen to %s", gatewayAddr(portEnd))
+		}
+	}
+
+	// A reload that drops the www domain and the second listener
+	config.Lock()
+	config.DomainNames = nil
+	config.ListenTo = StringList{gatewayAddr(630)}
+	config.Unlock()
+	config.changeCallback()
+
+	for i, uid := range expectedUIDs {
+		_, ok := gateway.proxies.Load(uid)
+		if i == 0 && !ok {
+			t.Errorf("proxy is not registered as %s anymore", uid)
+		}
+		if i != 0 && ok {
+			t.Errorf("proxy is still registered as %s", uid)
+		}
+	}
+}
+
+func TestUnknownHostFallback(t *testing.T) {
+	events := make(chan callback.EventLog, 1)
+	callbackServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var eventLog callback.EventLog
+		if err := json.NewDecoder(r.Body).Decode(&eventLog); err == nil {
+			events <- eventLog
+		}
+	}))
+	defer callbackServer.Close()
+
+	tt := []struct {
+		name    string
+		portEnd int
+		login   bool
+	}{
+		{
+			name:    "Status",
+			portEnd: 640,
+		},
+		{
+			name:    "Login",
+			portEnd: 641,
+			login:   true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			config := proxyConfigWithPortEnd(tc.portEnd)
+			config.DomainName = "fallback"
+			config.ProxyTo = nil
+			config.Default = true
+			config.OfflineStatus = StatusConfig{VersionName: "Unknown server"}
+			config.DisconnectMessage = "{{requestedDomain}} does not exist"
+			config.CallbackServer = CallbackServerConfig{
+				URL:    callbackServer.URL,
+				Events: []string{callback.EventTypeUnknownHost},
+			}
+
+			gateway := Gateway{}
+			if err := gateway.ListenAndServe(configToProxies(config)); err != nil {
+				t.Fatalf("Can't start gateway: %v", err)
+			}
+			defer gateway.Close()
+
+			if !tc.login {
+				version, err := statusDial(statusDialConfig{
+					pk:          serverHandshake("unknown.gateway", gatewayPort(tc.portEnd)),
+					gatewayAddr: gatewayAddr(tc.portEnd),
+				})
+				if err != nil {
+					t.Fatalf("%s: %v", err.Message, err.Error)
+				}
+
+				if version != config.OfflineStatus.VersionName {
+					t.Errorf("got: %v; want: %v", version, config.OfflineStatus.VersionName)
+				}
+			} else {
+				conn, err := Dial(gatewayAddr(tc.portEnd))
+				if err != nil {
+					t.Fatalf("Can't make a connection with gateway: %v", err)
+				}
+				defer conn.Close()
+
+				hs := handshaking.ServerBoundHandshake{
+					ProtocolVersion: 754,
+					ServerAddress:   "unknown.gateway",
+					ServerPort:      protocol.UnsignedShort(gatewayPort(tc.portEnd)),
+					NextState:       handshaking.ServerBoundHandshakeLoginState,
+				}
+				if err := conn.WritePacket(hs.Marshal()); err != nil {
+					t.Fatalf("Can't write handshake: %v", err)
+				}
+
+				if err := conn.WritePacket(login.ServerLoginStart{Name: "Notch"}.Marshal(hs.ProtocolVersion)); err != nil {
+					t.Fatalf("Can't write login start: %v", err)
+				}
+
+				pk, err := conn.ReadPacket()
+				if err != nil {
+					t.Fatalf("Can't read login response: %v", err)
+				}
+
+				disconnect, err := login.UnmarshalClientBoundDisconnect(pk)
+				if err != nil {
+					t.Fatalf("Can't read login disconnect: %v", err)
+				}
+
+				if !strings.Contains(string(disconnect.Reason), "unknown.gateway does not exist") {
+					t.Errorf("got: %v; want the requested domain in the reason", disconnect.Reason)
+				}
+			}
+
+			select {
+			case eventLog := <-events:
+				payload, _ := eventLog.Payload.(map[string]interface{})
+				if eventLog.Event != callback.EventTypeUnknownHost || payload["requestedDomain"] != "unknown.gateway" {
+					t.Errorf("got: %v; want: an UnknownHost event for unknown.gateway", eventLog)
+				}
+			case <-time.After(time.Second):
+				t.Error("no UnknownHost event was logged")
+			}
+		})
+	}
+}
+
+func TestGateway_CallbackServers(t *testing.T) {
+	contentTypes := make(chan string, 4)
+	callbackServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentTypes <- r.URL.Path + " " + r.Header.Get("Content-Type")
+	}))
+	defer callbackServer.Close()
+
+	config := proxyConfigWithPortEnd(670)
+	config.CallbackServers = []CallbackServerConfig{
+		{
+			URL:    callbackServer.URL + "/proxy",
+			Events: []string{callback.EventTypePlayerJoin},
+			Format: callback.FormatCloudEvents,
+		},
+		{
+			URL:    callbackServer.URL + "/ignored",
+			Events: []string{callback.EventTypePlayerLeave},
+		},
+	}
+	proxy := &Proxy{Config: config}
+
+	gateway := Gateway{
+		CallbackServers: []CallbackServerConfig{
+			{
+				URL:    callbackServer.URL + "/global",
+				Events: []string{callback.EventTypePlayerJoin},
+			},
+		},
+	}
+	if err := gateway.RegisterProxy(proxy); err != nil {
+		t.Fatal(err)
+	}
+	defer gateway.UnregisterProxy(proxy)
+
+	proxy.logEvent(callback.PlayerJoinEvent{Username: "Steve", ProxyUID: proxy.UID()})
+
+	expected := map[string]bool{
+		"/global application/json":            true,
+		"/proxy application/cloudevents+json": true,
+	}
+	for len(expected) > 0 {
+		select {
+		case got := <-contentTypes:
+			if !expected[got] {
+				t.Errorf("got: %v; want one of: %v", got, expected)
+			}
+			delete(expected, got)
+		case <-time.After(3 * time.Second):
+			t.Fatalf("events were not delivered to: %v", expected)
+		}
+	}
+}
+
+func TestGateway_Sinks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "infrared-sinks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	proxyPath := filepath.Join(dir, "proxy.log")
+	globalPath := filepath.Join(dir, "global.log")
+
+	config := proxyConfigWithPortEnd(671)
+	config.Sinks = []callback.SinkConfig{
+		{
+			Type:   callback.SinkTypeFile,
+			Events: []string{callback.EventTypePlayerLeave},
+			Path:   proxyPath,
+		},
+	}
+	proxy := &Proxy{Config: config}
+
+	gateway := Gateway{
+		Sinks: []callback.SinkConfig{
+			{
+				Type:   callback.SinkTypeFile,
+				Events: []string{callback.EventTypePlayerJoin, callback.EventTypePlayerLeave},
+				Path:   globalPath,
+			},
+		},
+	}
+	if err := gateway.RegisterProxy(proxy); err != nil {
+		t.Fatal(err)
+	}
+	defer gateway.UnregisterProxy(proxy)
+
+	proxy.logEvent(callback.PlayerJoinEvent{Username: "Steve", ProxyUID: proxy.UID()})
+	proxy.logEvent(callback.PlayerLeaveEvent{Username: "Steve", ProxyUID: proxy.UID()})
+
+	// Closing the pool writes the queued events
+	if err := gateway.SinkPool.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, lines := range map[string]int{proxyPath: 1, globalPath: 2} {
+		bb, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if n := strings.Count(string(bb), "\n"); n != lines {
+			t.Errorf("got: %v; want: %v events in %s", n, lines, path)
+		}
+	}
+}
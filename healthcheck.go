@@ -0,0 +1,257 @@
+package infrared
+
+import (
+	"errors"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/haveachin/infrared/protocol"
+	"github.com/haveachin/infrared/protocol/handshaking"
+)
+
+// healthCheckProtocolVersion is sent in the handshake of health checks.
+// Servers answer status requests of every protocol version.
+const healthCheckProtocolVersion = protocol.Version1_16
+
+// defaultHealthCheckInterval is used if the configured interval is not positive
+const defaultHealthCheckInterval = 5 * time.Second
+
+var ErrBackendsDown = errors.New("all backends are down")
+
+// BackendHealth is the state of a backend that the health checker of its proxy observed
+type BackendHealth struct {
+	Address   string        `json:"address"`
+	Up        bool          `json:"up"`
+	Latency   time.Duration `json:"latency"`
+	LastCheck time.Time     `json:"lastCheck"`
+	LastError string        `json:"lastError,omitempty"`
+
+	successes int
+	failures  int
+}
+
+// healthChecker pings the backends of a proxy on an interval. A backend is down after
+// fall failed checks in a row and up again after rise successful checks in a row.
+// Backends that were not checked yet are considered up.
+type healthChecker struct {
+	proxy    *Proxy
+	stop     chan struct{}
+	mu       sync.RWMutex
+	backends map[string]*BackendHealth
+}
+
+func newHealthChecker(proxy *Proxy) *healthChecker {
+	return &healthChecker{
+		proxy:    proxy,
+		stop:     make(chan struct{}),
+		backends: map[string]*BackendHealth{},
+	}
+}
+
+func (checker *healthChecker) run() {
+	for {
+		checker.checkAll()
+
+		select {
+		case <-checker.stop:
+			return
+		case <-time.After(checker.proxy.HealthCheckInterval()):
+		}
+	}
+}
+
+func (checker *healthChecker) checkAll() {
+	backends := checker.proxy.Backends()
+
+	wg := sync.WaitGroup{}
+	for _, backend := range backends {
+		wg.Add(1)
+		go func(backend string) {
+			defer wg.Done()
+			start := time.Now()
+			err := checker.proxy.checkBackend(backend)
+			checker.record(backend, time.Since(start), err)
+		}(backend)
+	}
+	wg.Wait()
+
+	// Forget the backends that were removed from the config
+	checker.mu.Lock()
+	defer checker.mu.Unlock()
+	for backend := range checker.backends {
+		if !containsString(backends, backend) {
+			delete(checker.backends, backend)
+		}
+	}
+}
+
+func (checker *healthChecker) record(backend string, latency time.Duration, err error) {
+	rise, fall := checker.proxy.HealthCheckRise(), checker.proxy.HealthCheckFall()
+
+	checker.mu.Lock()
+	defer checker.mu.Unlock()
+	health, ok := checker.backends[backend]
+	if !ok {
+		health = &BackendHealth{Address: backend, Up: true}
+		checker.backends[backend] = health
+	}
+	health.LastCheck = time.Now()
+
+	if err != nil {
+		health.successes = 0
+		health.failures++
+		health.LastError = err.Error()
+		if health.Up && health.failures >= fall {
+			health.Up = false
+			log.Printf("[i] Backend %s of %s is down; error: %s", backend, checker.proxy.UID(), err)
+		}
+		return
+	}
+
+	health.failures = 0
+	health.successes++
+	health.Latency = latency
+	health.LastError = ""
+	if !health.Up && health.successes >= rise {
+		health.Up = true
+		log.Printf("[i] Backend %s of %s is up", backend, checker.proxy.UID())
+	}
+}
+
+// markUp is used when a backend was reached outside of a health check,
+// like after a cold start, so that it doesn't have to rise first
+func (checker *healthChecker) markUp(backend string) {
+	checker.mu.Lock()
+	defer checker.mu.Unlock()
+	health, ok := checker.backends[backend]
+	if !ok || health.Up {
+		return
+	}
+	health.Up = true
+	health.failures = 0
+	health.LastError = ""
+}
+
+func (checker *healthChecker) isUp(backend string) bool {
+	checker.mu.RLock()
+	defer checker.mu.RUnlock()
+	health, ok := checker.backends[backend]
+	return !ok || health.Up
+}
+
+func (checker *healthChecker) snapshot(backends []string) []BackendHealth {
+	checker.mu.RLock()
+	defer checker.mu.RUnlock()
+	var healths []BackendHealth
+	for _, backend := range backends {
+		health, ok := checker.backends[backend]
+		if !ok {
+			healths = append(healths, BackendHealth{Address: backend, Up: true})
+			continue
+		}
+		healths = append(healths, *health)
+	}
+	return healths
+}
+
+// checkBackend runs a handshake and status request against the backend
+func (proxy *Proxy) checkBackend(backend string) error {
+	rconn, err := DialTimeout(backend, proxy.Timeout())
+	if err != nil {
+		return err
+	}
+	defer rconn.Close()
+
+	port := 25565
+	if _, p, err := net.SplitHostPort(proxy.ListenTo()); err == nil {
+		if n, err := strconv.Atoi(p); err == nil {
+			port = n
+		}
+	}
+
+	hs := handshaking.ServerBoundHandshake{
+		ProtocolVersion: healthCheckProtocolVersion,
+		ServerAddress:   protocol.String(proxy.DomainName()),
+		ServerPort:      protocol.UnsignedShort(port),
+	}
+	return proxy.pingServer(rconn, hs, rconn.LocalAddr())
+}
+
+// startHealthCheck starts checking the backends of the proxy if health checks are
+// enabled and no health checker is running yet. The backends of domain patterns
+// are templates, so they can't be checked.
+func (proxy *Proxy) startHealthCheck() {
+	if !proxy.IsHealthCheckEnabled() {
+		return
+	}
+
+	for _, domainName := range proxy.DomainNames() {
+		if isDomainPattern(domainName) {
+			return
+		}
+	}
+
+	proxy.mu.Lock()
+	defer proxy.mu.Unlock()
+	if proxy.healthChecker != nil {
+		return
+	}
+	proxy.healthChecker = newHealthChecker(proxy)
+	go proxy.healthChecker.run()
+}
+
+func (proxy *Proxy) stopHealthCheck() {
+	proxy.mu.Lock()
+	defer proxy.mu.Unlock()
+	if proxy.healthChecker == nil {
+		return
+	}
+	close(proxy.healthChecker.stop)
+	proxy.healthChecker = nil
+}
+
+func (proxy *Proxy) runningHealthChecker() *healthChecker {
+	proxy.mu.Lock()
+	defer proxy.mu.Unlock()
+	return proxy.healthChecker
+}
+
+// BackendHealth returns the health of every backend. Without
+// health checks every backend is reported as up.
+func (proxy *Proxy) BackendHealth() []BackendHealth {
+	backends := proxy.Backends()
+	checker := proxy.runningHealthChecker()
+	if checker == nil {
+		var healths []BackendHealth
+		for _, backend := range backends {
+			healths = append(healths, BackendHealth{Address: backend, Up: true})
+		}
+		return healths
+	}
+	return checker.snapshot(backends)
+}
+
+// healthyBackends filters out the backends that the health checker considers down
+func (proxy *Proxy) healthyBackends(backends []string) []string {
+	checker := proxy.runningHealthChecker()
+	if checker == nil {
+		return backends
+	}
+
+	var healthy []string
+	for _, backend := range backends {
+		if checker.isUp(backend) {
+			healthy = append(healthy, backend)
+		}
+	}
+	return healthy
+}
+
+func (proxy *Proxy) markBackendUp(backend string) {
+	if checker := proxy.runningHealthChecker(); checker != nil {
+		checker.markUp(backend)
+	}
+}
@@ -0,0 +1,114 @@
+package infrared
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHealthChecker_record(t *testing.T) {
+	proxy := &Proxy{Config: &ProxyConfig{
+		ProxyTo:     StringList{"a:25565"},
+		HealthCheck: HealthCheckConfig{Rise: 2, Fall: 3},
+	}}
+	checker := newHealthChecker(proxy)
+	errDown := errors.New("connection refused")
+
+	tt := []struct {
+		err        error
+		expectedUp bool
+	}{
+		{err: errDown, expectedUp: true},
+		{err: errDown, expectedUp: true},
+		{err: errDown, expectedUp: false},
+		{err: nil, expectedUp: false},
+		{err: errDown, expectedUp: false},
+		{err: nil, expectedUp: false},
+		{err: nil, expectedUp: true},
+	}
+
+	if !checker.isUp("a:25565") {
+		t.Error("unchecked backends have to be up")
+	}
+
+	for i, tc := range tt {
+		checker.record("a:25565", time.Millisecond, tc.err)
+		if up := checker.isUp("a:25565"); up != tc.expectedUp {
+			t.Errorf("check %d: got: %v; want: %v", i, up, tc.expectedUp)
+		}
+	}
+
+	health := checker.snapshot(proxy.Backends())
+	if len(health) != 1 || health[0].Latency != time.Millisecond || health[0].LastError != "" {
+		t.Errorf("got: %v; want the latency of the last successful check", health)
+	}
+}
+
+func TestProxy_checkBackend(t *testing.T) {
+	listener, err := Listen(serverAddr(660))
+	if err != nil {
+		t.Fatalf("Can't listen to %v: %v", serverAddr(660), err)
+	}
+	defer listener.Close()
+
+	go func() {
+		rconn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer rconn.Close()
+
+		// Read the handshake and the status request before answering,
+		// so that the check never writes to a closed connection
+		for i := 0; i < 2; i++ {
+			if _, err := rconn.ReadPacket(); err != nil {
+				return
+			}
+		}
+
+		pk, err := StatusConfig{VersionName: serverVersionName}.StatusResponsePacket()
+		if err != nil {
+			return
+		}
+		_ = rconn.WritePacket(pk)
+	}()
+
+	proxy := &Proxy{Config: &ProxyConfig{
+		DomainName: serverDomain,
+		ListenTo:   StringList{gatewayAddr(660)},
+		Timeout:    1000,
+	}}
+
+	if err := proxy.checkBackend(serverAddr(660)); err != nil {
+		t.Errorf("online backend: %v", err)
+	}
+
+	if err := proxy.checkBackend(serverAddr(661)); err == nil {
+		t.Error("offline backend: expected an error")
+	}
+}
+
+func TestProxy_dialBackend_BackendsDown(t *testing.T) {
+	proxy := &Proxy{Config: &ProxyConfig{
+		ProxyTo:     StringList{serverAddr(662)},
+		Timeout:     1000,
+		HealthCheck: HealthCheckConfig{Enabled: true, Interval: 60000, Fall: 1},
+	}}
+
+	proxy.startHealthCheck()
+	defer proxy.stopHealthCheck()
+
+	deadline := time.Now().Add(time.Second)
+	for proxy.BackendHealth()[0].Up && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if _, _, err := proxy.dialBackend(""); err != ErrBackendsDown {
+		t.Errorf("got: %v; want: %v", err, ErrBackendsDown)
+	}
+
+	proxy.markBackendUp(serverAddr(662))
+	if !proxy.BackendHealth()[0].Up {
+		t.Error("backend was not marked as up")
+	}
+}
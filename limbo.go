@@ -0,0 +1,409 @@
+package infrared
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/haveachin/infrared/protocol"
+	"github.com/haveachin/infrared/protocol/configuration"
+	"github.com/haveachin/infrared/protocol/handshaking"
+	"github.com/haveachin/infrared/protocol/login"
+	"github.com/haveachin/infrared/protocol/play"
+)
+
+const (
+	// ColdStartModeLimbo finishes the login of a client itself and holds
+	// the client in a limbo until the server started
+	ColdStartModeLimbo = "limbo"
+
+	limboTickInterval      = time.Second
+	limboKeepAliveInterval = 10 * time.Second
+	limboPluginChannel     = "infrared:limbo"
+
+	// limboTitleStay keeps the title on the screen until the limbo ends
+	limboTitleStay = 20 * 60 * 60
+)
+
+var (
+	errLimboUnsupported = errors.New("limbo is not supported by the protocol version")
+	limboBossBarUUID    = protocol.UUID(uuid.NewV5(uuid.NamespaceURL, "https://github.com/haveachin/infrared/limbo"))
+)
+
+// limbo holds a client in a state where it waits for the server to start.
+// The state depends on what the protocol version of the client supports.
+type limbo interface {
+	// tick is called every limboTickInterval to keep the client connected and to show
+	// how long the client has waited so far
+	tick(waited time.Duration) error
+	// ready sends the client to the started server
+	ready(message protocol.ChatComponent) error
+	// disconnect kicks the client with a message
+	disconnect(message protocol.ChatComponent) error
+}
+
+// handleLimbo logs the client in and holds it in a limbo until the server started
+func (proxy *Proxy) handleLimbo(conn Conn, hs handshaking.ServerBoundHandshake, connRemoteAddr net.Addr) error {
+	_, loginStart, err := proxy.sniffUsername(conn, hs, connRemoteAddr)
+	if err != nil {
+		return err
+	}
+	username := string(loginStart.Name)
+
+	profile, err := proxy.gameProfile(conn, hs, loginStart)
+	if err != nil {
+		return err
+	}
+
+	if err := proxy.startProcessIfNotRunning(); err != nil {
+		return err
+	}
+	defer proxy.timeoutProcess()
+
+	maxWait := proxy.ColdStartMaxWait()
+	message := proxy.templateMessage(proxy.ColdStartLimboMessage(), conn, username, nil)
+	l, err := newLimbo(conn, hs, profile, message, maxWait)
+	if err == errLimboUnsupported {
+		log.Printf("[i] %s can't join the limbo of %s with protocol version %d", connRemoteAddr, proxy.UID(), hs.ProtocolVersion)
+		reason := proxy.templateMessage(proxy.DisconnectMessage(), conn, username, nil)
+		return conn.WritePacket(login.ClientBoundDisconnect{
+			Reason: reason.Chat(),
+		}.Marshal())
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("[i] %s joined the limbo of %s", connRemoteAddr, proxy.UID())
+
+	done := make(chan struct{})
+	defer close(done)
+
+	serverReady := make(chan error, 1)
+	go func() {
+		rconn, _, err := proxy.waitForServer(hs, connRemoteAddr, maxWait, done)
+		if err == nil {
+			rconn.Close()
+		}
+		serverReady <- err
+	}()
+
+	connClosed := make(chan error, 1)
+	go func() {
+		// Everything that the client sends is ignored; even
+		// the answers to keep-alives are only sent to not time out
+		for {
+			if _, err := conn.ReadPacket(); err != nil {
+				connClosed <- err
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(limboTickInterval)
+	defer ticker.Stop()
+	start := time.Now()
+
+	for {
+		select {
+		case err := <-connClosed:
+			return err
+		case err := <-serverReady:
+			if err != nil {
+				log.Printf("[i] %s did not start in time for %s in the limbo; error: %s", proxy.ProxyTo(), connRemoteAddr, err)
+				return l.disconnect(proxy.templateMessage(proxy.DisconnectMessage(), conn, username, nil))
+			}
+			log.Printf("[i] %s is ready for %s in the limbo", proxy.ProxyTo(), connRemoteAddr)
+			return l.ready(proxy.templateMessage(proxy.ColdStartReadyMessage(), conn, username, nil))
+		case <-ticker.C:
+			if err := l.tick(time.Since(start)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// newLimbo picks the limbo for the protocol version of the client and lets the client join it.
+// Clients that enter the configuration state are held there. Clients that would need registry
+// data or chunks to join a world are held in the login state. Clients up to 1.13.2 join an empty world.
+func newLimbo(conn Conn, hs handshaking.ServerBoundHandshake, profile GameProfile, message protocol.ChatComponent, maxWait time.Duration) (limbo, error) {
+	protocolVersion := hs.ProtocolVersion
+	id, err := uuid.FromString(profile.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	loginSuccess := login.ClientBoundLoginSuccess{
+		UUID:     protocol.UUID(id),
+		Username: protocol.String(profile.Name),
+	}
+
+	switch {
+	case protocolVersion >= protocol.Version1_20_2:
+		l := &configurationLimbo{
+			conn:            conn,
+			protocolVersion: protocolVersion,
+			host:            hs.ParseServerAddress(),
+			port:            int(hs.ServerPort),
+		}
+		return l, l.join(loginSuccess)
+	case protocolVersion > protocol.Version1_13_2:
+		l := &loginLimbo{conn: conn}
+		return l, l.join()
+	case protocol.DefaultRegistry.IsSupported(protocolVersion, protocol.StatePlay, protocol.ClientBound,
+		protocol.PacketJoinGame, protocol.PacketPlayerPositionAndLook, protocol.PacketKeepAlive,
+		protocol.PacketTitle, protocol.PacketDisconnect):
+		l := &playLimbo{
+			conn:            conn,
+			protocolVersion: protocolVersion,
+			message:         message,
+			maxWait:         maxWait,
+		}
+		return l, l.join(loginSuccess)
+	}
+
+	return nil, errLimboUnsupported
+}
+
+// loginLimbo holds clients in the login state by sending them login plugin requests,
+// which they have to answer, so that they don't time out
+type loginLimbo struct {
+	conn      Conn
+	messageID protocol.VarInt
+	lastSent  time.Time
+}
+
+func (l *loginLimbo) join() error {
+	return l.sendPluginRequest()
+}
+
+func (l *loginLimbo) sendPluginRequest() error {
+	l.messageID++
+	l.lastSent = time.Now()
+	return l.conn.WritePacket(login.ClientBoundLoginPluginRequest{
+		MessageID: l.messageID,
+		Channel:   limboPluginChannel,
+	}.Marshal())
+}
+
+func (l *loginLimbo) tick(time.Duration) error {
+	if time.Since(l.lastSent) < limboKeepAliveInterval {
+		return nil
+	}
+	return l.sendPluginRequest()
+}
+
+func (l *loginLimbo) ready(message protocol.ChatComponent) error {
+	return l.disconnect(message)
+}
+
+func (l *loginLimbo) disconnect(message protocol.ChatComponent) error {
+	return l.conn.WritePacket(login.ClientBoundDisconnect{
+		Reason: message.Chat(),
+	}.Marshal())
+}
+
+// configurationLimbo holds clients in the configuration state with keep-alives.
+// Once the server is ready, 1.20.5 and newer clients are transferred back to
+// the address that they connected to.
+type configurationLimbo struct {
+	conn            Conn
+	protocolVersion protocol.VarInt
+	host            string
+	port            int
+	lastSent        time.Time
+}
+
+func (l *configurationLimbo) join(loginSuccess login.ClientBoundLoginSuccess) error {
+	if err := l.conn.WritePacket(loginSuccess.Marshal(l.protocolVersion)); err != nil {
+		return err
+	}
+
+	// The client switches to the configuration state with its Login Acknowledged packet
+	for {
+		pk, err := l.conn.ReadPacket()
+		if err != nil {
+			return err
+		}
+
+		if _, err := login.UnmarshalServerBoundLoginAcknowledged(pk); err == nil {
+			break
+		}
+	}
+
+	return l.sendKeepAlive()
+}
+
+func (l *configurationLimbo) sendKeepAlive() error {
+	l.lastSent = time.Now()
+	pk, err := configuration.ClientBoundKeepAlive{
+		KeepAliveID: protocol.Long(l.lastSent.UnixNano()),
+	}.Marshal(l.protocolVersion)
+	if err != nil {
+		return err
+	}
+	return l.conn.WritePacket(pk)
+}
+
+func (l *configurationLimbo) tick(time.Duration) error {
+	if time.Since(l.lastSent) < limboKeepAliveInterval {
+		return nil
+	}
+	return l.sendKeepAlive()
+}
+
+func (l *configurationLimbo) ready(message protocol.ChatComponent) error {
+	if l.protocolVersion < protocol.Version1_20_5 || l.host == "" {
+		return l.disconnect(message)
+	}
+
+	pk, err := configuration.ClientBoundTransfer{
+		Host: protocol.String(l.host),
+		Port: protocol.VarInt(l.port),
+	}.Marshal(l.protocolVersion)
+	if err != nil {
+		return err
+	}
+	return l.conn.WritePacket(pk)
+}
+
+func (l *configurationLimbo) disconnect(message protocol.ChatComponent) error {
+	pk, err := configuration.ClientBoundDisconnect{
+		Reason: message,
+	}.Marshal(l.protocolVersion)
+	if err != nil {
+		return err
+	}
+	return l.conn.WritePacket(pk)
+}
+
+// playLimbo lets clients join an empty world in spectator mode. A title
+// and a boss bar show the player how long they have been waiting.
+type playLimbo struct {
+	conn            Conn
+	protocolVersion protocol.VarInt
+	message         protocol.ChatComponent
+	maxWait         time.Duration
+	lastSent        time.Time
+}
+
+func (l *playLimbo) join(loginSuccess login.ClientBoundLoginSuccess) error {
+	if err := l.conn.WritePacket(loginSuccess.Marshal(l.protocolVersion)); err != nil {
+		return err
+	}
+
+	packets := []interface {
+		Marshal(protocol.VarInt) (protocol.Packet, error)
+	}{
+		play.ClientBoundJoinGame{
+			EntityID:   1,
+			Gamemode:   play.GamemodeSpectator,
+			Dimension:  play.DimensionOverworld,
+			MaxPlayers: 1,
+			LevelType:  "flat",
+		},
+		play.ClientBoundPlayerPositionAndLook{
+			Y: 64,
+		},
+		play.ClientBoundTitle{
+			Action:  play.TitleActionSetTimes,
+			FadeIn:  10,
+			Stay:    limboTitleStay,
+			FadeOut: 20,
+		},
+		play.ClientBoundTitle{
+			Action: play.TitleActionSetSubtitle,
+			Text:   l.progressText(0),
+		},
+		play.ClientBoundTitle{
+			Action: play.TitleActionSetTitle,
+			Text:   l.message,
+		},
+	}
+
+	if l.hasBossBar() {
+		packets = append(packets, play.ClientBoundBossBar{
+			UUID:     limboBossBarUUID,
+			Action:   play.BossBarActionAdd,
+			Title:    l.message,
+			Color:    play.BossBarColorYellow,
+			Division: play.BossBarDivisionNone,
+		})
+	}
+
+	for _, packet := range packets {
+		if err := l.writePacket(packet); err != nil {
+			return err
+		}
+	}
+
+	l.lastSent = time.Now()
+	return nil
+}
+
+func (l *playLimbo) hasBossBar() bool {
+	_, ok := protocol.DefaultRegistry.PacketID(l.protocolVersion, protocol.StatePlay, protocol.ClientBound, protocol.PacketBossBar)
+	return ok
+}
+
+func (l *playLimbo) progressText(waited time.Duration) protocol.ChatComponent {
+	return protocol.ChatComponent{
+		Text:  fmt.Sprintf("%ds / %ds", int(waited.Seconds()), int(l.maxWait.Seconds())),
+		Color: "gray",
+	}
+}
+
+func (l *playLimbo) writePacket(packet interface {
+	Marshal(protocol.VarInt) (protocol.Packet, error)
+}) error {
+	pk, err := packet.Marshal(l.protocolVersion)
+	if err != nil {
+		return err
+	}
+	return l.conn.WritePacket(pk)
+}
+
+func (l *playLimbo) tick(waited time.Duration) error {
+	if err := l.writePacket(play.ClientBoundTitle{
+		Action: play.TitleActionSetSubtitle,
+		Text:   l.progressText(waited),
+	}); err != nil {
+		return err
+	}
+
+	if l.hasBossBar() && l.maxWait > 0 {
+		health := float64(waited) / float64(l.maxWait)
+		if health > 1 {
+			health = 1
+		}
+
+		if err := l.writePacket(play.ClientBoundBossBar{
+			UUID:   limboBossBarUUID,
+			Action: play.BossBarActionUpdateHealth,
+			Health: protocol.Float(health),
+		}); err != nil {
+			return err
+		}
+	}
+
+	if time.Since(l.lastSent) < limboKeepAliveInterval {
+		return nil
+	}
+
+	l.lastSent = time.Now()
+	return l.writePacket(play.ClientBoundKeepAlive{
+		KeepAliveID: protocol.Long(l.lastSent.Unix()),
+	})
+}
+
+func (l *playLimbo) ready(message protocol.ChatComponent) error {
+	return l.disconnect(message)
+}
+
+func (l *playLimbo) disconnect(message protocol.ChatComponent) error {
+	return l.writePacket(play.ClientBoundDisconnect{
+		Reason: message,
+	})
+}
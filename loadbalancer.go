@@ -0,0 +1,207 @@
+package infrared
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/haveachin/infrared/protocol/handshaking"
+	"github.com/haveachin/infrared/protocol/login"
+)
+
+const (
+	LoadBalancerRoundRobin       = "roundRobin"
+	LoadBalancerLeastConnections = "leastConnections"
+	LoadBalancerRandom           = "random"
+	LoadBalancerWeighted         = "weighted"
+
+	StickyUsername = "username"
+	StickyIP       = "ip"
+)
+
+// player is a client that is connected to a backend of the proxy
+type player struct {
+	username      string
+	backend       string
+	remoteAddress string
+	joinedAt      time.Time
+	// metricUID is the metric UID of the proxy when the player joined, so that
+	// the player is removed from the same series of the players metric
+	metricUID string
+}
+
+// dialBackend connects to the first healthy backend that accepts the connection. The backend
+// that the load balancer picks is dialed first and unreachable backends are skipped.
+func (proxy *Proxy) dialBackend(stickyKey string) (Conn, string, error) {
+	backends := proxy.backendOrder(stickyKey)
+	healthy := proxy.healthyBackends(backends)
+	if len(backends) > 0 && len(healthy) == 0 {
+		return nil, "", ErrBackendsDown
+	}
+	return proxy.dialFirst(healthy)
+}
+
+// dialFirst dials the backends in order and returns the first connection
+func (proxy *Proxy) dialFirst(backends []string) (Conn, string, error) {
+	err := ErrNoProxyTo
+	for _, backend := range backends {
+		var rconn Conn
+		start := time.Now()
+		rconn, err = DialTimeout(backend, proxy.Timeout())
+		if err != nil {
+			metricDialFailures.With(proxy.metricBackend(backend)).Inc()
+			continue
+		}
+		observeDuration(metricDialDuration.With(proxy.metricBackend(backend)), start)
+		return rconn, backend, nil
+	}
+
+	return nil, "", err
+}
+
+// backendOrder returns the backends in the order in which they are dialed.
+// Sticky keys have their own order. Otherwise the backend that the strategy
+// picks comes first and the other backends follow as fallbacks.
+func (proxy *Proxy) backendOrder(stickyKey string) []string {
+	backends := proxy.Backends()
+	if len(backends) <= 1 {
+		return backends
+	}
+
+	if stickyKey != "" {
+		return proxy.stickyOrder(stickyKey, backends)
+	}
+
+	first := proxy.pickBackend(backends)
+
+	order := []string{first}
+	for _, backend := range backends {
+		if backend != first {
+			order = append(order, backend)
+		}
+	}
+	return order
+}
+
+func (proxy *Proxy) pickBackend(backends []string) string {
+	strategy := proxy.LoadBalancerStrategy()
+	weights := proxy.LoadBalancerWeights()
+
+	proxy.mu.Lock()
+	defer proxy.mu.Unlock()
+	if proxy.rand == nil {
+		proxy.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+
+	switch strategy {
+	case LoadBalancerLeastConnections:
+		connections := map[string]int{}
+		for _, p := range proxy.players {
+			connections[p.backend]++
+		}
+
+		least := backends[0]
+		for _, backend := range backends[1:] {
+			if connections[backend] < connections[least] {
+				least = backend
+			}
+		}
+		return least
+	case LoadBalancerRandom:
+		return backends[proxy.rand.Intn(len(backends))]
+	case LoadBalancerWeighted:
+		total := 0
+		for _, backend := range backends {
+			total += backendWeight(weights, backend)
+		}
+
+		if total <= 0 {
+			return backends[0]
+		}
+
+		n := proxy.rand.Intn(total)
+		for _, backend := range backends {
+			n -= backendWeight(weights, backend)
+			if n < 0 {
+				return backend
+			}
+		}
+		return backends[len(backends)-1]
+	default:
+		backend := backends[proxy.roundRobin%len(backends)]
+		proxy.roundRobin++
+		return backend
+	}
+}
+
+// backendWeight defaults to 1 for backends without a configured weight
+func backendWeight(weights map[string]int, backend string) int {
+	weight, ok := weights[backend]
+	if !ok {
+		return 1
+	}
+
+	if weight < 0 {
+		return 0
+	}
+	return weight
+}
+
+// stickyKey returns the username or the IP of a client that logs in, depending on the sticky mode.
+// The username is peeked from the Login Start packet, so that it can still be read afterwards.
+func (proxy *Proxy) stickyKey(conn Conn, hs handshaking.ServerBoundHandshake, connRemoteAddr net.Addr) string {
+	if !hs.IsLoginRequest() {
+		return ""
+	}
+
+	switch proxy.LoadBalancerSticky() {
+	case StickyUsername:
+		pk, err := conn.PeekPacket()
+		if err != nil {
+			return ""
+		}
+
+		loginStart, err := login.UnmarshalServerBoundLoginStart(pk, hs.ProtocolVersion)
+		if err != nil {
+			return ""
+		}
+		return StickyUsername + ":" + string(loginStart.Name)
+	case StickyIP:
+		host, _, err := net.SplitHostPort(connRemoteAddr.String())
+		if err != nil {
+			host = connRemoteAddr.String()
+		}
+		return StickyIP + ":" + host
+	}
+
+	return ""
+}
+
+// stickyOrder ranks the backends for the sticky key by rendezvous hashing, so that a key
+// always prefers the same backend without remembering it. If that backend is down or
+// removed, only its keys move to the next backend of their order. The weights of the
+// weighted strategy are respected and backends with a weight of 0 come last.
+func (proxy *Proxy) stickyOrder(stickyKey string, backends []string) []string {
+	var weights map[string]int
+	if proxy.LoadBalancerStrategy() == LoadBalancerWeighted {
+		weights = proxy.LoadBalancerWeights()
+	}
+
+	scores := make(map[string]float64, len(backends))
+	for _, backend := range backends {
+		hash := sha256.Sum256([]byte(stickyKey + "\x00" + backend))
+		// u is uniformly distributed in (0, 1)
+		u := (float64(binary.BigEndian.Uint64(hash[:])>>11) + 0.5) / (1 << 53)
+		scores[backend] = float64(backendWeight(weights, backend)) / -math.Log(u)
+	}
+
+	order := append([]string(nil), backends...)
+	sort.SliceStable(order, func(i, j int) bool {
+		return scores[order[i]] > scores[order[j]]
+	})
+	return order
+}
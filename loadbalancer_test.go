@@ -0,0 +1,195 @@
+package infrared
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/haveachin/infrared/protocol/handshaking"
+	"github.com/haveachin/infrared/protocol/login"
+)
+
+func TestProxy_pickBackend(t *testing.T) {
+	backends := []string{"a:25565", "b:25565", "c:25565"}
+	tt := []struct {
+		name     string
+		config   LoadBalancerConfig
+		players  []string
+		expected []string
+	}{
+		{
+			name:     "RoundRobin",
+			expected: []string{"a:25565", "b:25565", "c:25565", "a:25565"},
+		},
+		{
+			name:     "LeastConnections",
+			config:   LoadBalancerConfig{Strategy: LoadBalancerLeastConnections},
+			players:  []string{"a:25565", "a:25565", "c:25565"},
+			expected: []string{"b:25565", "b:25565"},
+		},
+		{
+			name: "Weighted",
+			config: LoadBalancerConfig{
+				Strategy: LoadBalancerWeighted,
+				Weights:  map[string]int{"a:25565": 0, "c:25565": 0},
+			},
+			expected: []string{"b:25565", "b:25565", "b:25565"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			proxy := &Proxy{Config: &ProxyConfig{
+				ProxyTo:      backends,
+				LoadBalancer: tc.config,
+			}}
+
+			proxy.players = map[Conn]player{}
+			for _, backend := range tc.players {
+				proxy.players[&conn{}] = player{backend: backend}
+			}
+
+			for i, expected := range tc.expected {
+				if actual := proxy.pickBackend(backends); actual != expected {
+					t.Errorf("pick %d: got: %s; want: %s", i, actual, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestProxy_pickBackend_Random(t *testing.T) {
+	backends := []string{"a:25565", "b:25565"}
+	proxy := &Proxy{Config: &ProxyConfig{
+		ProxyTo:      backends,
+		LoadBalancer: LoadBalancerConfig{Strategy: LoadBalancerRandom},
+	}}
+
+	for i := 0; i < 10; i++ {
+		if backend := proxy.pickBackend(backends); !containsString(backends, backend) {
+			t.Errorf("got: %s; want one of: %v", backend, backends)
+		}
+	}
+}
+
+func TestProxy_backendOrder_Sticky(t *testing.T) {
+	backends := []string{"a:25565", "b:25565", "c:25565"}
+	proxy := &Proxy{Config: &ProxyConfig{
+		ProxyTo: backends,
+	}}
+
+	firsts := map[string]int{}
+	for i := 0; i < 300; i++ {
+		key := fmt.Sprintf("username:player%d", i)
+		order := proxy.backendOrder(key)
+		if len(order) != len(backends) {
+			t.Fatalf("got: %v; want every backend of %v", order, backends)
+		}
+
+		if again := proxy.backendOrder(key); !equalStrings(order, again) {
+			t.Errorf("%s: got: %v; want the same order: %v", key, again, order)
+		}
+		firsts[order[0]]++
+
+		// Removing another backend must not move the key, removing its backend
+		// moves it to the next backend of its order
+		proxy.Config.ProxyTo = StringList{order[0], order[1]}
+		if first := proxy.backendOrder(key)[0]; first != order[0] {
+			t.Errorf("%s: got: %s; want: %s", key, first, order[0])
+		}
+		proxy.Config.ProxyTo = StringList{order[1], order[2]}
+		if first := proxy.backendOrder(key)[0]; first != order[1] {
+			t.Errorf("%s: got: %s; want: %s", key, first, order[1])
+		}
+		proxy.Config.ProxyTo = backends
+	}
+
+	for _, backend := range backends {
+		if firsts[backend] < 50 {
+			t.Errorf("%s: got: %d of 300 keys; want them to be spread evenly", backend, firsts[backend])
+		}
+	}
+
+	proxy.Config.LoadBalancer = LoadBalancerConfig{
+		Strategy: LoadBalancerWeighted,
+		Weights:  map[string]int{"a:25565": 0},
+	}
+	if order := proxy.backendOrder("username:Notch"); order[2] != "a:25565" {
+		t.Errorf("got: %v; want a:25565 without weight last", order)
+	}
+}
+
+func TestProxy_dialBackend(t *testing.T) {
+	listener, err := Listen(serverAddr(650))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	proxy := &Proxy{Config: &ProxyConfig{
+		ProxyTo: StringList{serverAddr(651), serverAddr(650)},
+		Timeout: 1000,
+	}}
+
+	for i := 0; i < 2; i++ {
+		rconn, backend, err := proxy.dialBackend("ip:127.0.0.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		rconn.Close()
+
+		if backend != serverAddr(650) {
+			t.Errorf("got: %s; want the reachable backend %s", backend, serverAddr(650))
+		}
+	}
+
+	proxy.Config.ProxyTo = nil
+	if _, _, err := proxy.dialBackend(""); err != ErrNoProxyTo {
+		t.Errorf("got: %v; want: %v", err, ErrNoProxyTo)
+	}
+}
+
+func TestProxy_stickyKey(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	hs := handshaking.ServerBoundHandshake{
+		ProtocolVersion: 754,
+		NextState:       handshaking.ServerBoundHandshakeLoginState,
+	}
+
+	go func() {
+		_ = wrapConn(client).WritePacket(login.ServerLoginStart{Name: "Notch"}.Marshal(hs.ProtocolVersion))
+	}()
+
+	proxy := &Proxy{Config: &ProxyConfig{
+		LoadBalancer: LoadBalancerConfig{Sticky: StickyUsername},
+	}}
+
+	conn := wrapConn(server)
+	if key := proxy.stickyKey(conn, hs, client.RemoteAddr()); key != "username:Notch" {
+		t.Errorf("got: %s; want: username:Notch", key)
+	}
+
+	// The Login Start packet has to be left for the proxy to read
+	pk, err := conn.ReadPacket()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := login.UnmarshalServerBoundLoginStart(pk, hs.ProtocolVersion); err != nil {
+		t.Error(err)
+	}
+
+	proxy.Config.LoadBalancer.Sticky = StickyIP
+	addr := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 50000}
+	if key := proxy.stickyKey(conn, hs, addr); key != "ip:10.0.0.1" {
+		t.Errorf("got: %s; want: ip:10.0.0.1", key)
+	}
+
+	hs.NextState = handshaking.ServerBoundHandshakeStatusState
+	if key := proxy.stickyKey(conn, hs, addr); key != "" {
+		t.Errorf("got: %s; want no key for status requests", key)
+	}
+}
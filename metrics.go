@@ -0,0 +1,131 @@
+package infrared
+
+import (
+	"time"
+
+	"github.com/haveachin/infrared/metrics"
+	"github.com/haveachin/infrared/protocol/handshaking"
+)
+
+const (
+	directionServerBound = "serverbound"
+	directionClientBound = "clientbound"
+)
+
+// Metrics holds all metrics of Infrared. It can be served as a http.Handler
+// for Prometheus to scrape.
+var Metrics = metrics.NewRegistry()
+
+var (
+	metricConnections = metrics.NewCounterVec(
+		"infrared_connections_total",
+		"Connections accepted by a listener",
+		"listener",
+	)
+	metricHandshakes = metrics.NewCounterVec(
+		"infrared_handshakes_total",
+		"Handshakes by the state that the client requests next",
+		"next_state",
+	)
+	metricUnknownHosts = metrics.NewCounterVec(
+		"infrared_unknown_host_total",
+		"Requests for domains without a proxy on a listener",
+		"listener",
+	)
+	metricPlayersOnline = metrics.NewGaugeVec(
+		"infrared_players_online",
+		"Players that are connected through a proxy",
+		"proxy_uid",
+	)
+	metricBytes = metrics.NewCounterVec(
+		"infrared_bytes_total",
+		"Bytes piped between clients and backends",
+		"proxy_uid", "direction",
+	)
+	metricDialDuration = metrics.NewHistogramVec(
+		"infrared_backend_dial_duration_seconds",
+		"Time it took to connect to a backend",
+		nil,
+		"backend",
+	)
+	metricDialFailures = metrics.NewCounterVec(
+		"infrared_backend_dial_failures_total",
+		"Failed connection attempts to a backend",
+		"backend",
+	)
+	metricContainerStarts = metrics.NewCounterVec(
+		"infrared_container_starts_total",
+		"Containers started by a proxy",
+		"proxy_uid",
+	)
+	metricContainerStops = metrics.NewCounterVec(
+		"infrared_container_stops_total",
+		"Containers stopped by a proxy",
+		"proxy_uid",
+	)
+	metricContainerStartDuration = metrics.NewHistogramVec(
+		"infrared_container_start_duration_seconds",
+		"Time it took to start a container",
+		[]float64{.1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+		"proxy_uid",
+	)
+	metricCallbackFailures = metrics.NewCounterVec(
+		"infrared_callback_failures_total",
+		"Failed attempts to deliver an event to a callback server",
+		"event",
+	)
+	metricCallbackDropped = metrics.NewCounterVec(
+		"infrared_callback_dropped_total",
+		"Events that were dropped without being delivered to a callback server",
+		"event",
+	)
+	metricSinkFailures = metrics.NewCounterVec(
+		"infrared_sink_failures_total",
+		"Events that could not be written to a sink",
+		"sink",
+		"event",
+	)
+	metricSinkDropped = metrics.NewCounterVec(
+		"infrared_sink_dropped_total",
+		"Events that were dropped, since the queue of their sink was full",
+		"sink",
+		"event",
+	)
+)
+
+func init() {
+	Metrics.Register(
+		metricConnections,
+		metricHandshakes,
+		metricUnknownHosts,
+		metricPlayersOnline,
+		metricBytes,
+		metricDialDuration,
+		metricDialFailures,
+		metricContainerStarts,
+		metricContainerStops,
+		metricContainerStartDuration,
+		metricCallbackFailures,
+		metricCallbackDropped,
+		metricSinkFailures,
+		metricSinkDropped,
+	)
+}
+
+// nextStateLabel names the state of a handshake for the handshake metric
+func nextStateLabel(hs handshaking.ServerBoundHandshake) string {
+	switch {
+	case hs.IsStatusRequest():
+		return "status"
+	case hs.IsLoginRequest():
+		return "login"
+	case hs.IsTransferRequest():
+		return "transfer"
+	default:
+		return "unknown"
+	}
+}
+
+func observeDuration(histogram *metrics.Histogram, start time.Time) {
+	histogram.Observe(time.Since(start).Seconds())
+}
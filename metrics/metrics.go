@@ -0,0 +1,354 @@
+// Package metrics implements counters, gauges and histograms with labels
+// that are exposed in the Prometheus text format.
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets are the upper bounds of histogram buckets in seconds
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Collector is a metric that can be registered in a Registry
+type Collector interface {
+	write(buf *bytes.Buffer)
+}
+
+// Registry writes all of its collectors in the Prometheus text format
+type Registry struct {
+	mu         sync.Mutex
+	collectors []Collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) Register(collectors ...Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, collectors...)
+}
+
+// Bytes returns the metrics of all collectors in the Prometheus text format
+func (r *Registry) Bytes() []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var buf bytes.Buffer
+	for _, c := range r.collectors {
+		c.write(&buf)
+	}
+	return buf.Bytes()
+}
+
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", contentType)
+	_, _ = w.Write(r.Bytes())
+}
+
+type child interface {
+	write(buf *bytes.Buffer, name, labels string)
+}
+
+// vec holds the children of a metric for every combination of label values
+type vec struct {
+	name       string
+	help       string
+	typ        string
+	labelNames []string
+	newChild   func() child
+
+	mu       sync.Mutex
+	children map[string]child
+	labels   map[string]string
+}
+
+func newVec(name, help, typ string, labelNames []string, newChild func() child) *vec {
+	return &vec{
+		name:       name,
+		help:       help,
+		typ:        typ,
+		labelNames: labelNames,
+		newChild:   newChild,
+		children:   map[string]child{},
+		labels:     map[string]string{},
+	}
+}
+
+func (v *vec) with(labelValues []string) child {
+	if len(labelValues) != len(v.labelNames) {
+		panic(fmt.Sprintf("metrics: %s has %d labels, but got %d values", v.name, len(v.labelNames), len(labelValues)))
+	}
+
+	key := strings.Join(labelValues, "\xff")
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	c, ok := v.children[key]
+	if !ok {
+		c = v.newChild()
+		v.children[key] = c
+		v.labels[key] = formatLabels(v.labelNames, labelValues)
+	}
+	return c
+}
+
+func (v *vec) delete(labelValues []string) {
+	key := strings.Join(labelValues, "\xff")
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	delete(v.children, key)
+	delete(v.labels, key)
+}
+
+func (v *vec) write(buf *bytes.Buffer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	fmt.Fprintf(buf, "# HELP %s %s\n", v.name, escapeHelp(v.help))
+	fmt.Fprintf(buf, "# TYPE %s %s\n", v.name, v.typ)
+
+	keys := make([]string, 0, len(v.children))
+	for key := range v.children {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		v.children[key].write(buf, v.name, v.labels[key])
+	}
+}
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, len(names))
+	for i := range names {
+		pairs[i] = fmt.Sprintf(`%s="%s"`, names[i], escapeLabelValue(values[i]))
+	}
+	return strings.Join(pairs, ",")
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(v string) string {
+	return labelValueReplacer.Replace(v)
+}
+
+func escapeHelp(help string) string {
+	help = strings.Replace(help, `\`, `\\`, -1)
+	return strings.Replace(help, "\n", `\n`, -1)
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func writeSample(buf *bytes.Buffer, name, labels string, value float64) {
+	if labels == "" {
+		fmt.Fprintf(buf, "%s %s\n", name, formatValue(value))
+		return
+	}
+	fmt.Fprintf(buf, "%s{%s} %s\n", name, labels, formatValue(value))
+}
+
+// value is a float that is safe for concurrent use
+type value struct {
+	mu sync.Mutex
+	v  float64
+}
+
+func (v *value) add(delta float64) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.v += delta
+}
+
+func (v *value) set(x float64) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.v = x
+}
+
+func (v *value) get() float64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.v
+}
+
+// Counter is a value that only goes up
+type Counter struct {
+	value
+}
+
+func (c *Counter) Inc() {
+	c.add(1)
+}
+
+// Add ignores negative values, since counters can't go down
+func (c *Counter) Add(delta float64) {
+	if delta < 0 {
+		return
+	}
+	c.add(delta)
+}
+
+func (c *Counter) Value() float64 {
+	return c.get()
+}
+
+func (c *Counter) write(buf *bytes.Buffer, name, labels string) {
+	writeSample(buf, name, labels, c.get())
+}
+
+type CounterVec struct {
+	*vec
+}
+
+func NewCounterVec(name, help string, labelNames ...string) *CounterVec {
+	return &CounterVec{newVec(name, help, "counter", labelNames, func() child {
+		return &Counter{}
+	})}
+}
+
+func (v *CounterVec) With(labelValues ...string) *Counter {
+	return v.with(labelValues).(*Counter)
+}
+
+// Gauge is a value that can go up and down
+type Gauge struct {
+	value
+}
+
+func (g *Gauge) Set(x float64) {
+	g.set(x)
+}
+
+func (g *Gauge) Inc() {
+	g.add(1)
+}
+
+func (g *Gauge) Dec() {
+	g.add(-1)
+}
+
+func (g *Gauge) Add(delta float64) {
+	g.add(delta)
+}
+
+func (g *Gauge) Value() float64 {
+	return g.get()
+}
+
+func (g *Gauge) write(buf *bytes.Buffer, name, labels string) {
+	writeSample(buf, name, labels, g.get())
+}
+
+type GaugeVec struct {
+	*vec
+}
+
+func NewGaugeVec(name, help string, labelNames ...string) *GaugeVec {
+	return &GaugeVec{newVec(name, help, "gauge", labelNames, func() child {
+		return &Gauge{}
+	})}
+}
+
+func (v *GaugeVec) With(labelValues ...string) *Gauge {
+	return v.with(labelValues).(*Gauge)
+}
+
+// Delete removes the gauge with the label values, like the players
+// of a proxy that was removed
+func (v *GaugeVec) Delete(labelValues ...string) {
+	v.delete(labelValues)
+}
+
+// Histogram counts observations in buckets with an upper bound
+type Histogram struct {
+	mu      sync.Mutex
+	bounds  []float64
+	buckets []uint64
+	sum     float64
+	count   uint64
+}
+
+func (h *Histogram) Observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, bound := range h.bounds {
+		if v <= bound {
+			h.buckets[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+func (h *Histogram) Count() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.count
+}
+
+func (h *Histogram) write(buf *bytes.Buffer, name, labels string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	separator := ""
+	if labels != "" {
+		separator = ","
+	}
+
+	for i, bound := range h.bounds {
+		le := fmt.Sprintf(`%s%sle="%s"`, labels, separator, formatValue(bound))
+		writeSample(buf, name+"_bucket", le, float64(h.buckets[i]))
+	}
+	writeSample(buf, name+"_bucket", fmt.Sprintf(`%s%sle="+Inf"`, labels, separator), float64(h.count))
+	writeSample(buf, name+"_sum", labels, h.sum)
+	writeSample(buf, name+"_count", labels, float64(h.count))
+}
+
+type HistogramVec struct {
+	*vec
+}
+
+// NewHistogramVec creates a histogram with the upper bounds of its buckets.
+// If buckets is nil, DefaultBuckets are used.
+func NewHistogramVec(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+
+	bounds := append([]float64(nil), buckets...)
+	sort.Float64s(bounds)
+
+	return &HistogramVec{newVec(name, help, "histogram", labelNames, func() child {
+		return &Histogram{
+			bounds:  bounds,
+			buckets: make([]uint64, len(bounds)),
+		}
+	})}
+}
+
+func (v *HistogramVec) With(labelValues ...string) *Histogram {
+	return v.with(labelValues).(*Histogram)
+}
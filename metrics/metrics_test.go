@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRegistry_Bytes(t *testing.T) {
+	connections := NewCounterVec("test_connections_total", "Accepted connections.", "listener")
+	players := NewGaugeVec("test_players_online", "Players online.", "proxy_uid")
+	latency := NewHistogramVec("test_dial_duration_seconds", "Dial latency.", []float64{0.1, 1}, "backend")
+	errors := NewCounterVec("test_errors_total", "Errors.")
+
+	registry := NewRegistry()
+	registry.Register(connections, players, latency, errors)
+
+	connections.With(":25566").Inc()
+	connections.With(":25565").Add(2)
+	connections.With(":25565").Add(-1)
+	players.With(`mc."example".com@:25565`).Inc()
+	players.With("gone@:25565").Inc()
+	players.Delete("gone@:25565")
+	latency.With("10.0.0.1:25565").Observe(0.05)
+	latency.With("10.0.0.1:25565").Observe(0.5)
+	latency.With("10.0.0.1:25565").Observe(5)
+
+	expected := `# HELP test_connections_total Accepted connections.
+# TYPE test_connections_total counter
+test_connections_total{listener=":25565"} 2
+test_connections_total{listener=":25566"} 1
+# HELP test_players_online Players online.
+# TYPE test_players_online gauge
+test_players_online{proxy_uid="mc.\"example\".com@:25565"} 1
+# HELP test_dial_duration_seconds Dial latency.
+# TYPE test_dial_duration_seconds histogram
+test_dial_duration_seconds_bucket{backend="10.0.0.1:25565",le="0.1"} 1
+test_dial_duration_seconds_bucket{backend="10.0.0.1:25565",le="1"} 2
+test_dial_duration_seconds_bucket{backend="10.0.0.1:25565",le="+Inf"} 3
+test_dial_duration_seconds_sum{backend="10.0.0.1:25565"} 5.55
+test_dial_duration_seconds_count{backend="10.0.0.1:25565"} 3
+# HELP test_errors_total Errors.
+# TYPE test_errors_total counter
+`
+
+	if actual := string(registry.Bytes()); actual != expected {
+		t.Errorf("got:\n%s\nwant:\n%s", actual, expected)
+	}
+
+	errors.With().Inc()
+	recorder := httptest.NewRecorder()
+	registry.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	if contentType := recorder.Header().Get("Content-Type"); contentType != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Errorf("got: %s; want the Prometheus text format", contentType)
+	}
+
+	if body := recorder.Body.String(); body[len(body)-len("test_errors_total 1\n"):] != "test_errors_total 1\n" {
+		t.Errorf("got: %s; want a metric without labels", body)
+	}
+}
@@ -0,0 +1,107 @@
+package infrared
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/haveachin/infrared/protocol"
+	"github.com/haveachin/infrared/protocol/handshaking"
+)
+
+func TestNextStateLabel(t *testing.T) {
+	tt := []struct {
+		nextState protocol.Byte
+		label     string
+	}{
+		{nextState: 1, label: "status"},
+		{nextState: 2, label: "login"},
+		{nextState: 3, label: "transfer"},
+		{nextState: 4, label: "unknown"},
+	}
+
+	for _, tc := range tt {
+		hs := handshaking.ServerBoundHandshake{NextState: tc.nextState}
+		if label := nextStateLabel(hs); label != tc.label {
+			t.Errorf("got: %v, want: %v", label, tc.label)
+		}
+	}
+}
+
+func TestProxy_PlayersOnlineMetric(t *testing.T) {
+	proxy := &Proxy{Config: &ProxyConfig{
+		DomainNames: StringList{"metrics.example.com"},
+		ListenTo:    StringList{":25565"},
+	}}
+	gauge := metricPlayersOnline.With(proxy.UID())
+
+	conn := &conn{}
+	proxy.addPlayer(conn, &net.TCPAddr{}, "Steve", "localhost:25566")
+	if gauge.Value() != 1 {
+		t.Errorf("got: %v, want: %v", gauge.Value(), 1)
+	}
+
+	proxy.removePlayer(conn)
+	proxy.removePlayer(conn)
+	if gauge.Value() != 0 {
+		t.Errorf("got: %v, want: %v", gauge.Value(), 0)
+	}
+
+	want := []byte(`infrared_players_online{proxy_uid="metrics.example.com@:25565"} 0`)
+	if !bytes.Contains(Metrics.Bytes(), want) {
+		t.Errorf("metrics do not contain %s", want)
+	}
+}
+
+func TestProxy_PlayersOnlineMetric_DomainPattern(t *testing.T) {
+	proxy := &Proxy{Config: &ProxyConfig{
+		DomainName: "*.metrics.example.com",
+		ListenTo:   StringList{":25565"},
+	}}
+	gauge := metricPlayersOnline.With(proxy.UID())
+
+	for _, domain := range []string{"a.metrics.example.com", "b.metrics.example.com"} {
+		domainProxy, err := proxy.domainProxy(domain)
+		if err != nil {
+			t.Fatal(err)
+		}
+		domainProxy.addPlayer(&conn{}, &net.TCPAddr{}, "Steve", "localhost:25566")
+	}
+
+	if gauge.Value() != 2 {
+		t.Errorf("got: %v, want: %v", gauge.Value(), 2)
+	}
+
+	unwanted := []byte(`proxy_uid="a.metrics.example.com@:25565"`)
+	if bytes.Contains(Metrics.Bytes(), unwanted) {
+		t.Errorf("metrics contain %s", unwanted)
+	}
+}
+
+func TestProxy_DialMetric_DomainPattern(t *testing.T) {
+	proxy := &Proxy{Config: &ProxyConfig{
+		DomainName: "*.dial.example.com",
+		ListenTo:   StringList{":25565"},
+		ProxyTo:    StringList{"{1}.invalid:25565"},
+		Timeout:    100,
+	}}
+
+	domainProxy, err := proxy.domainProxy("alice.dial.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := domainProxy.dialBackend(""); err == nil {
+		t.Fatal("got: nil, want: a dial error")
+	}
+
+	want := []byte(`infrared_backend_dial_failures_total{backend="{1}.invalid:25565"} 1`)
+	if !bytes.Contains(Metrics.Bytes(), want) {
+		t.Errorf("metrics do not contain %s", want)
+	}
+
+	unwanted := []byte(`backend="alice.invalid:25565"`)
+	if bytes.Contains(Metrics.Bytes(), unwanted) {
+		t.Errorf("metrics contain %s", unwanted)
+	}
+}
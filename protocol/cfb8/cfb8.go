@@ -0,0 +1,64 @@
+// Package cfb8 implements the 8-bit cipher feedback mode that Minecraft
+// uses to encrypt the connection after a successful online-mode login.
+package cfb8
+
+import (
+	"crypto/cipher"
+)
+
+type cfb8 struct {
+	block   cipher.Block
+	iv      []byte
+	tmp     []byte
+	decrypt bool
+}
+
+// NewEncrypter returns a Stream which encrypts with 8-bit cipher feedback mode
+// using the given Block. The iv must be the same length as the Block's block size.
+func NewEncrypter(block cipher.Block, iv []byte) cipher.Stream {
+	return newCFB8(block, iv, false)
+}
+
+// NewDecrypter returns a Stream which decrypts with 8-bit cipher feedback mode
+// using the given Block. The iv must be the same length as the Block's block size.
+func NewDecrypter(block cipher.Block, iv []byte) cipher.Stream {
+	return newCFB8(block, iv, true)
+}
+
+func newCFB8(block cipher.Block, iv []byte, decrypt bool) cipher.Stream {
+	blockSize := block.BlockSize()
+	if len(iv) != blockSize {
+		panic("cfb8: IV length must equal block size")
+	}
+
+	x := &cfb8{
+		block:   block,
+		iv:      make([]byte, blockSize),
+		tmp:     make([]byte, blockSize),
+		decrypt: decrypt,
+	}
+	copy(x.iv, iv)
+
+	return x
+}
+
+func (x *cfb8) XORKeyStream(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic("cfb8: output smaller than input")
+	}
+
+	for i := range src {
+		x.block.Encrypt(x.tmp, x.iv)
+		in := src[i]
+		out := in ^ x.tmp[0]
+		dst[i] = out
+
+		// Shift the register by one byte and feed back the cipher text
+		copy(x.iv, x.iv[1:])
+		if x.decrypt {
+			x.iv[len(x.iv)-1] = in
+		} else {
+			x.iv[len(x.iv)-1] = out
+		}
+	}
+}
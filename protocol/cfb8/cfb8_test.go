@@ -0,0 +1,61 @@
+package cfb8
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"testing"
+)
+
+// Test vectors from NIST SP 800-38A F.3.7 and F.3.8 (CFB8-AES128)
+var (
+	testKey        = "2b7e151628aed2a6abf7158809cf4f3c"
+	testIV         = "000102030405060708090a0b0c0d0e0f"
+	testPlaintext  = "6bc1bee22e409f96e93d7e117393172aae2d"
+	testCiphertext = "3b79424c9c0dd436bace9e0ed4586a4f32b9"
+)
+
+func decodeHex(t *testing.T, s string) []byte {
+	bb, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bb
+}
+
+func TestNewEncrypter(t *testing.T) {
+	block, err := aes.NewCipher(decodeHex(t, testKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext := decodeHex(t, testPlaintext)
+	expected := decodeHex(t, testCiphertext)
+	actual := make([]byte, len(plaintext))
+
+	NewEncrypter(block, decodeHex(t, testIV)).XORKeyStream(actual, plaintext)
+
+	if !bytes.Equal(actual, expected) {
+		t.Errorf("got: %x; want: %x", actual, expected)
+	}
+}
+
+func TestNewDecrypter(t *testing.T) {
+	block, err := aes.NewCipher(decodeHex(t, testKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ciphertext := decodeHex(t, testCiphertext)
+	expected := decodeHex(t, testPlaintext)
+	actual := make([]byte, len(ciphertext))
+
+	stream := NewDecrypter(block, decodeHex(t, testIV))
+	// Decrypt in two steps to make sure that the stream keeps its state
+	stream.XORKeyStream(actual[:5], ciphertext[:5])
+	stream.XORKeyStream(actual[5:], ciphertext[5:])
+
+	if !bytes.Equal(actual, expected) {
+		t.Errorf("got: %x; want: %x", actual, expected)
+	}
+}
@@ -0,0 +1,299 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+const (
+	// LegacyFormattingCode prefixes the formatting codes of legacy text
+	LegacyFormattingCode = '§'
+	// LegacyAlternateFormattingCode can be used instead of LegacyFormattingCode in configs
+	LegacyAlternateFormattingCode = '&'
+)
+
+var legacyColors = map[rune]string{
+	'0': "black",
+	'1': "dark_blue",
+	'2': "dark_green",
+	'3': "dark_aqua",
+	'4': "dark_red",
+	'5': "dark_purple",
+	'6': "gold",
+	'7': "gray",
+	'8': "dark_gray",
+	'9': "blue",
+	'a': "green",
+	'b': "aqua",
+	'c': "red",
+	'd': "light_purple",
+	'e': "yellow",
+	'f': "white",
+}
+
+// ChatComponent is the JSON text format that Minecraft uses for
+// chat messages, disconnect reasons and server descriptions
+type ChatComponent struct {
+	Text          string          `json:"text,omitempty"`
+	Translate     string          `json:"translate,omitempty"`
+	With          []ChatComponent `json:"with,omitempty"`
+	Color         string          `json:"color,omitempty"`
+	Bold          *bool           `json:"bold,omitempty"`
+	Italic        *bool           `json:"italic,omitempty"`
+	Underlined    *bool           `json:"underlined,omitempty"`
+	Strikethrough *bool           `json:"strikethrough,omitempty"`
+	Obfuscated    *bool           `json:"obfuscated,omitempty"`
+	Extra         []ChatComponent `json:"extra,omitempty"`
+}
+
+type chatComponentJSON ChatComponent
+
+// MarshalJSON always encodes the text field of components that are not translated,
+// because clients reject components without any content
+func (c ChatComponent) MarshalJSON() ([]byte, error) {
+	if c.Translate != "" || c.Text != "" {
+		return marshalChatJSON(chatComponentJSON(c))
+	}
+
+	return marshalChatJSON(struct {
+		Text string `json:"text"`
+		chatComponentJSON
+	}{
+		chatComponentJSON: chatComponentJSON(c),
+	})
+}
+
+// marshalChatJSON encodes v without escaping HTML characters like '&',
+// which are quite common in chat messages
+func marshalChatJSON(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// UnmarshalJSON decodes a component from an object, a plain string or
+// an array whose first element is the parent of all following elements
+func (c *ChatComponent) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil
+	}
+
+	switch data[0] {
+	case '"':
+		*c = ChatComponent{}
+		return json.Unmarshal(data, &c.Text)
+	case '[':
+		var components []ChatComponent
+		if err := json.Unmarshal(data, &components); err != nil {
+			return err
+		}
+
+		*c = ChatComponent{}
+		if len(components) == 0 {
+			return nil
+		}
+
+		*c = components[0]
+		c.Extra = append(c.Extra, components[1:]...)
+		return nil
+	}
+
+	var component chatComponentJSON
+	if err := json.Unmarshal(data, &component); err != nil {
+		return err
+	}
+
+	*c = ChatComponent(component)
+	return nil
+}
+
+// String returns the JSON representation of the component
+func (c ChatComponent) String() string {
+	bb, err := marshalChatJSON(c)
+	if err != nil {
+		return ""
+	}
+	return string(bb)
+}
+
+// Chat encodes the component as a Chat field
+func (c ChatComponent) Chat() Chat {
+	return Chat(c.String())
+}
+
+// MapText returns a copy of the component where fn was applied
+// to the text of the component and all of its children
+func (c ChatComponent) MapText(fn func(string) string) ChatComponent {
+	c.Text = fn(c.Text)
+
+	if c.With != nil {
+		with := make([]ChatComponent, len(c.With))
+		for i, child := range c.With {
+			with[i] = child.MapText(fn)
+		}
+		c.With = with
+	}
+
+	if c.Extra != nil {
+		extra := make([]ChatComponent, len(c.Extra))
+		for i, child := range c.Extra {
+			extra[i] = child.MapText(fn)
+		}
+		c.Extra = extra
+	}
+
+	return c
+}
+
+// PlainText returns the text of the component and all of its children without formatting.
+// Translated components are represented by their translation key.
+func (c ChatComponent) PlainText() string {
+	var sb strings.Builder
+	c.writeText(&sb, false, "")
+	return sb.String()
+}
+
+// LegacyText returns the text of the component and all of
+// its children formatted with legacy formatting codes
+func (c ChatComponent) LegacyText() string {
+	var sb strings.Builder
+	c.writeText(&sb, true, "")
+	return sb.String()
+}
+
+func (c ChatComponent) writeText(sb *strings.Builder, legacy bool, inheritedCodes string) {
+	codes := inheritedCodes
+	if legacy {
+		codes = c.legacyCodes(inheritedCodes)
+		sb.WriteString(codes)
+	}
+
+	if c.Text != "" {
+		sb.WriteString(c.Text)
+	} else {
+		sb.WriteString(c.Translate)
+	}
+
+	for i, child := range c.Extra {
+		if legacy && i > 0 && c.Extra[i-1].legacyCodes(codes) != codes {
+			// Reset the formatting of the previous sibling
+			sb.WriteString(string(LegacyFormattingCode) + "r" + codes)
+		}
+		child.writeText(sb, legacy, codes)
+	}
+}
+
+func (c ChatComponent) legacyCodes(inheritedCodes string) string {
+	codes := inheritedCodes
+	for code, color := range legacyColors {
+		if c.Color == color {
+			// A color resets all previous formatting
+			codes = string([]rune{LegacyFormattingCode, code})
+		}
+	}
+
+	styles := []struct {
+		enabled *bool
+		code    rune
+	}{
+		{c.Obfuscated, 'k'},
+		{c.Bold, 'l'},
+		{c.Strikethrough, 'm'},
+		{c.Underlined, 'n'},
+		{c.Italic, 'o'},
+	}
+
+	for _, style := range styles {
+		if style.enabled != nil && *style.enabled {
+			codes += string([]rune{LegacyFormattingCode, style.code})
+		}
+	}
+
+	return codes
+}
+
+// ParseLegacyText converts text with legacy formatting codes into a component.
+// Both '§' and '&' can be used to prefix a formatting code.
+func ParseLegacyText(text string) ChatComponent {
+	var components []ChatComponent
+	var current ChatComponent
+	var sb strings.Builder
+
+	flush := func() {
+		if sb.Len() == 0 {
+			return
+		}
+		current.Text = sb.String()
+		components = append(components, current)
+		sb.Reset()
+	}
+
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if (r != LegacyFormattingCode && r != LegacyAlternateFormattingCode) || i+1 >= len(runes) {
+			sb.WriteRune(r)
+			continue
+		}
+
+		code := []rune(strings.ToLower(string(runes[i+1])))[0]
+		style := current
+		enabled := true
+
+		if color, ok := legacyColors[code]; ok {
+			style = ChatComponent{Color: color}
+		} else {
+			switch code {
+			case 'k':
+				style.Obfuscated = &enabled
+			case 'l':
+				style.Bold = &enabled
+			case 'm':
+				style.Strikethrough = &enabled
+			case 'n':
+				style.Underlined = &enabled
+			case 'o':
+				style.Italic = &enabled
+			case 'r':
+				style = ChatComponent{}
+			default:
+				sb.WriteRune(r)
+				continue
+			}
+		}
+
+		flush()
+		current = style
+		i++
+	}
+	flush()
+
+	switch len(components) {
+	case 0:
+		return ChatComponent{}
+	case 1:
+		return components[0]
+	}
+
+	return ChatComponent{Extra: components}
+}
+
+// ParseChatComponent parses a component from its JSON representation.
+// Text that is not valid JSON is parsed as legacy formatted text.
+func ParseChatComponent(text string) ChatComponent {
+	trimmed := strings.TrimSpace(text)
+	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		var component ChatComponent
+		if err := json.Unmarshal([]byte(trimmed), &component); err == nil {
+			return component
+		}
+	}
+
+	return ParseLegacyText(text)
+}
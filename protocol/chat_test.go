@@ -0,0 +1,167 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParseLegacyText(t *testing.T) {
+	tt := []struct {
+		text     string
+		expected string
+	}{
+		{
+			text:     "",
+			expected: `{"text":""}`,
+		},
+		{
+			text:     "Powered by Infrared",
+			expected: `{"text":"Powered by Infrared"}`,
+		},
+		{
+			text:     "Say \"hi\"",
+			expected: `{"text":"Say \"hi\""}`,
+		},
+		{
+			text:     "§cOffline",
+			expected: `{"text":"Offline","color":"red"}`,
+		},
+		{
+			text:     "&6Gold &lbold&r plain",
+			expected: `{"text":"","extra":[{"text":"Gold ","color":"gold"},{"text":"bold","color":"gold","bold":true},{"text":" plain"}]}`,
+		},
+		{
+			text:     "Fish & Chips &z",
+			expected: `{"text":"Fish & Chips &z"}`,
+		},
+	}
+
+	for _, tc := range tt {
+		if actual := ParseLegacyText(tc.text).String(); actual != tc.expected {
+			t.Errorf("%q: got: %s; want: %s", tc.text, actual, tc.expected)
+		}
+	}
+}
+
+func TestParseChatComponent(t *testing.T) {
+	tt := []struct {
+		text     string
+		expected string
+	}{
+		{
+			text:     `{"text":"Hello","color":"aqua","extra":[{"text":" World"}]}`,
+			expected: `{"text":"Hello","color":"aqua","extra":[{"text":" World"}]}`,
+		},
+		{
+			text:     `["Hello",{"text":" World","italic":true}]`,
+			expected: `{"text":"Hello","extra":[{"text":" World","italic":true}]}`,
+		},
+		{
+			text:     `{"translate":"multiplayer.disconnect.unverified_username"}`,
+			expected: `{"translate":"multiplayer.disconnect.unverified_username"}`,
+		},
+		{
+			text:     `{not json}`,
+			expected: `{"text":"{not json}"}`,
+		},
+	}
+
+	for _, tc := range tt {
+		if actual := ParseChatComponent(tc.text).String(); actual != tc.expected {
+			t.Errorf("%q: got: %s; want: %s", tc.text, actual, tc.expected)
+		}
+	}
+}
+
+func TestChatComponent_UnmarshalJSON(t *testing.T) {
+	var component ChatComponent
+	if err := json.Unmarshal([]byte(`"plain"`), &component); err != nil {
+		t.Fatal(err)
+	}
+
+	if component.Text != "plain" {
+		t.Errorf("got: %s; want: plain", component.Text)
+	}
+}
+
+func TestChatComponent_MapText(t *testing.T) {
+	component := ParseLegacyText("&cHello &l{{username}}")
+	mapped := component.MapText(func(text string) string {
+		return strings.Replace(text, "{{username}}", "Notch", -1)
+	})
+
+	if actual := mapped.PlainText(); actual != "Hello Notch" {
+		t.Errorf("got: %s; want: Hello Notch", actual)
+	}
+
+	if actual := component.PlainText(); actual != "Hello {{username}}" {
+		t.Errorf("original was modified: %s", actual)
+	}
+}
+
+func TestChatComponent_LegacyText(t *testing.T) {
+	tt := []struct {
+		text     string
+		expected string
+	}{
+		{
+			text:     "Powered by Infrared",
+			expected: "Powered by Infrared",
+		},
+		{
+			text:     "&6Gold &lbold&r plain",
+			expected: "§6Gold §r§6§lbold§r plain",
+		},
+	}
+
+	for _, tc := range tt {
+		if actual := ParseLegacyText(tc.text).LegacyText(); actual != tc.expected {
+			t.Errorf("%q: got: %q; want: %q", tc.text, actual, tc.expected)
+		}
+	}
+}
+
+func TestChatComponent_NBT(t *testing.T) {
+	bold := true
+	tt := []struct {
+		component ChatComponent
+		expected  []byte
+	}{
+		{
+			component: ChatComponent{Text: "Hi"},
+			expected: []byte{
+				0x0a,
+				0x08, 0x00, 0x04, 't', 'e', 'x', 't', 0x00, 0x02, 'H', 'i',
+				0x00,
+			},
+		},
+		{
+			component: ChatComponent{Text: "§", Bold: &bold},
+			expected: []byte{
+				0x0a,
+				0x08, 0x00, 0x04, 't', 'e', 'x', 't', 0x00, 0x02, 0xc2, 0xa7,
+				0x01, 0x00, 0x04, 'b', 'o', 'l', 'd', 0x01,
+				0x00,
+			},
+		},
+		{
+			component: ChatComponent{Extra: []ChatComponent{{Text: "a"}}},
+			expected: []byte{
+				0x0a,
+				0x08, 0x00, 0x04, 't', 'e', 'x', 't', 0x00, 0x00,
+				0x09, 0x00, 0x05, 'e', 'x', 't', 'r', 'a', 0x0a, 0x00, 0x00, 0x00, 0x01,
+				0x08, 0x00, 0x04, 't', 'e', 'x', 't', 0x00, 0x01, 'a',
+				0x00,
+				0x00,
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		if actual := tc.component.NBT(); !bytes.Equal(actual, tc.expected) {
+			t.Errorf("%v: got: %v; want: %v", tc.component, actual, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package protocol
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+)
+
+// MaxUncompressedPacketSize is the upper limit that the vanilla server
+// accepts for the uncompressed data of a compressed packet
+const MaxUncompressedPacketSize = 8388608
+
+// MarshalCompressed encodes the packet in the compressed packet format.
+// Packets that are smaller than the threshold are sent uncompressed with a data length of zero.
+// A negative threshold disables compression and falls back to Marshal.
+func (pk *Packet) MarshalCompressed(threshold int) ([]byte, error) {
+	if threshold < 0 {
+		return pk.Marshal()
+	}
+
+	data := append(pk.ID.Encode(), pk.Data...)
+
+	var body []byte
+	if len(data) < threshold {
+		body = append(VarInt(0).Encode(), data...)
+	} else {
+		var buf bytes.Buffer
+		zw := zlib.NewWriter(&buf)
+		if _, err := zw.Write(data); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		body = append(VarInt(int32(len(data))).Encode(), buf.Bytes()...)
+	}
+
+	return append(VarInt(int32(len(body))).Encode(), body...), nil
+}
+
+// ReadCompressedPacketBytes decodes a byte stream in the compressed packet format
+// and cuts out the decompressed ID and data of the first Packet
+func ReadCompressedPacketBytes(r DecodeReader, threshold int) ([]byte, error) {
+	packetBytes, err := ReadPacketBytes(r)
+	if err != nil {
+		return nil, err
+	}
+
+	br := bytes.NewReader(packetBytes)
+	var dataLength VarInt
+	if err := dataLength.Decode(br); err != nil {
+		return nil, err
+	}
+
+	if dataLength == 0 {
+		data := packetBytes[len(packetBytes)-br.Len():]
+		if len(data) < 1 {
+			return nil, fmt.Errorf("packet length too short")
+		}
+		return data, nil
+	}
+
+	if dataLength < 0 || dataLength > MaxUncompressedPacketSize {
+		return nil, fmt.Errorf("data length %d is out of bounds", dataLength)
+	}
+
+	if int(dataLength) < threshold {
+		return nil, fmt.Errorf("data length %d is below the compression threshold %d", dataLength, threshold)
+	}
+
+	zr, err := zlib.NewReader(br)
+	if err != nil {
+		return nil, fmt.Errorf("reading the compressed content of the packet failed: %v", err)
+	}
+	defer zr.Close()
+
+	data := make([]byte, dataLength)
+	if _, err := io.ReadFull(zr, data); err != nil {
+		return nil, fmt.Errorf("decompressing the content of the packet failed: %v", err)
+	}
+
+	return data, nil
+}
+
+// ReadCompressedPacket decodes and decompresses a byte stream in the compressed
+// packet format and cuts the first Packet out
+func ReadCompressedPacket(r DecodeReader, threshold int) (Packet, error) {
+	data, err := ReadCompressedPacketBytes(r, threshold)
+	if err != nil {
+		return Packet{}, err
+	}
+
+	return unmarshalPacketBytes(data)
+}
+
+// PeekCompressedPacket decodes and decompresses a byte stream in the compressed
+// packet format and peeks the first Packet
+func PeekCompressedPacket(p PeekReader, threshold int) (Packet, error) {
+	r := bytePeeker{
+		PeekReader: p,
+		cursor:     0,
+	}
+
+	return ReadCompressedPacket(&r, threshold)
+}
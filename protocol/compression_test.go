@@ -0,0 +1,146 @@
+package protocol
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+)
+
+func TestPacket_MarshalCompressed(t *testing.T) {
+	tt := []struct {
+		packet    Packet
+		threshold int
+		expected  []byte
+	}{
+		{
+			packet: Packet{
+				ID:   0x00,
+				Data: []byte{0x00, 0xf2},
+			},
+			threshold: -1,
+			expected:  []byte{0x03, 0x00, 0x00, 0xf2},
+		},
+		{
+			packet: Packet{
+				ID:   0x0f,
+				Data: []byte{0x00, 0xf2, 0x03, 0x50},
+			},
+			threshold: 256,
+			expected:  []byte{0x06, 0x00, 0x0f, 0x00, 0xf2, 0x03, 0x50},
+		},
+	}
+
+	for _, tc := range tt {
+		actual, err := tc.packet.MarshalCompressed(tc.threshold)
+		if err != nil {
+			t.Error(err)
+		}
+
+		if !bytes.Equal(actual, tc.expected) {
+			t.Errorf("got: %v; want: %v", actual, tc.expected)
+		}
+	}
+}
+
+func TestReadCompressedPacket(t *testing.T) {
+	tt := []struct {
+		packet    Packet
+		threshold int
+	}{
+		{
+			packet: Packet{
+				ID:   0x00,
+				Data: []byte{0x00, 0xf2},
+			},
+			threshold: 256,
+		},
+		{
+			packet: Packet{
+				ID:   0x0f,
+				Data: bytes.Repeat([]byte{0x00, 0xf2, 0x03, 0x50}, 128),
+			},
+			threshold: 256,
+		},
+		{
+			packet: Packet{
+				ID:   0x02,
+				Data: []byte{0x01},
+			},
+			threshold: 0,
+		},
+	}
+
+	for _, tc := range tt {
+		bb, err := tc.packet.MarshalCompressed(tc.threshold)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		trailingData := []byte{0x30, 0x01, 0xef, 0xaa}
+		buf := bytes.NewBuffer(append(bb, trailingData...))
+		pk, err := ReadCompressedPacket(buf, tc.threshold)
+		if err != nil {
+			t.Error(err)
+		}
+
+		if pk.ID != tc.packet.ID {
+			t.Errorf("packet ID: got: %v; want: %v", pk.ID, tc.packet.ID)
+		}
+
+		if !bytes.Equal(pk.Data, tc.packet.Data) {
+			t.Errorf("packet data: got: %v; want: %v", pk.Data, tc.packet.Data)
+		}
+
+		if !bytes.Equal(buf.Bytes(), trailingData) {
+			t.Errorf("data after read: got: %v; want: %v", buf.Bytes(), trailingData)
+		}
+	}
+}
+
+func TestReadCompressedPacket_BelowThreshold(t *testing.T) {
+	pk := Packet{
+		ID:   0x0f,
+		Data: []byte{0x00, 0xf2, 0x03, 0x50},
+	}
+
+	bb, err := pk.MarshalCompressed(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ReadCompressedPacket(bytes.NewBuffer(bb), 256); err == nil {
+		t.Error("expected an error for a compressed packet below the threshold")
+	}
+}
+
+func TestPeekCompressedPacket(t *testing.T) {
+	packet := Packet{
+		ID:   0x0f,
+		Data: bytes.Repeat([]byte{0x00, 0xf2, 0x03, 0x50}, 128),
+	}
+
+	data, err := packet.MarshalCompressed(64)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dataCopy := make([]byte, len(data))
+	copy(dataCopy, data)
+
+	pk, err := PeekCompressedPacket(bufio.NewReader(bytes.NewReader(dataCopy)), 64)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if pk.ID != packet.ID {
+		t.Errorf("packet ID: got: %v; want: %v", pk.ID, packet.ID)
+	}
+
+	if !bytes.Equal(pk.Data, packet.Data) {
+		t.Errorf("packet data: got: %v; want: %v", pk.Data, packet.Data)
+	}
+
+	if !bytes.Equal(dataCopy, data) {
+		t.Errorf("data after read: got: %v; want: %v", dataCopy, data)
+	}
+}
@@ -0,0 +1,25 @@
+package configuration
+
+import (
+	"github.com/haveachin/infrared/protocol"
+)
+
+type ClientBoundDisconnect struct {
+	Reason protocol.ChatComponent
+}
+
+func (pk ClientBoundDisconnect) Marshal(protocolVersion protocol.VarInt) (protocol.Packet, error) {
+	var reason protocol.FieldEncoder = pk.Reason.Chat()
+	// 1.20.3 and newer clients expect the reason as NBT
+	if protocolVersion >= protocol.Version1_20_3 {
+		reason = protocol.OptionalByteArray(pk.Reason.NBT())
+	}
+
+	return protocol.DefaultRegistry.MarshalPacket(
+		protocolVersion,
+		protocol.StateConfiguration,
+		protocol.ClientBound,
+		protocol.PacketDisconnect,
+		reason,
+	)
+}
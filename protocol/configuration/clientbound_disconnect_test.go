@@ -0,0 +1,50 @@
+package configuration
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/haveachin/infrared/protocol"
+)
+
+func TestClientBoundDisconnect_Marshal(t *testing.T) {
+	reason := protocol.ChatComponent{Text: "Bye"}
+
+	tt := []struct {
+		protocolVersion protocol.VarInt
+		marshaledPacket protocol.Packet
+	}{
+		{
+			protocolVersion: protocol.Version1_20_2,
+			marshaledPacket: protocol.Packet{
+				ID:   0x01,
+				Data: protocol.String(`{"text":"Bye"}`).Encode(),
+			},
+		},
+		{
+			protocolVersion: protocol.Version1_20_3,
+			marshaledPacket: protocol.Packet{
+				ID:   0x01,
+				Data: reason.NBT(),
+			},
+		},
+		{
+			protocolVersion: protocol.Version1_20_5,
+			marshaledPacket: protocol.Packet{
+				ID:   0x02,
+				Data: reason.NBT(),
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		pk, err := ClientBoundDisconnect{Reason: reason}.Marshal(tc.protocolVersion)
+		if err != nil {
+			t.Error(err)
+		}
+
+		if pk.ID != tc.marshaledPacket.ID || !bytes.Equal(pk.Data, tc.marshaledPacket.Data) {
+			t.Errorf("%d: got: %v, want: %v", tc.protocolVersion, pk, tc.marshaledPacket)
+		}
+	}
+}
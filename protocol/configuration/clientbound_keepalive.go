@@ -0,0 +1,19 @@
+package configuration
+
+import (
+	"github.com/haveachin/infrared/protocol"
+)
+
+type ClientBoundKeepAlive struct {
+	KeepAliveID protocol.Long
+}
+
+func (pk ClientBoundKeepAlive) Marshal(protocolVersion protocol.VarInt) (protocol.Packet, error) {
+	return protocol.DefaultRegistry.MarshalPacket(
+		protocolVersion,
+		protocol.StateConfiguration,
+		protocol.ClientBound,
+		protocol.PacketKeepAlive,
+		pk.KeepAliveID,
+	)
+}
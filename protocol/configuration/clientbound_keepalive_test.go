@@ -0,0 +1,46 @@
+package configuration
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/haveachin/infrared/protocol"
+)
+
+func TestClientBoundKeepAlive_Marshal(t *testing.T) {
+	tt := []struct {
+		protocolVersion protocol.VarInt
+		marshaledPacket protocol.Packet
+		err             error
+	}{
+		{
+			protocolVersion: protocol.Version1_19_3,
+			err:             protocol.ErrUnsupportedPacket,
+		},
+		{
+			protocolVersion: protocol.Version1_20_2,
+			marshaledPacket: protocol.Packet{
+				ID:   0x03,
+				Data: []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x2a},
+			},
+		},
+		{
+			protocolVersion: protocol.Version1_20_5,
+			marshaledPacket: protocol.Packet{
+				ID:   0x04,
+				Data: []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x2a},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		pk, err := ClientBoundKeepAlive{KeepAliveID: 42}.Marshal(tc.protocolVersion)
+		if err != tc.err {
+			t.Errorf("%d: got: %v, want: %v", tc.protocolVersion, err, tc.err)
+		}
+
+		if pk.ID != tc.marshaledPacket.ID || !bytes.Equal(pk.Data, tc.marshaledPacket.Data) {
+			t.Errorf("%d: got: %v, want: %v", tc.protocolVersion, pk, tc.marshaledPacket)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package configuration
+
+import (
+	"github.com/haveachin/infrared/protocol"
+)
+
+// ClientBoundTransfer tells 1.20.5 and newer clients to connect to another server.
+// The client starts a new connection with a handshake that has the transfer state.
+type ClientBoundTransfer struct {
+	Host protocol.String
+	Port protocol.VarInt
+}
+
+func (pk ClientBoundTransfer) Marshal(protocolVersion protocol.VarInt) (protocol.Packet, error) {
+	return protocol.DefaultRegistry.MarshalPacket(
+		protocolVersion,
+		protocol.StateConfiguration,
+		protocol.ClientBound,
+		protocol.PacketTransfer,
+		pk.Host,
+		pk.Port,
+	)
+}
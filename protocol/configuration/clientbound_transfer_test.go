@@ -0,0 +1,27 @@
+package configuration
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/haveachin/infrared/protocol"
+)
+
+func TestClientBoundTransfer_Marshal(t *testing.T) {
+	pk, err := ClientBoundTransfer{
+		Host: "a.b",
+		Port: 25565,
+	}.Marshal(protocol.Version1_20_5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []byte{0x03, 0x61, 0x2e, 0x62, 0xdd, 0xc7, 0x01}
+	if pk.ID != 0x0B || !bytes.Equal(pk.Data, expected) {
+		t.Errorf("got: %v, want: %v", pk.Data, expected)
+	}
+
+	if _, err := (ClientBoundTransfer{}).Marshal(protocol.Version1_20_3); err != protocol.ErrUnsupportedPacket {
+		t.Errorf("got: %v, want: %v", err, protocol.ErrUnsupportedPacket)
+	}
+}
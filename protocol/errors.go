@@ -5,5 +5,6 @@ import (
 )
 
 var (
-	ErrInvalidPacketID = errors.New("invalid packet id")
+	ErrInvalidPacketID   = errors.New("invalid packet id")
+	ErrUnsupportedPacket = errors.New("packet is not supported by the protocol version")
 )
@@ -9,13 +9,17 @@ import (
 )
 
 const (
-	ServerBoundHandshakePacketID byte = 0x00
+	ServerBoundHandshakePacketID protocol.VarInt = 0x00
 
 	ServerBoundHandshakeStatusState = protocol.Byte(1)
 	ServerBoundHandshakeLoginState  = protocol.Byte(2)
+	// ServerBoundHandshakeTransferState is sent by 1.20.5 and newer clients
+	// that reconnect because a server transferred them
+	ServerBoundHandshakeTransferState = protocol.Byte(3)
 
-	ForgeSeparator  = "\x00"
-	RealIPSeparator = "///"
+	ForgeSeparator      = "\x00"
+	RealIPSeparator     = "///"
+	BungeeCordSeparator = "\x00"
 )
 
 type ServerBoundHandshake struct {
@@ -62,6 +66,10 @@ func (pk ServerBoundHandshake) IsLoginRequest() bool {
 	return pk.NextState == ServerBoundHandshakeLoginState
 }
 
+func (pk ServerBoundHandshake) IsTransferRequest() bool {
+	return pk.NextState == ServerBoundHandshakeTransferState
+}
+
 func (pk ServerBoundHandshake) IsForgeAddress() bool {
 	addr := string(pk.ServerAddress)
 	return len(strings.Split(addr, ForgeSeparator)) > 1
@@ -97,3 +105,22 @@ func (pk *ServerBoundHandshake) UpgradeToRealIP(clientAddr net.Addr, timestamp t
 
 	pk.ServerAddress = protocol.String(addr)
 }
+
+// UpgradeToBungeeCord rewrites the server address in BungeeCord's legacy IP forwarding format
+// "host\x00clientIP\x00uuid[\x00properties]". The uuid is expected without dashes and the
+// optional properties as a JSON array of the player's profile properties.
+func (pk *ServerBoundHandshake) UpgradeToBungeeCord(clientAddr net.Addr, uuid string, properties string) {
+	addr := strings.Split(string(pk.ServerAddress), BungeeCordSeparator)[0]
+
+	clientIP, _, err := net.SplitHostPort(clientAddr.String())
+	if err != nil {
+		clientIP = clientAddr.String()
+	}
+
+	segments := []string{addr, clientIP, uuid}
+	if properties != "" {
+		segments = append(segments, properties)
+	}
+
+	pk.ServerAddress = protocol.String(strings.Join(segments, BungeeCordSeparator))
+}
@@ -156,6 +156,32 @@ func TestServerBoundHandshake_IsLoginRequest(t *testing.T) {
 	}
 }
 
+func TestServerBoundHandshake_IsTransferRequest(t *testing.T) {
+	tt := []struct {
+		handshake ServerBoundHandshake
+		result    bool
+	}{
+		{
+			handshake: ServerBoundHandshake{
+				NextState: ServerBoundHandshakeLoginState,
+			},
+			result: false,
+		},
+		{
+			handshake: ServerBoundHandshake{
+				NextState: ServerBoundHandshakeTransferState,
+			},
+			result: true,
+		},
+	}
+
+	for _, tc := range tt {
+		if tc.handshake.IsTransferRequest() != tc.result {
+			t.Fail()
+		}
+	}
+}
+
 func TestServerBoundHandshake_IsForgeAddress(t *testing.T) {
 	tt := []struct {
 		addr   string
@@ -346,6 +372,49 @@ func TestServerBoundHandshake_UpgradeToRealIP(t *testing.T) {
 	}
 }
 
+func TestServerBoundHandshake_UpgradeToBungeeCord(t *testing.T) {
+	tt := []struct {
+		addr         string
+		clientAddr   net.TCPAddr
+		uuid         string
+		properties   string
+		expectedAddr string
+	}{
+		{
+			addr: "example.com",
+			clientAddr: net.TCPAddr{
+				IP:   net.IPv4(127, 0, 0, 1),
+				Port: 12345,
+			},
+			uuid:         "069a79f444e94726a5befca90e38aaf5",
+			expectedAddr: "example.com\x00127.0.0.1\x00069a79f444e94726a5befca90e38aaf5",
+		},
+		{
+			addr: "example.com" + ForgeSeparator + "FML" + ForgeSeparator,
+			clientAddr: net.TCPAddr{
+				IP:   net.IPv4(127, 0, 1, 1),
+				Port: 25565,
+			},
+			uuid:         "069a79f444e94726a5befca90e38aaf5",
+			properties:   `[{"name":"textures","value":"e30="}]`,
+			expectedAddr: "example.com\x00127.0.1.1\x00069a79f444e94726a5befca90e38aaf5\x00[{\"name\":\"textures\",\"value\":\"e30=\"}]",
+		},
+	}
+
+	for _, tc := range tt {
+		hs := ServerBoundHandshake{ServerAddress: protocol.String(tc.addr)}
+		hs.UpgradeToBungeeCord(&tc.clientAddr, tc.uuid, tc.properties)
+
+		if string(hs.ServerAddress) != tc.expectedAddr {
+			t.Errorf("got: %q; want: %q", hs.ServerAddress, tc.expectedAddr)
+		}
+
+		if hs.ParseServerAddress() != "example.com" {
+			t.Errorf("got: %v; want: %v", hs.ParseServerAddress(), "example.com")
+		}
+	}
+}
+
 func BenchmarkHandshakingServerBoundHandshake_Marshal(b *testing.B) {
 	isHandshakePk := ServerBoundHandshake{
 		ProtocolVersion: 578,
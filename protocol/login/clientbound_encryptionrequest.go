@@ -0,0 +1,58 @@
+package login
+
+import (
+	"github.com/haveachin/infrared/protocol"
+)
+
+const (
+	ClientBoundEncryptionRequestPacketID protocol.VarInt = 0x01
+
+	// encryptionRequestShouldAuthenticateVersion is the first protocol version (1.20.5)
+	// that sends the should authenticate field
+	encryptionRequestShouldAuthenticateVersion = 766
+)
+
+type ClientBoundEncryptionRequest struct {
+	ServerID           protocol.String
+	PublicKey          protocol.ByteArray
+	VerifyToken        protocol.ByteArray
+	ShouldAuthenticate protocol.Boolean
+}
+
+func (pk ClientBoundEncryptionRequest) Marshal(protocolVersion protocol.VarInt) protocol.Packet {
+	fields := []protocol.FieldEncoder{
+		pk.ServerID,
+		pk.PublicKey,
+		pk.VerifyToken,
+	}
+
+	if protocolVersion >= encryptionRequestShouldAuthenticateVersion {
+		fields = append(fields, pk.ShouldAuthenticate)
+	}
+
+	return protocol.MarshalPacket(ClientBoundEncryptionRequestPacketID, fields...)
+}
+
+func UnmarshalClientBoundEncryptionRequest(packet protocol.Packet, protocolVersion protocol.VarInt) (ClientBoundEncryptionRequest, error) {
+	var pk ClientBoundEncryptionRequest
+
+	if packet.ID != ClientBoundEncryptionRequestPacketID {
+		return pk, protocol.ErrInvalidPacketID
+	}
+
+	fields := []protocol.FieldDecoder{
+		&pk.ServerID,
+		&pk.PublicKey,
+		&pk.VerifyToken,
+	}
+
+	if protocolVersion >= encryptionRequestShouldAuthenticateVersion {
+		fields = append(fields, &pk.ShouldAuthenticate)
+	}
+
+	if err := packet.Scan(fields...); err != nil {
+		return pk, err
+	}
+
+	return pk, nil
+}
@@ -0,0 +1,101 @@
+package login
+
+import (
+	"bytes"
+	"github.com/haveachin/infrared/protocol"
+	"testing"
+)
+
+func TestClientBoundEncryptionRequest_Marshal(t *testing.T) {
+	tt := []struct {
+		packet          ClientBoundEncryptionRequest
+		protocolVersion protocol.VarInt
+		marshaledPacket protocol.Packet
+	}{
+		{
+			packet: ClientBoundEncryptionRequest{
+				ServerID:    protocol.String(""),
+				PublicKey:   protocol.ByteArray{0x30, 0x81},
+				VerifyToken: protocol.ByteArray{0xca, 0xfe, 0xba, 0xbe},
+			},
+			protocolVersion: 754,
+			marshaledPacket: protocol.Packet{
+				ID:   0x01,
+				Data: []byte{0x00, 0x02, 0x30, 0x81, 0x04, 0xca, 0xfe, 0xba, 0xbe},
+			},
+		},
+		{
+			packet: ClientBoundEncryptionRequest{
+				ServerID:           protocol.String(""),
+				PublicKey:          protocol.ByteArray{0x30, 0x81},
+				VerifyToken:        protocol.ByteArray{0xca, 0xfe, 0xba, 0xbe},
+				ShouldAuthenticate: true,
+			},
+			protocolVersion: 766,
+			marshaledPacket: protocol.Packet{
+				ID:   0x01,
+				Data: []byte{0x00, 0x02, 0x30, 0x81, 0x04, 0xca, 0xfe, 0xba, 0xbe, 0x01},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		pk := tc.packet.Marshal(tc.protocolVersion)
+
+		if pk.ID != ClientBoundEncryptionRequestPacketID {
+			t.Error("invalid packet id")
+		}
+
+		if !bytes.Equal(pk.Data, tc.marshaledPacket.Data) {
+			t.Errorf("got: %v, want: %v", pk.Data, tc.marshaledPacket.Data)
+		}
+	}
+}
+
+func TestUnmarshalClientBoundEncryptionRequest(t *testing.T) {
+	tt := []struct {
+		packet             protocol.Packet
+		protocolVersion    protocol.VarInt
+		unmarshalledPacket ClientBoundEncryptionRequest
+	}{
+		{
+			packet: protocol.Packet{
+				ID:   0x01,
+				Data: []byte{0x00, 0x02, 0x30, 0x81, 0x04, 0xca, 0xfe, 0xba, 0xbe},
+			},
+			protocolVersion: 754,
+			unmarshalledPacket: ClientBoundEncryptionRequest{
+				PublicKey:   protocol.ByteArray{0x30, 0x81},
+				VerifyToken: protocol.ByteArray{0xca, 0xfe, 0xba, 0xbe},
+			},
+		},
+		{
+			packet: protocol.Packet{
+				ID:   0x01,
+				Data: []byte{0x00, 0x02, 0x30, 0x81, 0x04, 0xca, 0xfe, 0xba, 0xbe, 0x01},
+			},
+			protocolVersion: 766,
+			unmarshalledPacket: ClientBoundEncryptionRequest{
+				PublicKey:          protocol.ByteArray{0x30, 0x81},
+				VerifyToken:        protocol.ByteArray{0xca, 0xfe, 0xba, 0xbe},
+				ShouldAuthenticate: true,
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		actual, err := UnmarshalClientBoundEncryptionRequest(tc.packet, tc.protocolVersion)
+		if err != nil {
+			t.Error(err)
+		}
+
+		expected := tc.unmarshalledPacket
+
+		if actual.ServerID != expected.ServerID ||
+			!bytes.Equal(actual.PublicKey, expected.PublicKey) ||
+			!bytes.Equal(actual.VerifyToken, expected.VerifyToken) ||
+			actual.ShouldAuthenticate != expected.ShouldAuthenticate {
+			t.Errorf("got: %v, want: %v", actual, expected)
+		}
+	}
+}
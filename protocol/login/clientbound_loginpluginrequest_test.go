@@ -0,0 +1,93 @@
+package login
+
+import (
+	"bytes"
+	"github.com/haveachin/infrared/protocol"
+	"testing"
+)
+
+func TestClientBoundLoginPluginRequest_Marshal(t *testing.T) {
+	tt := []struct {
+		packet          ClientBoundLoginPluginRequest
+		marshaledPacket protocol.Packet
+	}{
+		{
+			packet: ClientBoundLoginPluginRequest{
+				MessageID: 1,
+				Channel:   "a:b",
+			},
+			marshaledPacket: protocol.Packet{
+				ID:   0x04,
+				Data: []byte{0x01, 0x03, 0x61, 0x3a, 0x62},
+			},
+		},
+		{
+			packet: ClientBoundLoginPluginRequest{
+				MessageID: 2,
+				Channel:   "a:b",
+				Data:      protocol.OptionalByteArray{0x04},
+			},
+			marshaledPacket: protocol.Packet{
+				ID:   0x04,
+				Data: []byte{0x02, 0x03, 0x61, 0x3a, 0x62, 0x04},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		pk := tc.packet.Marshal()
+
+		if pk.ID != ClientBoundLoginPluginRequestPacketID {
+			t.Error("invalid packet id")
+		}
+
+		if !bytes.Equal(pk.Data, tc.marshaledPacket.Data) {
+			t.Errorf("got: %v, want: %v", pk.Data, tc.marshaledPacket.Data)
+		}
+	}
+}
+
+func TestUnmarshalClientBoundLoginPluginRequest(t *testing.T) {
+	tt := []struct {
+		packet             protocol.Packet
+		unmarshalledPacket ClientBoundLoginPluginRequest
+	}{
+		{
+			packet: protocol.Packet{
+				ID:   0x04,
+				Data: []byte{0x01, 0x03, 0x61, 0x3a, 0x62},
+			},
+			unmarshalledPacket: ClientBoundLoginPluginRequest{
+				MessageID: 1,
+				Channel:   "a:b",
+				Data:      protocol.OptionalByteArray{},
+			},
+		},
+		{
+			packet: protocol.Packet{
+				ID:   0x04,
+				Data: []byte{0x02, 0x03, 0x61, 0x3a, 0x62, 0x04},
+			},
+			unmarshalledPacket: ClientBoundLoginPluginRequest{
+				MessageID: 2,
+				Channel:   "a:b",
+				Data:      protocol.OptionalByteArray{0x04},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		actual, err := UnmarshalClientBoundLoginPluginRequest(tc.packet)
+		if err != nil {
+			t.Error(err)
+		}
+
+		expected := tc.unmarshalledPacket
+
+		if actual.MessageID != expected.MessageID ||
+			actual.Channel != expected.Channel ||
+			!bytes.Equal(actual.Data, expected.Data) {
+			t.Errorf("got: %v, want: %v", actual, expected)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package login
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/haveachin/infrared/protocol"
+)
+
+const ClientBoundLoginSuccessPacketID protocol.VarInt = 0x02
+
+type LoginSuccessProperty struct {
+	Name      protocol.String
+	Value     protocol.String
+	Signature protocol.String
+}
+
+type ClientBoundLoginSuccess struct {
+	UUID       protocol.UUID
+	Username   protocol.String
+	Properties []LoginSuccessProperty
+}
+
+func (pk ClientBoundLoginSuccess) Marshal(protocolVersion protocol.VarInt) protocol.Packet {
+	// Clients older than 1.16 expect the UUID as a string with hyphens
+	if protocolVersion < protocol.Version1_16 {
+		return protocol.MarshalPacket(
+			ClientBoundLoginSuccessPacketID,
+			protocol.String(uuid.UUID(pk.UUID).String()),
+			pk.Username,
+		)
+	}
+
+	fields := []protocol.FieldEncoder{pk.UUID, pk.Username}
+
+	if protocolVersion >= protocol.Version1_19 {
+		fields = append(fields, protocol.VarInt(len(pk.Properties)))
+		for _, property := range pk.Properties {
+			hasSignature := protocol.Boolean(property.Signature != "")
+			fields = append(fields, property.Name, property.Value, hasSignature)
+			if hasSignature {
+				fields = append(fields, property.Signature)
+			}
+		}
+	}
+
+	// Only 1.20.5 and 1.21 clients expect the strict error handling flag
+	if protocolVersion >= protocol.Version1_20_5 && protocolVersion < protocol.Version1_21_2 {
+		fields = append(fields, protocol.Boolean(false))
+	}
+
+	return protocol.MarshalPacket(ClientBoundLoginSuccessPacketID, fields...)
+}
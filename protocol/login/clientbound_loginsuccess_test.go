@@ -0,0 +1,60 @@
+package login
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/haveachin/infrared/protocol"
+)
+
+func TestClientBoundLoginSuccess_Marshal(t *testing.T) {
+	id := uuid.Must(uuid.FromString("069a79f4-44e9-4726-a5be-fca90e38aaf5"))
+	packet := ClientBoundLoginSuccess{
+		UUID:     protocol.UUID(id),
+		Username: "Notch",
+	}
+
+	uuidAndName := func(suffix ...byte) []byte {
+		data := append(id.Bytes(), protocol.String("Notch").Encode()...)
+		return append(data, suffix...)
+	}
+
+	tt := []struct {
+		protocolVersion protocol.VarInt
+		data            []byte
+	}{
+		{
+			protocolVersion: protocol.Version1_8,
+			data:            append(protocol.String(id.String()).Encode(), protocol.String("Notch").Encode()...),
+		},
+		{
+			protocolVersion: protocol.Version1_16,
+			data:            uuidAndName(),
+		},
+		{
+			protocolVersion: protocol.Version1_19,
+			data:            uuidAndName(0x00),
+		},
+		{
+			protocolVersion: protocol.Version1_20_5,
+			data:            uuidAndName(0x00, 0x00),
+		},
+		{
+			protocolVersion: protocol.Version1_21_2,
+			data:            uuidAndName(0x00),
+		},
+	}
+
+	for _, tc := range tt {
+		pk := packet.Marshal(tc.protocolVersion)
+
+		if pk.ID != ClientBoundLoginSuccessPacketID {
+			t.Error("invalid packet id")
+		}
+
+		if !bytes.Equal(pk.Data, tc.data) {
+			t.Errorf("%d: got: %v, want: %v", tc.protocolVersion, pk.Data, tc.data)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package login
+
+import (
+	"bytes"
+	"github.com/haveachin/infrared/protocol"
+	"testing"
+)
+
+func TestClientBoundSetCompression_Marshal(t *testing.T) {
+	tt := []struct {
+		packet          ClientBoundSetCompression
+		marshaledPacket protocol.Packet
+	}{
+		{
+			packet: ClientBoundSetCompression{
+				Threshold: 256,
+			},
+			marshaledPacket: protocol.Packet{
+				ID:   0x03,
+				Data: []byte{0x80, 0x02},
+			},
+		},
+		{
+			packet: ClientBoundSetCompression{
+				Threshold: -1,
+			},
+			marshaledPacket: protocol.Packet{
+				ID:   0x03,
+				Data: []byte{0xff, 0xff, 0xff, 0xff, 0x0f},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		pk := tc.packet.Marshal()
+
+		if pk.ID != ClientBoundSetCompressionPacketID {
+			t.Error("invalid packet id")
+		}
+
+		if !bytes.Equal(pk.Data, tc.marshaledPacket.Data) {
+			t.Errorf("got: %v, want: %v", pk.Data, tc.marshaledPacket.Data)
+		}
+	}
+}
+
+func TestUnmarshalClientBoundSetCompression(t *testing.T) {
+	tt := []struct {
+		packet             protocol.Packet
+		unmarshalledPacket ClientBoundSetCompression
+	}{
+		{
+			packet: protocol.Packet{
+				ID:   0x03,
+				Data: []byte{0x80, 0x02},
+			},
+			unmarshalledPacket: ClientBoundSetCompression{
+				Threshold: 256,
+			},
+		},
+		{
+			packet: protocol.Packet{
+				ID:   0x03,
+				Data: []byte{0x00},
+			},
+			unmarshalledPacket: ClientBoundSetCompression{
+				Threshold: 0,
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		actual, err := UnmarshalClientBoundSetCompression(tc.packet)
+		if err != nil {
+			t.Error(err)
+		}
+
+		if actual.Threshold != tc.unmarshalledPacket.Threshold {
+			t.Errorf("got: %v, want: %v", actual.Threshold, tc.unmarshalledPacket.Threshold)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package login
+
+import (
+	"bytes"
+
+	"github.com/haveachin/infrared/protocol"
+)
+
+const (
+	ServerBoundEncryptionResponsePacketID protocol.VarInt = 0x01
+
+	// Protocol versions 1.19 up to 1.19.2 allow the client to sign a salt
+	// with its chat key instead of sending back the verify token
+	encryptionResponseSignatureMinVersion = 759
+	encryptionResponseSignatureMaxVersion = 760
+)
+
+type ServerBoundEncryptionResponse struct {
+	SharedSecret protocol.ByteArray
+	VerifyToken  protocol.ByteArray
+
+	// Salt and MessageSignature are only set by 1.19 up to 1.19.2 clients
+	// that signed the verify token instead of sending it back
+	Salt             protocol.Long
+	MessageSignature protocol.ByteArray
+}
+
+// HasVerifyToken reports whether the client sent back the verify token
+func (pk ServerBoundEncryptionResponse) HasVerifyToken() bool {
+	return pk.MessageSignature == nil
+}
+
+func (pk ServerBoundEncryptionResponse) Marshal(protocolVersion protocol.VarInt) protocol.Packet {
+	if protocolVersion < encryptionResponseSignatureMinVersion ||
+		protocolVersion > encryptionResponseSignatureMaxVersion {
+		return protocol.MarshalPacket(
+			ServerBoundEncryptionResponsePacketID,
+			pk.SharedSecret,
+			pk.VerifyToken,
+		)
+	}
+
+	if pk.HasVerifyToken() {
+		return protocol.MarshalPacket(
+			ServerBoundEncryptionResponsePacketID,
+			pk.SharedSecret,
+			protocol.Boolean(true),
+			pk.VerifyToken,
+		)
+	}
+
+	return protocol.MarshalPacket(
+		ServerBoundEncryptionResponsePacketID,
+		pk.SharedSecret,
+		protocol.Boolean(false),
+		pk.Salt,
+		pk.MessageSignature,
+	)
+}
+
+func UnmarshalServerBoundEncryptionResponse(packet protocol.Packet, protocolVersion protocol.VarInt) (ServerBoundEncryptionResponse, error) {
+	var pk ServerBoundEncryptionResponse
+
+	if packet.ID != ServerBoundEncryptionResponsePacketID {
+		return pk, protocol.ErrInvalidPacketID
+	}
+
+	if protocolVersion < encryptionResponseSignatureMinVersion ||
+		protocolVersion > encryptionResponseSignatureMaxVersion {
+		if err := packet.Scan(
+			&pk.SharedSecret,
+			&pk.VerifyToken,
+		); err != nil {
+			return pk, err
+		}
+		return pk, nil
+	}
+
+	r := bytes.NewReader(packet.Data)
+	var hasVerifyToken protocol.Boolean
+	if err := protocol.ScanFields(r, &pk.SharedSecret, &hasVerifyToken); err != nil {
+		return pk, err
+	}
+
+	if hasVerifyToken {
+		if err := protocol.ScanFields(r, &pk.VerifyToken); err != nil {
+			return pk, err
+		}
+		return pk, nil
+	}
+
+	if err := protocol.ScanFields(r, &pk.Salt, &pk.MessageSignature); err != nil {
+		return pk, err
+	}
+
+	return pk, nil
+}
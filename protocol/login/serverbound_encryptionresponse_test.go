@@ -0,0 +1,117 @@
+package login
+
+import (
+	"bytes"
+	"github.com/haveachin/infrared/protocol"
+	"testing"
+)
+
+func TestServerBoundEncryptionResponse_Marshal(t *testing.T) {
+	tt := []struct {
+		packet          ServerBoundEncryptionResponse
+		protocolVersion protocol.VarInt
+		marshaledPacket protocol.Packet
+	}{
+		{
+			packet: ServerBoundEncryptionResponse{
+				SharedSecret: protocol.ByteArray{0x01, 0x02},
+				VerifyToken:  protocol.ByteArray{0x03, 0x04},
+			},
+			protocolVersion: 754,
+			marshaledPacket: protocol.Packet{
+				ID:   0x01,
+				Data: []byte{0x02, 0x01, 0x02, 0x02, 0x03, 0x04},
+			},
+		},
+		{
+			packet: ServerBoundEncryptionResponse{
+				SharedSecret: protocol.ByteArray{0x01, 0x02},
+				VerifyToken:  protocol.ByteArray{0x03, 0x04},
+			},
+			protocolVersion: 759,
+			marshaledPacket: protocol.Packet{
+				ID:   0x01,
+				Data: []byte{0x02, 0x01, 0x02, 0x01, 0x02, 0x03, 0x04},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		pk := tc.packet.Marshal(tc.protocolVersion)
+
+		if pk.ID != ServerBoundEncryptionResponsePacketID {
+			t.Error("invalid packet id")
+		}
+
+		if !bytes.Equal(pk.Data, tc.marshaledPacket.Data) {
+			t.Errorf("got: %v, want: %v", pk.Data, tc.marshaledPacket.Data)
+		}
+	}
+}
+
+func TestUnmarshalServerBoundEncryptionResponse(t *testing.T) {
+	tt := []struct {
+		packet             protocol.Packet
+		protocolVersion    protocol.VarInt
+		unmarshalledPacket ServerBoundEncryptionResponse
+		hasVerifyToken     bool
+	}{
+		{
+			packet: protocol.Packet{
+				ID:   0x01,
+				Data: []byte{0x02, 0x01, 0x02, 0x02, 0x03, 0x04},
+			},
+			protocolVersion: 754,
+			unmarshalledPacket: ServerBoundEncryptionResponse{
+				SharedSecret: protocol.ByteArray{0x01, 0x02},
+				VerifyToken:  protocol.ByteArray{0x03, 0x04},
+			},
+			hasVerifyToken: true,
+		},
+		{
+			packet: protocol.Packet{
+				ID:   0x01,
+				Data: []byte{0x02, 0x01, 0x02, 0x01, 0x02, 0x03, 0x04},
+			},
+			protocolVersion: 760,
+			unmarshalledPacket: ServerBoundEncryptionResponse{
+				SharedSecret: protocol.ByteArray{0x01, 0x02},
+				VerifyToken:  protocol.ByteArray{0x03, 0x04},
+			},
+			hasVerifyToken: true,
+		},
+		{
+			packet: protocol.Packet{
+				ID:   0x01,
+				Data: []byte{0x02, 0x01, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x2a, 0x01, 0xff},
+			},
+			protocolVersion: 759,
+			unmarshalledPacket: ServerBoundEncryptionResponse{
+				SharedSecret:     protocol.ByteArray{0x01, 0x02},
+				Salt:             42,
+				MessageSignature: protocol.ByteArray{0xff},
+			},
+			hasVerifyToken: false,
+		},
+	}
+
+	for _, tc := range tt {
+		actual, err := UnmarshalServerBoundEncryptionResponse(tc.packet, tc.protocolVersion)
+		if err != nil {
+			t.Error(err)
+		}
+
+		expected := tc.unmarshalledPacket
+
+		if !bytes.Equal(actual.SharedSecret, expected.SharedSecret) ||
+			!bytes.Equal(actual.VerifyToken, expected.VerifyToken) ||
+			actual.Salt != expected.Salt ||
+			!bytes.Equal(actual.MessageSignature, expected.MessageSignature) {
+			t.Errorf("got: %v, want: %v", actual, expected)
+		}
+
+		if actual.HasVerifyToken() != tc.hasVerifyToken {
+			t.Errorf("has verify token: got: %v, want: %v", actual.HasVerifyToken(), tc.hasVerifyToken)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package login
+
+import (
+	"github.com/haveachin/infrared/protocol"
+)
+
+// ServerBoundLoginAcknowledgedPacketID is sent by 1.20.2 and newer clients
+// to switch to the configuration state after the Login Success packet
+const ServerBoundLoginAcknowledgedPacketID protocol.VarInt = 0x03
+
+type ServerBoundLoginAcknowledged struct{}
+
+func (pk ServerBoundLoginAcknowledged) Marshal() protocol.Packet {
+	return protocol.MarshalPacket(
+		ServerBoundLoginAcknowledgedPacketID,
+	)
+}
+
+func UnmarshalServerBoundLoginAcknowledged(packet protocol.Packet) (ServerBoundLoginAcknowledged, error) {
+	var pk ServerBoundLoginAcknowledged
+
+	if packet.ID != ServerBoundLoginAcknowledgedPacketID {
+		return pk, protocol.ErrInvalidPacketID
+	}
+
+	return pk, nil
+}
@@ -0,0 +1,17 @@
+package login
+
+import (
+	"testing"
+
+	"github.com/haveachin/infrared/protocol"
+)
+
+func TestUnmarshalServerBoundLoginAcknowledged(t *testing.T) {
+	if _, err := UnmarshalServerBoundLoginAcknowledged(ServerBoundLoginAcknowledged{}.Marshal()); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := UnmarshalServerBoundLoginAcknowledged(protocol.Packet{ID: 0x00}); err != protocol.ErrInvalidPacketID {
+		t.Errorf("got: %v, want: %v", err, protocol.ErrInvalidPacketID)
+	}
+}
@@ -0,0 +1,55 @@
+package login
+
+import (
+	"bytes"
+
+	"github.com/haveachin/infrared/protocol"
+)
+
+const ServerBoundLoginPluginResponsePacketID protocol.VarInt = 0x02
+
+type ServerBoundLoginPluginResponse struct {
+	MessageID  protocol.VarInt
+	Successful protocol.Boolean
+	Data       protocol.OptionalByteArray
+}
+
+func (pk ServerBoundLoginPluginResponse) Marshal() protocol.Packet {
+	if !pk.Successful {
+		return protocol.MarshalPacket(
+			ServerBoundLoginPluginResponsePacketID,
+			pk.MessageID,
+			pk.Successful,
+		)
+	}
+
+	return protocol.MarshalPacket(
+		ServerBoundLoginPluginResponsePacketID,
+		pk.MessageID,
+		pk.Successful,
+		pk.Data,
+	)
+}
+
+func UnmarshalServerBoundLoginPluginResponse(packet protocol.Packet) (ServerBoundLoginPluginResponse, error) {
+	var pk ServerBoundLoginPluginResponse
+
+	if packet.ID != ServerBoundLoginPluginResponsePacketID {
+		return pk, protocol.ErrInvalidPacketID
+	}
+
+	r := bytes.NewReader(packet.Data)
+	if err := protocol.ScanFields(r, &pk.MessageID, &pk.Successful); err != nil {
+		return pk, err
+	}
+
+	if !pk.Successful {
+		return pk, nil
+	}
+
+	if err := protocol.ScanFields(r, &pk.Data); err != nil {
+		return pk, err
+	}
+
+	return pk, nil
+}
@@ -0,0 +1,93 @@
+package login
+
+import (
+	"bytes"
+	"github.com/haveachin/infrared/protocol"
+	"testing"
+)
+
+func TestServerBoundLoginPluginResponse_Marshal(t *testing.T) {
+	tt := []struct {
+		packet          ServerBoundLoginPluginResponse
+		marshaledPacket protocol.Packet
+	}{
+		{
+			packet: ServerBoundLoginPluginResponse{
+				MessageID:  1,
+				Successful: false,
+				Data:       protocol.OptionalByteArray{0x04},
+			},
+			marshaledPacket: protocol.Packet{
+				ID:   0x02,
+				Data: []byte{0x01, 0x00},
+			},
+		},
+		{
+			packet: ServerBoundLoginPluginResponse{
+				MessageID:  2,
+				Successful: true,
+				Data:       protocol.OptionalByteArray{0x04, 0x05},
+			},
+			marshaledPacket: protocol.Packet{
+				ID:   0x02,
+				Data: []byte{0x02, 0x01, 0x04, 0x05},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		pk := tc.packet.Marshal()
+
+		if pk.ID != ServerBoundLoginPluginResponsePacketID {
+			t.Error("invalid packet id")
+		}
+
+		if !bytes.Equal(pk.Data, tc.marshaledPacket.Data) {
+			t.Errorf("got: %v, want: %v", pk.Data, tc.marshaledPacket.Data)
+		}
+	}
+}
+
+func TestUnmarshalServerBoundLoginPluginResponse(t *testing.T) {
+	tt := []struct {
+		packet             protocol.Packet
+		unmarshalledPacket ServerBoundLoginPluginResponse
+	}{
+		{
+			packet: protocol.Packet{
+				ID:   0x02,
+				Data: []byte{0x01, 0x00},
+			},
+			unmarshalledPacket: ServerBoundLoginPluginResponse{
+				MessageID:  1,
+				Successful: false,
+			},
+		},
+		{
+			packet: protocol.Packet{
+				ID:   0x02,
+				Data: []byte{0x02, 0x01, 0x04, 0x05},
+			},
+			unmarshalledPacket: ServerBoundLoginPluginResponse{
+				MessageID:  2,
+				Successful: true,
+				Data:       protocol.OptionalByteArray{0x04, 0x05},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		actual, err := UnmarshalServerBoundLoginPluginResponse(tc.packet)
+		if err != nil {
+			t.Error(err)
+		}
+
+		expected := tc.unmarshalledPacket
+
+		if actual.MessageID != expected.MessageID ||
+			actual.Successful != expected.Successful ||
+			!bytes.Equal(actual.Data, expected.Data) {
+			t.Errorf("got: %v, want: %v", actual, expected)
+		}
+	}
+}
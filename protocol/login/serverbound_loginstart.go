@@ -1,25 +1,93 @@
 package login
 
 import (
+	"bytes"
+
 	"github.com/haveachin/infrared/protocol"
 )
 
-const ServerBoundLoginStartPacketID byte = 0x00
+const ServerBoundLoginStartPacketID protocol.VarInt = 0x00
 
 type ServerLoginStart struct {
 	Name protocol.String
+
+	// HasSignature, Timestamp, PublicKey and Signature are only
+	// sent by 1.19 up to 1.19.2 clients that have a chat key
+	HasSignature protocol.Boolean
+	Timestamp    protocol.Long
+	PublicKey    protocol.ByteArray
+	Signature    protocol.ByteArray
+
+	// HasPlayerUUID is always true for 1.20.2 and newer clients
+	// since they have to send their UUID
+	HasPlayerUUID protocol.Boolean
+	PlayerUUID    protocol.UUID
+}
+
+func (pk ServerLoginStart) Marshal(protocolVersion protocol.VarInt) protocol.Packet {
+	fields := []protocol.FieldEncoder{pk.Name}
+
+	if protocolVersion >= protocol.Version1_19 && protocolVersion <= protocol.Version1_19_2 {
+		fields = append(fields, pk.HasSignature)
+		if pk.HasSignature {
+			fields = append(fields, pk.Timestamp, pk.PublicKey, pk.Signature)
+		}
+	}
+
+	switch {
+	case protocolVersion >= protocol.Version1_20_2:
+		fields = append(fields, pk.PlayerUUID)
+	case protocolVersion > protocol.Version1_19:
+		fields = append(fields, pk.HasPlayerUUID)
+		if pk.HasPlayerUUID {
+			fields = append(fields, pk.PlayerUUID)
+		}
+	}
+
+	return protocol.MarshalPacket(ServerBoundLoginStartPacketID, fields...)
 }
 
-func UnmarshalServerBoundLoginStart(packet protocol.Packet) (ServerLoginStart, error) {
+func UnmarshalServerBoundLoginStart(packet protocol.Packet, protocolVersion protocol.VarInt) (ServerLoginStart, error) {
 	var pk ServerLoginStart
 
 	if packet.ID != ServerBoundLoginStartPacketID {
 		return pk, protocol.ErrInvalidPacketID
 	}
 
-	if err := packet.Scan(&pk.Name); err != nil {
+	r := bytes.NewReader(packet.Data)
+	if err := protocol.ScanFields(r, &pk.Name); err != nil {
 		return pk, err
 	}
 
+	if protocolVersion >= protocol.Version1_19 && protocolVersion <= protocol.Version1_19_2 {
+		if err := protocol.ScanFields(r, &pk.HasSignature); err != nil {
+			return pk, err
+		}
+
+		if pk.HasSignature {
+			if err := protocol.ScanFields(r, &pk.Timestamp, &pk.PublicKey, &pk.Signature); err != nil {
+				return pk, err
+			}
+		}
+	}
+
+	switch {
+	case protocolVersion >= protocol.Version1_20_2:
+		pk.HasPlayerUUID = true
+		if err := protocol.ScanFields(r, &pk.PlayerUUID); err != nil {
+			return pk, err
+		}
+	case protocolVersion > protocol.Version1_19:
+		if err := protocol.ScanFields(r, &pk.HasPlayerUUID); err != nil {
+			return pk, err
+		}
+
+		if pk.HasPlayerUUID {
+			if err := protocol.ScanFields(r, &pk.PlayerUUID); err != nil {
+				return pk, err
+			}
+		}
+	}
+
 	return pk, nil
 }
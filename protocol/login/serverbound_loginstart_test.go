@@ -1,8 +1,10 @@
 package login
 
 import (
-	"github.com/haveachin/infrared/protocol"
 	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/haveachin/infrared/protocol"
 )
 
 func TestUnmarshalServerBoundLoginStart(t *testing.T) {
@@ -31,7 +33,7 @@ func TestUnmarshalServerBoundLoginStart(t *testing.T) {
 	}
 
 	for _, tc := range tt {
-		loginStart, err := UnmarshalServerBoundLoginStart(tc.packet)
+		loginStart, err := UnmarshalServerBoundLoginStart(tc.packet, protocol.Version1_8)
 		if err != nil {
 			t.Error(err)
 		}
@@ -41,3 +43,85 @@ func TestUnmarshalServerBoundLoginStart(t *testing.T) {
 		}
 	}
 }
+
+func TestServerLoginStart_MarshalVersions(t *testing.T) {
+	playerUUID := protocol.UUID(uuid.Must(uuid.FromString("069a79f4-44e9-4726-a5be-fca90e38aaf5")))
+
+	tt := []struct {
+		protocolVersion protocol.VarInt
+		loginStart      ServerLoginStart
+		dataLength      int
+	}{
+		{
+			protocolVersion: protocol.Version1_8,
+			loginStart:      ServerLoginStart{Name: "Notch"},
+			dataLength:      6,
+		},
+		{
+			protocolVersion: protocol.Version1_19,
+			loginStart: ServerLoginStart{
+				Name:         "Notch",
+				HasSignature: true,
+				Timestamp:    1337,
+				PublicKey:    []byte{0x01, 0x02},
+				Signature:    []byte{0x03},
+			},
+			dataLength: 6 + 1 + 8 + 3 + 2,
+		},
+		{
+			protocolVersion: protocol.Version1_19_2,
+			loginStart: ServerLoginStart{
+				Name:          "Notch",
+				HasPlayerUUID: true,
+				PlayerUUID:    playerUUID,
+			},
+			dataLength: 6 + 1 + 1 + 16,
+		},
+		{
+			protocolVersion: protocol.Version1_19_3,
+			loginStart: ServerLoginStart{
+				Name:          "Notch",
+				HasPlayerUUID: true,
+				PlayerUUID:    playerUUID,
+			},
+			dataLength: 6 + 1 + 16,
+		},
+		{
+			protocolVersion: protocol.Version1_19_3,
+			loginStart:      ServerLoginStart{Name: "Notch"},
+			dataLength:      6 + 1,
+		},
+		{
+			protocolVersion: protocol.Version1_20_2,
+			loginStart: ServerLoginStart{
+				Name:          "Notch",
+				HasPlayerUUID: true,
+				PlayerUUID:    playerUUID,
+			},
+			dataLength: 6 + 16,
+		},
+	}
+
+	for _, tc := range tt {
+		pk := tc.loginStart.Marshal(tc.protocolVersion)
+		if len(pk.Data) != tc.dataLength {
+			t.Errorf("%d: data length: got: %d, want: %d", tc.protocolVersion, len(pk.Data), tc.dataLength)
+		}
+
+		loginStart, err := UnmarshalServerBoundLoginStart(pk, tc.protocolVersion)
+		if err != nil {
+			t.Errorf("%d: %v", tc.protocolVersion, err)
+			continue
+		}
+
+		if loginStart.Name != tc.loginStart.Name ||
+			loginStart.HasSignature != tc.loginStart.HasSignature ||
+			loginStart.Timestamp != tc.loginStart.Timestamp ||
+			string(loginStart.PublicKey) != string(tc.loginStart.PublicKey) ||
+			string(loginStart.Signature) != string(tc.loginStart.Signature) ||
+			loginStart.HasPlayerUUID != tc.loginStart.HasPlayerUUID ||
+			loginStart.PlayerUUID != tc.loginStart.PlayerUUID {
+			t.Errorf("%d: got: %v, want: %v", tc.protocolVersion, loginStart, tc.loginStart)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"unicode/utf16"
+)
+
+const (
+	nbtTagEnd      byte = 0x00
+	nbtTagByte     byte = 0x01
+	nbtTagString   byte = 0x08
+	nbtTagList     byte = 0x09
+	nbtTagCompound byte = 0x0A
+)
+
+// NBT encodes the component as network NBT, which 1.20.3 and newer
+// clients expect instead of JSON. The root compound has no name.
+func (c ChatComponent) NBT() []byte {
+	return append([]byte{nbtTagCompound}, c.nbtCompound()...)
+}
+
+func (c ChatComponent) nbtCompound() []byte {
+	var bb []byte
+
+	if c.Translate == "" || c.Text != "" {
+		bb = appendNBTString(bb, "text", c.Text)
+	}
+
+	if c.Translate != "" {
+		bb = appendNBTString(bb, "translate", c.Translate)
+	}
+
+	if c.Color != "" {
+		bb = appendNBTString(bb, "color", c.Color)
+	}
+
+	styles := []struct {
+		name    string
+		enabled *bool
+	}{
+		{"bold", c.Bold},
+		{"italic", c.Italic},
+		{"underlined", c.Underlined},
+		{"strikethrough", c.Strikethrough},
+		{"obfuscated", c.Obfuscated},
+	}
+
+	for _, style := range styles {
+		if style.enabled == nil {
+			continue
+		}
+
+		value := byte(0)
+		if *style.enabled {
+			value = 1
+		}
+		bb = appendNBTName(bb, nbtTagByte, style.name)
+		bb = append(bb, value)
+	}
+
+	bb = appendNBTComponents(bb, "with", c.With)
+	bb = appendNBTComponents(bb, "extra", c.Extra)
+
+	return append(bb, nbtTagEnd)
+}
+
+func appendNBTComponents(bb []byte, name string, components []ChatComponent) []byte {
+	if len(components) == 0 {
+		return bb
+	}
+
+	bb = appendNBTName(bb, nbtTagList, name)
+	bb = append(bb, nbtTagCompound)
+
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(components)))
+	bb = append(bb, length[:]...)
+
+	for _, component := range components {
+		bb = append(bb, component.nbtCompound()...)
+	}
+
+	return bb
+}
+
+func appendNBTString(bb []byte, name, value string) []byte {
+	bb = appendNBTName(bb, nbtTagString, name)
+	return appendModifiedUTF8(bb, value)
+}
+
+func appendNBTName(bb []byte, tag byte, name string) []byte {
+	bb = append(bb, tag)
+	return appendModifiedUTF8(bb, name)
+}
+
+// appendModifiedUTF8 appends the length prefixed string in Java's modified UTF-8,
+// which encodes the null character and every half of a surrogate pair separately
+func appendModifiedUTF8(bb []byte, s string) []byte {
+	var encoded []byte
+	for _, c := range utf16.Encode([]rune(s)) {
+		switch {
+		case c >= 0x0001 && c <= 0x007F:
+			encoded = append(encoded, byte(c))
+		case c <= 0x07FF:
+			encoded = append(encoded,
+				byte(0xC0|(c>>6)&0x1F),
+				byte(0x80|c&0x3F))
+		default:
+			encoded = append(encoded,
+				byte(0xE0|(c>>12)&0x0F),
+				byte(0x80|(c>>6)&0x3F),
+				byte(0x80|c&0x3F))
+		}
+	}
+
+	var length [2]byte
+	binary.BigEndian.PutUint16(length[:], uint16(len(encoded)))
+	bb = append(bb, length[:]...)
+	return append(bb, encoded...)
+}
@@ -8,7 +8,7 @@ import (
 
 // Packet is the raw representation of message that is send between the client and the server
 type Packet struct {
-	ID   byte
+	ID   VarInt
 	Data []byte
 }
 
@@ -20,7 +20,7 @@ func (pk Packet) Scan(fields ...FieldDecoder) error {
 // Marshal encodes the packet and all it's fields
 func (pk *Packet) Marshal() ([]byte, error) {
 	var packedData []byte
-	data := []byte{pk.ID}
+	data := pk.ID.Encode()
 	data = append(data, pk.Data...)
 
 	packedData = append(packedData, VarInt(int32(len(data))).Encode()...)
@@ -39,7 +39,7 @@ func ScanFields(r DecodeReader, fields ...FieldDecoder) error {
 }
 
 // MarshalPacket transforms an ID and Fields into a Packet
-func MarshalPacket(ID byte, fields ...FieldEncoder) Packet {
+func MarshalPacket(ID VarInt, fields ...FieldEncoder) Packet {
 	var pkt Packet
 	pkt.ID = ID
 
@@ -76,9 +76,20 @@ func ReadPacket(r DecodeReader) (Packet, error) {
 		return Packet{}, err
 	}
 
+	return unmarshalPacketBytes(data)
+}
+
+// unmarshalPacketBytes splits the VarInt packet ID from the data of the packet
+func unmarshalPacketBytes(data []byte) (Packet, error) {
+	r := bytes.NewReader(data)
+	var id VarInt
+	if err := id.Decode(r); err != nil {
+		return Packet{}, err
+	}
+
 	return Packet{
-		ID:   data[0],
-		Data: data[1:],
+		ID:   id,
+		Data: data[len(data)-r.Len():],
 	}, nil
 }
 
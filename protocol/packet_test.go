@@ -25,6 +25,13 @@ func TestPacket_Marshal(t *testing.T) {
 			},
 			expected: []byte{0x05, 0x0f, 0x00, 0xf2, 0x03, 0x50},
 		},
+		{
+			packet: Packet{
+				ID:   0x80,
+				Data: []byte{0x00, 0xf2},
+			},
+			expected: []byte{0x04, 0x80, 0x01, 0x00, 0xf2},
+		},
 	}
 
 	for _, tc := range tt {
@@ -102,7 +109,7 @@ func TestScanFields(t *testing.T) {
 
 func TestMarshalPacket(t *testing.T) {
 	// Arrange
-	packetId := byte(0x00)
+	packetId := VarInt(0x00)
 	booleanField := Boolean(false)
 	byteField := Byte(0x0f)
 	packetData := []byte{0x00, 0x0f}
@@ -169,6 +176,14 @@ func TestReadPacket(t *testing.T) {
 			},
 			dataAfterRead: []byte{0x30, 0x01, 0xef, 0xaa},
 		},
+		{
+			data: []byte{0x04, 0x80, 0x01, 0x00, 0xf2, 0x30},
+			packet: Packet{
+				ID:   0x80,
+				Data: []byte{0x00, 0xf2},
+			},
+			dataAfterRead: []byte{0x30},
+		},
 	}
 
 	for _, tc := range tt {
@@ -0,0 +1,52 @@
+package play
+
+import (
+	"github.com/haveachin/infrared/protocol"
+)
+
+const (
+	BossBarActionAdd          protocol.VarInt = 0
+	BossBarActionRemove       protocol.VarInt = 1
+	BossBarActionUpdateHealth protocol.VarInt = 2
+	BossBarActionUpdateTitle  protocol.VarInt = 3
+
+	BossBarColorPink   protocol.VarInt = 0
+	BossBarColorBlue   protocol.VarInt = 1
+	BossBarColorYellow protocol.VarInt = 4
+
+	BossBarDivisionNone protocol.VarInt = 0
+)
+
+// ClientBoundBossBar shows a boss bar to 1.9 and newer clients
+type ClientBoundBossBar struct {
+	UUID     protocol.UUID
+	Action   protocol.VarInt
+	Title    protocol.ChatComponent
+	Health   protocol.Float
+	Color    protocol.VarInt
+	Division protocol.VarInt
+	Flags    protocol.UnsignedByte
+}
+
+func (pk ClientBoundBossBar) Marshal(protocolVersion protocol.VarInt) (protocol.Packet, error) {
+	fields := []protocol.FieldEncoder{pk.UUID, pk.Action}
+	switch pk.Action {
+	case BossBarActionAdd:
+		fields = append(fields, pk.Title.Chat(), pk.Health, pk.Color, pk.Division, pk.Flags)
+	case BossBarActionRemove:
+	case BossBarActionUpdateHealth:
+		fields = append(fields, pk.Health)
+	case BossBarActionUpdateTitle:
+		fields = append(fields, pk.Title.Chat())
+	default:
+		return protocol.Packet{}, protocol.ErrUnsupportedPacket
+	}
+
+	return protocol.DefaultRegistry.MarshalPacket(
+		protocolVersion,
+		protocol.StatePlay,
+		protocol.ClientBound,
+		protocol.PacketBossBar,
+		fields...,
+	)
+}
@@ -0,0 +1,30 @@
+package play
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/haveachin/infrared/protocol"
+)
+
+func TestClientBoundBossBar_Marshal(t *testing.T) {
+	uuid := protocol.UUID{0x01}
+
+	pk, err := ClientBoundBossBar{
+		UUID:   uuid,
+		Action: BossBarActionUpdateHealth,
+		Health: 1,
+	}.Marshal(protocol.Version1_12_2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := append(uuid.Encode(), 0x02, 0x3f, 0x80, 0x00, 0x00)
+	if pk.ID != 0x0C || !bytes.Equal(pk.Data, expected) {
+		t.Errorf("got: %v, want: %v", pk.Data, expected)
+	}
+
+	if _, err := (ClientBoundBossBar{}).Marshal(protocol.Version1_8); err != protocol.ErrUnsupportedPacket {
+		t.Errorf("got: %v, want: %v", err, protocol.ErrUnsupportedPacket)
+	}
+}
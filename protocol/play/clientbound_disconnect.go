@@ -0,0 +1,19 @@
+package play
+
+import (
+	"github.com/haveachin/infrared/protocol"
+)
+
+type ClientBoundDisconnect struct {
+	Reason protocol.ChatComponent
+}
+
+func (pk ClientBoundDisconnect) Marshal(protocolVersion protocol.VarInt) (protocol.Packet, error) {
+	return protocol.DefaultRegistry.MarshalPacket(
+		protocolVersion,
+		protocol.StatePlay,
+		protocol.ClientBound,
+		protocol.PacketDisconnect,
+		pk.Reason.Chat(),
+	)
+}
@@ -0,0 +1,22 @@
+package play
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/haveachin/infrared/protocol"
+)
+
+func TestClientBoundDisconnect_Marshal(t *testing.T) {
+	pk, err := ClientBoundDisconnect{
+		Reason: protocol.ChatComponent{Text: "Bye"},
+	}.Marshal(protocol.Version1_12_2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := protocol.String(`{"text":"Bye"}`).Encode()
+	if pk.ID != 0x1A || !bytes.Equal(pk.Data, expected) {
+		t.Errorf("got: %v, want: %v", pk.Data, expected)
+	}
+}
@@ -0,0 +1,49 @@
+package play
+
+import (
+	"github.com/haveachin/infrared/protocol"
+)
+
+// joinGameIntDimensionVersion is the protocol version of 1.9.1
+// that widened the dimension from a byte to an int
+const joinGameIntDimensionVersion protocol.VarInt = 108
+
+const (
+	GamemodeSurvival  protocol.UnsignedByte = 0
+	GamemodeSpectator protocol.UnsignedByte = 3
+
+	DimensionOverworld protocol.Int = 0
+)
+
+// ClientBoundJoinGame is the first packet that a client receives in the play state.
+// Only the layouts up to 1.13.2 are supported; newer clients can't join a world without chunks.
+type ClientBoundJoinGame struct {
+	EntityID         protocol.Int
+	Gamemode         protocol.UnsignedByte
+	Dimension        protocol.Int
+	Difficulty       protocol.UnsignedByte
+	MaxPlayers       protocol.UnsignedByte
+	LevelType        protocol.String
+	ReducedDebugInfo protocol.Boolean
+}
+
+func (pk ClientBoundJoinGame) Marshal(protocolVersion protocol.VarInt) (protocol.Packet, error) {
+	var dimension protocol.FieldEncoder = pk.Dimension
+	if protocolVersion < joinGameIntDimensionVersion {
+		dimension = protocol.Byte(pk.Dimension)
+	}
+
+	return protocol.DefaultRegistry.MarshalPacket(
+		protocolVersion,
+		protocol.StatePlay,
+		protocol.ClientBound,
+		protocol.PacketJoinGame,
+		pk.EntityID,
+		pk.Gamemode,
+		dimension,
+		pk.Difficulty,
+		pk.MaxPlayers,
+		pk.LevelType,
+		pk.ReducedDebugInfo,
+	)
+}
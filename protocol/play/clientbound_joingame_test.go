@@ -0,0 +1,62 @@
+package play
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/haveachin/infrared/protocol"
+)
+
+func TestClientBoundJoinGame_Marshal(t *testing.T) {
+	packet := ClientBoundJoinGame{
+		EntityID:   1,
+		Gamemode:   GamemodeSpectator,
+		Dimension:  DimensionOverworld,
+		MaxPlayers: 1,
+		LevelType:  "flat",
+	}
+	flat := protocol.String("flat").Encode()
+
+	tt := []struct {
+		protocolVersion protocol.VarInt
+		marshaledPacket protocol.Packet
+	}{
+		{
+			protocolVersion: protocol.Version1_8,
+			marshaledPacket: protocol.Packet{
+				ID:   0x01,
+				Data: concat([]byte{0x00, 0x00, 0x00, 0x01, 0x03, 0x00, 0x00, 0x01}, flat, []byte{0x00}),
+			},
+		},
+		{
+			protocolVersion: protocol.Version1_13_2,
+			marshaledPacket: protocol.Packet{
+				ID:   0x25,
+				Data: concat([]byte{0x00, 0x00, 0x00, 0x01, 0x03, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01}, flat, []byte{0x00}),
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		pk, err := packet.Marshal(tc.protocolVersion)
+		if err != nil {
+			t.Error(err)
+		}
+
+		if pk.ID != tc.marshaledPacket.ID || !bytes.Equal(pk.Data, tc.marshaledPacket.Data) {
+			t.Errorf("%d: got: %v, want: %v", tc.protocolVersion, pk, tc.marshaledPacket)
+		}
+	}
+
+	if _, err := packet.Marshal(protocol.Version1_14); err != protocol.ErrUnsupportedPacket {
+		t.Errorf("got: %v, want: %v", err, protocol.ErrUnsupportedPacket)
+	}
+}
+
+func concat(slices ...[]byte) []byte {
+	var bb []byte
+	for _, s := range slices {
+		bb = append(bb, s...)
+	}
+	return bb
+}
@@ -0,0 +1,25 @@
+package play
+
+import (
+	"github.com/haveachin/infrared/protocol"
+)
+
+type ClientBoundKeepAlive struct {
+	KeepAliveID protocol.Long
+}
+
+func (pk ClientBoundKeepAlive) Marshal(protocolVersion protocol.VarInt) (protocol.Packet, error) {
+	var id protocol.FieldEncoder = pk.KeepAliveID
+	// Clients older than 1.12.2 expect a VarInt
+	if protocolVersion < protocol.Version1_12_2 {
+		id = protocol.VarInt(pk.KeepAliveID)
+	}
+
+	return protocol.DefaultRegistry.MarshalPacket(
+		protocolVersion,
+		protocol.StatePlay,
+		protocol.ClientBound,
+		protocol.PacketKeepAlive,
+		id,
+	)
+}
@@ -0,0 +1,41 @@
+package play
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/haveachin/infrared/protocol"
+)
+
+func TestClientBoundKeepAlive_Marshal(t *testing.T) {
+	tt := []struct {
+		protocolVersion protocol.VarInt
+		marshaledPacket protocol.Packet
+	}{
+		{
+			protocolVersion: protocol.Version1_8,
+			marshaledPacket: protocol.Packet{
+				ID:   0x00,
+				Data: []byte{0x2a},
+			},
+		},
+		{
+			protocolVersion: protocol.Version1_12_2,
+			marshaledPacket: protocol.Packet{
+				ID:   0x1F,
+				Data: []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x2a},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		pk, err := ClientBoundKeepAlive{KeepAliveID: 42}.Marshal(tc.protocolVersion)
+		if err != nil {
+			t.Error(err)
+		}
+
+		if pk.ID != tc.marshaledPacket.ID || !bytes.Equal(pk.Data, tc.marshaledPacket.Data) {
+			t.Errorf("%d: got: %v, want: %v", tc.protocolVersion, pk, tc.marshaledPacket)
+		}
+	}
+}
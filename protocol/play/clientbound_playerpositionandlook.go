@@ -0,0 +1,40 @@
+package play
+
+import (
+	"github.com/haveachin/infrared/protocol"
+)
+
+// ClientBoundPlayerPositionAndLook teleports the player and
+// closes the "Downloading terrain" screen after joining
+type ClientBoundPlayerPositionAndLook struct {
+	X          protocol.Double
+	Y          protocol.Double
+	Z          protocol.Double
+	Yaw        protocol.Float
+	Pitch      protocol.Float
+	Flags      protocol.Byte
+	TeleportID protocol.VarInt
+}
+
+func (pk ClientBoundPlayerPositionAndLook) Marshal(protocolVersion protocol.VarInt) (protocol.Packet, error) {
+	fields := []protocol.FieldEncoder{
+		pk.X,
+		pk.Y,
+		pk.Z,
+		pk.Yaw,
+		pk.Pitch,
+		pk.Flags,
+	}
+
+	if protocolVersion >= protocol.Version1_9 {
+		fields = append(fields, pk.TeleportID)
+	}
+
+	return protocol.DefaultRegistry.MarshalPacket(
+		protocolVersion,
+		protocol.StatePlay,
+		protocol.ClientBound,
+		protocol.PacketPlayerPositionAndLook,
+		fields...,
+	)
+}
@@ -0,0 +1,37 @@
+package play
+
+import (
+	"testing"
+
+	"github.com/haveachin/infrared/protocol"
+)
+
+func TestClientBoundPlayerPositionAndLook_Marshal(t *testing.T) {
+	tt := []struct {
+		protocolVersion protocol.VarInt
+		id              protocol.VarInt
+		dataLength      int
+	}{
+		{
+			protocolVersion: protocol.Version1_8,
+			id:              0x08,
+			dataLength:      3*8 + 2*4 + 1,
+		},
+		{
+			protocolVersion: protocol.Version1_12_2,
+			id:              0x2F,
+			dataLength:      3*8 + 2*4 + 1 + 1,
+		},
+	}
+
+	for _, tc := range tt {
+		pk, err := ClientBoundPlayerPositionAndLook{Y: 64}.Marshal(tc.protocolVersion)
+		if err != nil {
+			t.Error(err)
+		}
+
+		if pk.ID != tc.id || len(pk.Data) != tc.dataLength {
+			t.Errorf("%d: got: %v, want: ID %v with %d bytes", tc.protocolVersion, pk, tc.id, tc.dataLength)
+		}
+	}
+}
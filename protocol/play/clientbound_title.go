@@ -0,0 +1,51 @@
+package play
+
+import (
+	"github.com/haveachin/infrared/protocol"
+)
+
+type TitleAction int
+
+const (
+	TitleActionSetTitle TitleAction = iota
+	TitleActionSetSubtitle
+	TitleActionSetTimes
+)
+
+// ClientBoundTitle is the combined title packet of 1.8 up to 1.16.5
+type ClientBoundTitle struct {
+	Action TitleAction
+	Text   protocol.ChatComponent
+
+	// FadeIn, Stay and FadeOut are in ticks and only used by TitleActionSetTimes
+	FadeIn  protocol.Int
+	Stay    protocol.Int
+	FadeOut protocol.Int
+}
+
+func (pk ClientBoundTitle) Marshal(protocolVersion protocol.VarInt) (protocol.Packet, error) {
+	var fields []protocol.FieldEncoder
+	switch pk.Action {
+	case TitleActionSetTitle:
+		fields = []protocol.FieldEncoder{protocol.VarInt(0), pk.Text.Chat()}
+	case TitleActionSetSubtitle:
+		fields = []protocol.FieldEncoder{protocol.VarInt(1), pk.Text.Chat()}
+	case TitleActionSetTimes:
+		// 1.11 inserted the action bar action before the times action
+		action := protocol.VarInt(2)
+		if protocolVersion >= protocol.Version1_11 {
+			action = 3
+		}
+		fields = []protocol.FieldEncoder{action, pk.FadeIn, pk.Stay, pk.FadeOut}
+	default:
+		return protocol.Packet{}, protocol.ErrUnsupportedPacket
+	}
+
+	return protocol.DefaultRegistry.MarshalPacket(
+		protocolVersion,
+		protocol.StatePlay,
+		protocol.ClientBound,
+		protocol.PacketTitle,
+		fields...,
+	)
+}
@@ -0,0 +1,65 @@
+package play
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/haveachin/infrared/protocol"
+)
+
+func TestClientBoundTitle_Marshal(t *testing.T) {
+	tt := []struct {
+		protocolVersion protocol.VarInt
+		packet          ClientBoundTitle
+		marshaledPacket protocol.Packet
+	}{
+		{
+			protocolVersion: protocol.Version1_8,
+			packet: ClientBoundTitle{
+				Action: TitleActionSetTitle,
+				Text:   protocol.ChatComponent{Text: "Hi"},
+			},
+			marshaledPacket: protocol.Packet{
+				ID:   0x45,
+				Data: append([]byte{0x00}, protocol.String(`{"text":"Hi"}`).Encode()...),
+			},
+		},
+		{
+			protocolVersion: protocol.Version1_8,
+			packet: ClientBoundTitle{
+				Action:  TitleActionSetTimes,
+				FadeIn:  1,
+				Stay:    2,
+				FadeOut: 3,
+			},
+			marshaledPacket: protocol.Packet{
+				ID:   0x45,
+				Data: []byte{0x02, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x03},
+			},
+		},
+		{
+			protocolVersion: protocol.Version1_13,
+			packet: ClientBoundTitle{
+				Action:  TitleActionSetTimes,
+				FadeIn:  1,
+				Stay:    2,
+				FadeOut: 3,
+			},
+			marshaledPacket: protocol.Packet{
+				ID:   0x4B,
+				Data: []byte{0x03, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x03},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		pk, err := tc.packet.Marshal(tc.protocolVersion)
+		if err != nil {
+			t.Error(err)
+		}
+
+		if pk.ID != tc.marshaledPacket.ID || !bytes.Equal(pk.Data, tc.marshaledPacket.Data) {
+			t.Errorf("%d: got: %v, want: %v", tc.protocolVersion, pk, tc.marshaledPacket)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package protocol
+
+// State is the state of a connection which determines the packets that can be sent
+type State int
+
+const (
+	StateHandshaking State = iota
+	StateStatus
+	StateLogin
+	StateConfiguration
+	StatePlay
+)
+
+// Direction is the direction that a packet is sent in
+type Direction int
+
+const (
+	ServerBound Direction = iota
+	ClientBound
+)
+
+// AnyVersion can be used as the maximum protocol version of a registry entry
+// that is still valid for all newer protocol versions
+const AnyVersion VarInt = -1
+
+type registryKey struct {
+	state     State
+	direction Direction
+	name      string
+}
+
+type registryEntry struct {
+	minVersion VarInt
+	maxVersion VarInt
+	id         VarInt
+}
+
+func (entry registryEntry) contains(protocolVersion VarInt) bool {
+	if protocolVersion < entry.minVersion {
+		return false
+	}
+	return entry.maxVersion == AnyVersion || protocolVersion <= entry.maxVersion
+}
+
+// Registry maps packet names to their IDs for every protocol version and state.
+// Configuration and play packets are renumbered between Minecraft releases, so their
+// IDs should be looked up here instead of hardcoding them.
+type Registry struct {
+	entries map[registryKey][]registryEntry
+}
+
+// NewRegistry creates an empty Registry
+func NewRegistry() *Registry {
+	return &Registry{
+		entries: map[registryKey][]registryEntry{},
+	}
+}
+
+// Register maps the packet name to the ID for all protocol versions from minVersion
+// up to and including maxVersion. Use AnyVersion as maxVersion for open ranges.
+func (registry *Registry) Register(state State, direction Direction, name string, minVersion, maxVersion, id VarInt) {
+	key := registryKey{
+		state:     state,
+		direction: direction,
+		name:      name,
+	}
+
+	registry.entries[key] = append(registry.entries[key], registryEntry{
+		minVersion: minVersion,
+		maxVersion: maxVersion,
+		id:         id,
+	})
+}
+
+// PacketID looks up the ID of the named packet in the given protocol version
+func (registry *Registry) PacketID(protocolVersion VarInt, state State, direction Direction, name string) (VarInt, bool) {
+	key := registryKey{
+		state:     state,
+		direction: direction,
+		name:      name,
+	}
+
+	for _, entry := range registry.entries[key] {
+		if entry.contains(protocolVersion) {
+			return entry.id, true
+		}
+	}
+
+	return 0, false
+}
+
+// PacketName looks up the name of the packet with the given ID in the given protocol version
+func (registry *Registry) PacketName(protocolVersion VarInt, state State, direction Direction, id VarInt) (string, bool) {
+	for key, entries := range registry.entries {
+		if key.state != state || key.direction != direction {
+			continue
+		}
+
+		for _, entry := range entries {
+			if entry.id == id && entry.contains(protocolVersion) {
+				return key.name, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// MarshalPacket transforms the fields into the named packet with the ID of the given protocol version
+func (registry *Registry) MarshalPacket(protocolVersion VarInt, state State, direction Direction, name string, fields ...FieldEncoder) (Packet, error) {
+	id, ok := registry.PacketID(protocolVersion, state, direction, name)
+	if !ok {
+		return Packet{}, ErrUnsupportedPacket
+	}
+
+	return MarshalPacket(id, fields...), nil
+}
+
+// IsSupported reports whether the registry knows the ID of every named packet in the given protocol version
+func (registry *Registry) IsSupported(protocolVersion VarInt, state State, direction Direction, names ...string) bool {
+	for _, name := range names {
+		if _, ok := registry.PacketID(protocolVersion, state, direction, name); !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,107 @@
+package protocol
+
+// Protocol versions of Minecraft releases where packets have been renumbered
+const (
+	Version1_8    VarInt = 47
+	Version1_9    VarInt = 107
+	Version1_11   VarInt = 315
+	Version1_12   VarInt = 335
+	Version1_12_1 VarInt = 338
+	Version1_12_2 VarInt = 340
+	Version1_13   VarInt = 393
+	Version1_13_2 VarInt = 404
+	Version1_14   VarInt = 477
+	Version1_16   VarInt = 735
+	Version1_19   VarInt = 759
+	Version1_19_2 VarInt = 760
+	Version1_19_3 VarInt = 761
+	Version1_20_2 VarInt = 764
+	Version1_20_3 VarInt = 765
+	Version1_20_5 VarInt = 766
+	Version1_21_2 VarInt = 768
+)
+
+// Names of the packets in the DefaultRegistry
+const (
+	PacketPluginMessage                  = "PluginMessage"
+	PacketDisconnect                     = "Disconnect"
+	PacketKeepAlive                      = "KeepAlive"
+	PacketFinishConfiguration            = "FinishConfiguration"
+	PacketAcknowledgeFinishConfiguration = "AcknowledgeFinishConfiguration"
+	PacketTransfer                       = "Transfer"
+
+	PacketJoinGame              = "JoinGame"
+	PacketPlayerPositionAndLook = "PlayerPositionAndLook"
+	PacketTitle                 = "Title"
+	PacketBossBar               = "BossBar"
+)
+
+// DefaultRegistry holds the IDs of the configuration and play packets that Infrared understands.
+// The IDs of handshaking, status and login packets are the same in every protocol version,
+// so they are constants in the packages of their states, e.g. login.ClientBoundLoginSuccessPacketID.
+var DefaultRegistry = NewRegistry()
+
+func init() {
+	r := DefaultRegistry
+
+	r.Register(StateConfiguration, ServerBound, PacketPluginMessage, Version1_20_2, Version1_20_5-1, 0x01)
+	r.Register(StateConfiguration, ServerBound, PacketPluginMessage, Version1_20_5, AnyVersion, 0x02)
+	r.Register(StateConfiguration, ServerBound, PacketAcknowledgeFinishConfiguration, Version1_20_2, Version1_20_5-1, 0x02)
+	r.Register(StateConfiguration, ServerBound, PacketAcknowledgeFinishConfiguration, Version1_20_5, AnyVersion, 0x03)
+	r.Register(StateConfiguration, ServerBound, PacketKeepAlive, Version1_20_2, Version1_20_5-1, 0x03)
+	r.Register(StateConfiguration, ServerBound, PacketKeepAlive, Version1_20_5, AnyVersion, 0x04)
+	r.Register(StateConfiguration, ClientBound, PacketPluginMessage, Version1_20_2, Version1_20_5-1, 0x00)
+	r.Register(StateConfiguration, ClientBound, PacketPluginMessage, Version1_20_5, AnyVersion, 0x01)
+	r.Register(StateConfiguration, ClientBound, PacketDisconnect, Version1_20_2, Version1_20_5-1, 0x01)
+	r.Register(StateConfiguration, ClientBound, PacketDisconnect, Version1_20_5, AnyVersion, 0x02)
+	r.Register(StateConfiguration, ClientBound, PacketFinishConfiguration, Version1_20_2, Version1_20_5-1, 0x02)
+	r.Register(StateConfiguration, ClientBound, PacketFinishConfiguration, Version1_20_5, AnyVersion, 0x03)
+	r.Register(StateConfiguration, ClientBound, PacketKeepAlive, Version1_20_2, Version1_20_5-1, 0x03)
+	r.Register(StateConfiguration, ClientBound, PacketKeepAlive, Version1_20_5, AnyVersion, 0x04)
+	r.Register(StateConfiguration, ClientBound, PacketTransfer, Version1_20_5, AnyVersion, 0x0B)
+
+	// Play packets are only registered for the versions that Infrared's limbo supports
+	registerPlay := func(direction Direction, name string, ids map[[2]VarInt]VarInt) {
+		for versions, id := range ids {
+			r.Register(StatePlay, direction, name, versions[0], versions[1], id)
+		}
+	}
+
+	registerPlay(ServerBound, PacketKeepAlive, map[[2]VarInt]VarInt{
+		{Version1_8, Version1_9 - 1}:     0x00,
+		{Version1_9, Version1_12 - 1}:    0x0B,
+		{Version1_12, Version1_12_1 - 1}: 0x0C,
+		{Version1_12_1, Version1_12_2}:   0x0B,
+		{Version1_13, Version1_13_2}:     0x0E,
+	})
+	registerPlay(ClientBound, PacketKeepAlive, map[[2]VarInt]VarInt{
+		{Version1_8, Version1_9 - 1}: 0x00,
+		{Version1_9, Version1_12_2}:  0x1F,
+		{Version1_13, Version1_13_2}: 0x21,
+	})
+	registerPlay(ClientBound, PacketJoinGame, map[[2]VarInt]VarInt{
+		{Version1_8, Version1_9 - 1}: 0x01,
+		{Version1_9, Version1_12_2}:  0x23,
+		{Version1_13, Version1_13_2}: 0x25,
+	})
+	registerPlay(ClientBound, PacketPlayerPositionAndLook, map[[2]VarInt]VarInt{
+		{Version1_8, Version1_9 - 1}:    0x08,
+		{Version1_9, Version1_12_1 - 1}: 0x2E,
+		{Version1_12_1, Version1_12_2}:  0x2F,
+		{Version1_13, Version1_13_2}:    0x32,
+	})
+	registerPlay(ClientBound, PacketDisconnect, map[[2]VarInt]VarInt{
+		{Version1_8, Version1_9 - 1}: 0x40,
+		{Version1_9, Version1_12_2}:  0x1A,
+		{Version1_13, Version1_13_2}: 0x1B,
+	})
+	registerPlay(ClientBound, PacketTitle, map[[2]VarInt]VarInt{
+		{Version1_8, Version1_12 - 1}:    0x45,
+		{Version1_12, Version1_12_1 - 1}: 0x47,
+		{Version1_12_1, Version1_12_2}:   0x48,
+		{Version1_13, Version1_13_2}:     0x4B,
+	})
+	registerPlay(ClientBound, PacketBossBar, map[[2]VarInt]VarInt{
+		{Version1_9, Version1_13_2}: 0x0C,
+	})
+}
@@ -0,0 +1,91 @@
+package protocol
+
+import "testing"
+
+func TestRegistry_PacketID(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register(StatePlay, ClientBound, "Test", Version1_8, Version1_9-1, 0x01)
+	registry.Register(StatePlay, ClientBound, "Test", Version1_9, AnyVersion, 0x80)
+
+	tt := []struct {
+		protocolVersion VarInt
+		state           State
+		expectedID      VarInt
+		expectedOK      bool
+	}{
+		{
+			protocolVersion: 5,
+			state:           StatePlay,
+			expectedOK:      false,
+		},
+		{
+			protocolVersion: Version1_8,
+			state:           StatePlay,
+			expectedID:      0x01,
+			expectedOK:      true,
+		},
+		{
+			protocolVersion: Version1_9,
+			state:           StatePlay,
+			expectedID:      0x80,
+			expectedOK:      true,
+		},
+		{
+			protocolVersion: Version1_21_2,
+			state:           StatePlay,
+			expectedID:      0x80,
+			expectedOK:      true,
+		},
+		{
+			protocolVersion: Version1_9,
+			state:           StateLogin,
+			expectedOK:      false,
+		},
+	}
+
+	for _, tc := range tt {
+		id, ok := registry.PacketID(tc.protocolVersion, tc.state, ClientBound, "Test")
+		if ok != tc.expectedOK || id != tc.expectedID {
+			t.Errorf("%d: got: %v, %v; want: %v, %v", tc.protocolVersion, id, ok, tc.expectedID, tc.expectedOK)
+		}
+	}
+}
+
+func TestRegistry_PacketName(t *testing.T) {
+	name, ok := DefaultRegistry.PacketName(Version1_20_5, StateConfiguration, ClientBound, 0x0B)
+	if !ok || name != PacketTransfer {
+		t.Errorf("got: %s, %v; want: %s, true", name, ok, PacketTransfer)
+	}
+
+	if _, ok := DefaultRegistry.PacketName(Version1_20_3, StateConfiguration, ClientBound, 0x0B); ok {
+		t.Error("got: true; want: false")
+	}
+}
+
+func TestDefaultRegistry(t *testing.T) {
+	tt := []struct {
+		protocolVersion VarInt
+		state           State
+		direction       Direction
+		name            string
+		expected        VarInt
+	}{
+		{Version1_8, StatePlay, ClientBound, PacketJoinGame, 0x01},
+		{Version1_12, StatePlay, ServerBound, PacketKeepAlive, 0x0C},
+		{Version1_12_2, StatePlay, ServerBound, PacketKeepAlive, 0x0B},
+		{Version1_13_2, StatePlay, ClientBound, PacketTitle, 0x4B},
+		{Version1_20_3, StateConfiguration, ClientBound, PacketDisconnect, 0x01},
+		{Version1_20_5, StateConfiguration, ClientBound, PacketDisconnect, 0x02},
+	}
+
+	for _, tc := range tt {
+		id, ok := DefaultRegistry.PacketID(tc.protocolVersion, tc.state, tc.direction, tc.name)
+		if !ok || id != tc.expected {
+			t.Errorf("%s %d: got: %v; want: %v", tc.name, tc.protocolVersion, id, tc.expected)
+		}
+	}
+
+	if DefaultRegistry.IsSupported(Version1_8, StatePlay, ClientBound, PacketBossBar) {
+		t.Error("boss bar should not be supported in 1.8")
+	}
+}
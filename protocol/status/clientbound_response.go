@@ -4,7 +4,7 @@ import (
 	"github.com/haveachin/infrared/protocol"
 )
 
-const ClientBoundResponsePacketID byte = 0x00
+const ClientBoundResponsePacketID protocol.VarInt = 0x00
 
 type ClientBoundResponse struct {
 	JSONResponse protocol.String
@@ -56,6 +56,5 @@ type PlayerSampleJSON struct {
 	ID   string `json:"id"`
 }
 
-type DescriptionJSON struct {
-	Text string `json:"text"`
-}
+// DescriptionJSON is the MOTD of the server
+type DescriptionJSON = protocol.ChatComponent
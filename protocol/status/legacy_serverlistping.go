@@ -0,0 +1,156 @@
+package status
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	// LegacyServerListPingPacketID is the first byte that pre-1.7 clients send to ping a server
+	LegacyServerListPingPacketID byte = 0xFE
+	// LegacyKickPacketID is the ID of the kick packet that answers a legacy server list ping
+	LegacyKickPacketID byte = 0xFF
+
+	legacyServerListPingPayload    byte = 0x01
+	legacyPluginMessagePacketID    byte = 0xFA
+	legacyPingHostChannel               = "MC|PingHost"
+	legacyResponseFieldSeparator        = "\x00"
+	legacyResponsePrefix                = "§1"
+	legacyMaxStringLength               = 256
+	legacyMaxPingHostPayloadLength      = 512
+)
+
+var ErrInvalidLegacyServerListPing = errors.New("invalid legacy server list ping")
+
+// LegacyServerListPing is the server list ping of pre-1.7 clients. Only 1.6 clients
+// send the hostname and port that they are connecting to.
+type LegacyServerListPing struct {
+	ProtocolVersion byte
+	ServerAddress   string
+	ServerPort      int32
+}
+
+// HasServerAddress reports whether the ping carries the hostname that the client connects to
+func (pk LegacyServerListPing) HasServerAddress() bool {
+	return pk.ServerAddress != ""
+}
+
+// ReadLegacyServerListPing reads a legacy server list ping. Clients older than 1.6 stop
+// after the first or second byte, so an error after the first byte ends the ping
+// instead of failing it. The caller should set a read deadline to not wait for bytes
+// that never come.
+func ReadLegacyServerListPing(r io.Reader) (LegacyServerListPing, error) {
+	var pk LegacyServerListPing
+
+	var id [1]byte
+	if _, err := io.ReadFull(r, id[:]); err != nil {
+		return pk, err
+	}
+
+	if id[0] != LegacyServerListPingPacketID {
+		return pk, ErrInvalidLegacyServerListPing
+	}
+
+	var payload [2]byte
+	if _, err := io.ReadFull(r, payload[:1]); err != nil || payload[0] != legacyServerListPingPayload {
+		return pk, nil
+	}
+
+	if _, err := io.ReadFull(r, payload[1:]); err != nil || payload[1] != legacyPluginMessagePacketID {
+		return pk, nil
+	}
+
+	channel, err := readLegacyString(r)
+	if err != nil {
+		return pk, err
+	}
+
+	if channel != legacyPingHostChannel {
+		return pk, ErrInvalidLegacyServerListPing
+	}
+
+	var length int16
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return pk, err
+	}
+
+	if length < 0 || length > legacyMaxPingHostPayloadLength {
+		return pk, ErrInvalidLegacyServerListPing
+	}
+
+	data := io.LimitReader(r, int64(length))
+	if err := binary.Read(data, binary.BigEndian, &pk.ProtocolVersion); err != nil {
+		return pk, err
+	}
+
+	pk.ServerAddress, err = readLegacyString(data)
+	if err != nil {
+		return pk, err
+	}
+
+	if err := binary.Read(data, binary.BigEndian, &pk.ServerPort); err != nil {
+		return pk, err
+	}
+
+	return pk, nil
+}
+
+// ParseServerAddress returns the hostname without trailing dots
+func (pk LegacyServerListPing) ParseServerAddress() string {
+	return strings.Trim(pk.ServerAddress, ".")
+}
+
+// LegacyServerListPong is the kick message that answers a legacy server list ping
+type LegacyServerListPong struct {
+	ProtocolVersion int
+	VersionName     string
+	MOTD            string
+	PlayersOnline   int
+	MaxPlayers      int
+}
+
+// Marshal encodes the response in the "§1" kick string format that 1.4 and newer clients understand
+func (pk LegacyServerListPong) Marshal() []byte {
+	response := strings.Join([]string{
+		legacyResponsePrefix,
+		fmt.Sprint(pk.ProtocolVersion),
+		pk.VersionName,
+		pk.MOTD,
+		fmt.Sprint(pk.PlayersOnline),
+		fmt.Sprint(pk.MaxPlayers),
+	}, legacyResponseFieldSeparator)
+
+	return append([]byte{LegacyKickPacketID}, encodeLegacyString(response)...)
+}
+
+func readLegacyString(r io.Reader) (string, error) {
+	var length int16
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return "", err
+	}
+
+	if length < 0 || length > legacyMaxStringLength {
+		return "", ErrInvalidLegacyServerListPing
+	}
+
+	chars := make([]uint16, length)
+	if err := binary.Read(r, binary.BigEndian, chars); err != nil {
+		return "", err
+	}
+
+	return string(utf16.Decode(chars)), nil
+}
+
+func encodeLegacyString(s string) []byte {
+	chars := utf16.Encode([]rune(s))
+	bb := make([]byte, 2+len(chars)*2)
+	binary.BigEndian.PutUint16(bb, uint16(len(chars)))
+	for i, c := range chars {
+		binary.BigEndian.PutUint16(bb[2+i*2:], c)
+	}
+	return bb
+}
@@ -0,0 +1,85 @@
+package status
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestReadLegacyServerListPing(t *testing.T) {
+	tt := []struct {
+		name     string
+		data     []byte
+		expected LegacyServerListPing
+	}{
+		{
+			name:     "Beta",
+			data:     []byte{0xfe},
+			expected: LegacyServerListPing{},
+		},
+		{
+			name:     "1.4",
+			data:     []byte{0xfe, 0x01},
+			expected: LegacyServerListPing{},
+		},
+		{
+			name: "1.6",
+			data: append(append([]byte{0xfe, 0x01, 0xfa},
+				encodeLegacyString("MC|PingHost")...),
+				//    Length  | Proto | Hostname "a.b"                                 | Port
+				0x00, 0x0d, 0x4a, 0x00, 0x03, 0x00, 0x61, 0x00, 0x2e, 0x00, 0x62, 0x00, 0x00, 0x63, 0xdd),
+			expected: LegacyServerListPing{
+				ProtocolVersion: 74,
+				ServerAddress:   "a.b",
+				ServerPort:      25565,
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		actual, err := ReadLegacyServerListPing(bytes.NewReader(tc.data))
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+		}
+
+		if actual != tc.expected {
+			t.Errorf("%s: got: %v; want: %v", tc.name, actual, tc.expected)
+		}
+	}
+}
+
+func TestReadLegacyServerListPing_Invalid(t *testing.T) {
+	tt := [][]byte{
+		{0x00},
+		append([]byte{0xfe, 0x01, 0xfa}, encodeLegacyString("MC|Brand")...),
+	}
+
+	for _, data := range tt {
+		if _, err := ReadLegacyServerListPing(bytes.NewReader(data)); err == nil {
+			t.Errorf("%v: expected an error", data)
+		}
+	}
+}
+
+func TestLegacyServerListPong_Marshal(t *testing.T) {
+	pk := LegacyServerListPong{
+		ProtocolVersion: 1,
+		VersionName:     "v",
+		MOTD:            "m",
+		PlayersOnline:   2,
+		MaxPlayers:      3,
+	}
+
+	expected := []byte{
+		0xff, 0x00, 0x0c,
+		0x00, 0xa7, 0x00, 0x31, 0x00, 0x00, // §1
+		0x00, 0x31, 0x00, 0x00, // 1
+		0x00, 0x76, 0x00, 0x00, // v
+		0x00, 0x6d, 0x00, 0x00, // m
+		0x00, 0x32, 0x00, 0x00, // 2
+		0x00, 0x33, // 3
+	}
+
+	if actual := pk.Marshal(); !bytes.Equal(actual, expected) {
+		t.Errorf("got: %v; want: %v", actual, expected)
+	}
+}
@@ -4,7 +4,7 @@ import (
 	"github.com/haveachin/infrared/protocol"
 )
 
-const ServerBoundRequestPacketID byte = 0x00
+const ServerBoundRequestPacketID protocol.VarInt = 0x00
 
 type ServerBoundRequest struct{}
 
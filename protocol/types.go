@@ -5,6 +5,7 @@ import (
 	"errors"
 	"github.com/gofrs/uuid"
 	"io"
+	"math"
 )
 
 // A Field is both FieldEncoder and FieldDecoder
@@ -34,10 +35,18 @@ type (
 	Boolean bool
 	// Byte is signed 8-bit integer, two's complement
 	Byte int8
+	// UnsignedByte is unsigned 8-bit integer
+	UnsignedByte uint8
 	// UnsignedShort is unsigned 16-bit integer
 	UnsignedShort uint16
+	// Int is signed 32-bit integer, two's complement
+	Int int32
 	// Long is signed 64-bit integer, two's complement
 	Long int64
+	// Float is a single-precision 32-bit IEEE 754 floating point number
+	Float float32
+	// Double is a double-precision 64-bit IEEE 754 floating point number
+	Double float64
 	// String is sequence of Unicode scalar values
 	String string
 
@@ -131,6 +140,21 @@ func (b *Byte) Decode(r DecodeReader) error {
 	return nil
 }
 
+// Encode a UnsignedByte
+func (b UnsignedByte) Encode() []byte {
+	return []byte{byte(b)}
+}
+
+// Decode a UnsignedByte
+func (b *UnsignedByte) Decode(r DecodeReader) error {
+	v, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+	*b = UnsignedByte(v)
+	return nil
+}
+
 // Encode a Unsigned Short
 func (us UnsignedShort) Encode() []byte {
 	n := uint16(us)
@@ -151,6 +175,25 @@ func (us *UnsignedShort) Decode(r DecodeReader) error {
 	return nil
 }
 
+// Encode a Int
+func (i Int) Encode() []byte {
+	n := uint32(i)
+	return []byte{
+		byte(n >> 24), byte(n >> 16), byte(n >> 8), byte(n),
+	}
+}
+
+// Decode a Int
+func (i *Int) Decode(r DecodeReader) error {
+	bb, err := ReadNBytes(r, 4)
+	if err != nil {
+		return err
+	}
+
+	*i = Int(int32(bb[0])<<24 | int32(bb[1])<<16 | int32(bb[2])<<8 | int32(bb[3]))
+	return nil
+}
+
 // Encode a Long
 func (l Long) Encode() []byte {
 	n := uint64(l)
@@ -172,6 +215,36 @@ func (l *Long) Decode(r DecodeReader) error {
 	return nil
 }
 
+// Encode a Float
+func (f Float) Encode() []byte {
+	return Int(math.Float32bits(float32(f))).Encode()
+}
+
+// Decode a Float
+func (f *Float) Decode(r DecodeReader) error {
+	var i Int
+	if err := i.Decode(r); err != nil {
+		return err
+	}
+	*f = Float(math.Float32frombits(uint32(i)))
+	return nil
+}
+
+// Encode a Double
+func (d Double) Encode() []byte {
+	return Long(math.Float64bits(float64(d))).Encode()
+}
+
+// Decode a Double
+func (d *Double) Decode(r DecodeReader) error {
+	var l Long
+	if err := l.Decode(r); err != nil {
+		return err
+	}
+	*d = Double(math.Float64frombits(uint64(l)))
+	return nil
+}
+
 // Encode a VarInt
 func (v VarInt) Encode() []byte {
 	num := uint32(v)
@@ -224,7 +297,7 @@ func (b *ByteArray) Decode(r DecodeReader) error {
 		return err
 	}
 	*b = make([]byte, length)
-	_, err := r.Read(*b)
+	_, err := io.ReadFull(r, *b)
 	return err
 }
 
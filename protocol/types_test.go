@@ -303,6 +303,158 @@ func TestLong_Decode(t *testing.T) {
 	}
 }
 
+var unsignedByteTestTable = []struct {
+	decoded UnsignedByte
+	encoded []byte
+}{
+	{
+		decoded: UnsignedByte(0x00),
+		encoded: []byte{0x00},
+	},
+	{
+		decoded: UnsignedByte(0xff),
+		encoded: []byte{0xff},
+	},
+}
+
+func TestUnsignedByte_Encode(t *testing.T) {
+	for _, tc := range unsignedByteTestTable {
+		if !bytes.Equal(tc.decoded.Encode(), tc.encoded) {
+			t.Errorf("encoding: got: %v; want: %v", tc.decoded.Encode(), tc.encoded)
+		}
+	}
+}
+
+func TestUnsignedByte_Decode(t *testing.T) {
+	for _, tc := range unsignedByteTestTable {
+		var actualDecoded UnsignedByte
+		if err := actualDecoded.Decode(bytes.NewReader(tc.encoded)); err != nil {
+			t.Errorf("decoding: %s", err)
+		}
+
+		if actualDecoded != tc.decoded {
+			t.Errorf("decoding: got %v; want: %v", actualDecoded, tc.decoded)
+		}
+	}
+}
+
+var intTestTable = []struct {
+	decoded Int
+	encoded []byte
+}{
+	{
+		decoded: Int(-2147483648),
+		encoded: []byte{0x80, 0x00, 0x00, 0x00},
+	},
+	{
+		decoded: Int(0),
+		encoded: []byte{0x00, 0x00, 0x00, 0x00},
+	},
+	{
+		decoded: Int(16),
+		encoded: []byte{0x00, 0x00, 0x00, 0x10},
+	},
+	{
+		decoded: Int(2147483647),
+		encoded: []byte{0x7f, 0xff, 0xff, 0xff},
+	},
+}
+
+func TestInt_Encode(t *testing.T) {
+	for _, tc := range intTestTable {
+		if !bytes.Equal(tc.decoded.Encode(), tc.encoded) {
+			t.Errorf("encoding: got: %v; want: %v", tc.decoded.Encode(), tc.encoded)
+		}
+	}
+}
+
+func TestInt_Decode(t *testing.T) {
+	for _, tc := range intTestTable {
+		var actualDecoded Int
+		if err := actualDecoded.Decode(bytes.NewReader(tc.encoded)); err != nil {
+			t.Errorf("decoding: %s", err)
+		}
+
+		if actualDecoded != tc.decoded {
+			t.Errorf("decoding: got %v; want: %v", actualDecoded, tc.decoded)
+		}
+	}
+}
+
+var floatTestTable = []struct {
+	decoded Float
+	encoded []byte
+}{
+	{
+		decoded: Float(0),
+		encoded: []byte{0x00, 0x00, 0x00, 0x00},
+	},
+	{
+		decoded: Float(1),
+		encoded: []byte{0x3f, 0x80, 0x00, 0x00},
+	},
+	{
+		decoded: Float(-2.5),
+		encoded: []byte{0xc0, 0x20, 0x00, 0x00},
+	},
+}
+
+func TestFloat_Encode(t *testing.T) {
+	for _, tc := range floatTestTable {
+		if !bytes.Equal(tc.decoded.Encode(), tc.encoded) {
+			t.Errorf("encoding: got: %v; want: %v", tc.decoded.Encode(), tc.encoded)
+		}
+	}
+}
+
+func TestFloat_Decode(t *testing.T) {
+	for _, tc := range floatTestTable {
+		var actualDecoded Float
+		if err := actualDecoded.Decode(bytes.NewReader(tc.encoded)); err != nil {
+			t.Errorf("decoding: %s", err)
+		}
+
+		if actualDecoded != tc.decoded {
+			t.Errorf("decoding: got %v; want: %v", actualDecoded, tc.decoded)
+		}
+	}
+}
+
+var doubleTestTable = []struct {
+	decoded Double
+	encoded []byte
+}{
+	{
+		decoded: Double(0),
+		encoded: []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+	},
+	{
+		decoded: Double(64),
+		encoded: []byte{0x40, 0x50, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+	},
+}
+
+func TestDouble_Encode(t *testing.T) {
+	for _, tc := range doubleTestTable {
+		if !bytes.Equal(tc.decoded.Encode(), tc.encoded) {
+			t.Errorf("encoding: got: %v; want: %v", tc.decoded.Encode(), tc.encoded)
+		}
+	}
+}
+
+func TestDouble_Decode(t *testing.T) {
+	for _, tc := range doubleTestTable {
+		var actualDecoded Double
+		if err := actualDecoded.Decode(bytes.NewReader(tc.encoded)); err != nil {
+			t.Errorf("decoding: %s", err)
+		}
+
+		if actualDecoded != tc.decoded {
+			t.Errorf("decoding: got %v; want: %v", actualDecoded, tc.decoded)
+		}
+	}
+}
+
 var byteArrayTestTable = []struct {
 	decoded ByteArray
 	encoded []byte
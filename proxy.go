@@ -1,20 +1,35 @@
 package infrared
 
 import (
+	"crypto/rsa"
+	"errors"
 	"fmt"
+	"github.com/gofrs/uuid"
 	"github.com/haveachin/infrared/callback"
+	"github.com/haveachin/infrared/metrics"
 	"github.com/haveachin/infrared/process"
 	"github.com/haveachin/infrared/protocol"
 	"github.com/haveachin/infrared/protocol/handshaking"
 	"github.com/haveachin/infrared/protocol/login"
+	"github.com/haveachin/infrared/protocol/status"
 	"github.com/pires/go-proxyproto"
 	"log"
+	"math/rand"
 	"net"
+	"regexp"
 	"strings"
 	"sync"
 	"time"
 )
 
+var ErrNoProxyTo = errors.New("proxy has no server to proxy to")
+
+// eventStreamSize is how many events the EventStream keeps for replays
+const eventStreamSize = 256
+
+// EventStream receives the events of all proxies, even of proxies without a callback server
+var EventStream = callback.NewStream(eventStreamSize)
+
 func proxyUID(domain, addr string) string {
 	return fmt.Sprintf("%s@%s", strings.ToLower(domain), addr)
 }
@@ -22,9 +37,28 @@ func proxyUID(domain, addr string) string {
 type Proxy struct {
 	Config *ProxyConfig
 
-	cancelTimeoutFunc func()
-	players           map[Conn]string
-	mu                sync.Mutex
+	// processTimer stops the process after the Docker timeout
+	processTimer    *time.Timer
+	processPinned   bool
+	players         map[Conn]player
+	privateKey      *rsa.PrivateKey
+	statusCache     *StatusCache
+	callbacks       *callback.Dispatcher
+	globalCallbacks []CallbackServerConfig
+	sinkPool        *callback.SinkPool
+	globalSinks     []callback.SinkConfig
+	mu              sync.Mutex
+
+	domainPatterns map[string]*regexp.Regexp
+	domainProxies  map[string]*domainProxyEntry
+	// patternUID is the UID of the proxy with the domain pattern that this proxy was created for
+	patternUID string
+	// backendTemplates maps the backends of a matched domain to their templates in the config
+	backendTemplates map[string]string
+
+	roundRobin    int
+	rand          *rand.Rand
+	healthChecker *healthChecker
 }
 
 func (proxy *Proxy) Process() process.Process {
@@ -63,25 +97,124 @@ func (proxy *Proxy) Process() process.Process {
 	return nil
 }
 
+// DomainName returns the first domain name of the proxy
 func (proxy *Proxy) DomainName() string {
+	domainNames := proxy.DomainNames()
+	if len(domainNames) == 0 {
+		return ""
+	}
+	return domainNames[0]
+}
+
+func (proxy *Proxy) DomainNames() []string {
 	proxy.Config.RLock()
 	defer proxy.Config.RUnlock()
-	return proxy.Config.DomainName
+	return proxy.Config.domainNames()
 }
 
+// ListenTo returns the first address that the proxy listens to
 func (proxy *Proxy) ListenTo() string {
+	listenTo := proxy.ListenAddrs()
+	if len(listenTo) == 0 {
+		return ""
+	}
+	return listenTo[0]
+}
+
+func (proxy *Proxy) ListenAddrs() []string {
 	proxy.Config.RLock()
 	defer proxy.Config.RUnlock()
-	return proxy.Config.ListenTo
+	return append([]string(nil), proxy.Config.ListenTo...)
 }
 
+// IsListeningTo checks if addr is one of the addresses that the proxy listens to
+func (proxy *Proxy) IsListeningTo(addr string) bool {
+	for _, listenTo := range proxy.ListenAddrs() {
+		if listenTo == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// ProxyTo returns the first backend of the proxy
 func (proxy *Proxy) ProxyTo() string {
+	backends := proxy.Backends()
+	if len(backends) == 0 {
+		return ""
+	}
+	return backends[0]
+}
+
+// Backends returns the addresses that the proxy balances its connections across
+func (proxy *Proxy) Backends() []string {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return append([]string(nil), proxy.Config.ProxyTo...)
+}
+
+func (proxy *Proxy) LoadBalancerStrategy() string {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.LoadBalancer.Strategy
+}
+
+func (proxy *Proxy) LoadBalancerWeights() map[string]int {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.LoadBalancer.Weights
+}
+
+func (proxy *Proxy) LoadBalancerSticky() string {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.LoadBalancer.Sticky
+}
+
+func (proxy *Proxy) IsHealthCheckEnabled() bool {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.HealthCheck.Enabled
+}
+
+// HealthCheckInterval falls back to defaultHealthCheckInterval if the interval is not positive,
+// so that backends are not checked in a busy loop
+func (proxy *Proxy) HealthCheckInterval() time.Duration {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	if proxy.Config.HealthCheck.Interval < 1 {
+		return defaultHealthCheckInterval
+	}
+	return time.Millisecond * time.Duration(proxy.Config.HealthCheck.Interval)
+}
+
+// HealthCheckRise is the number of successful checks in a row after which a backend is up again
+func (proxy *Proxy) HealthCheckRise() int {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	if proxy.Config.HealthCheck.Rise < 1 {
+		return 1
+	}
+	return proxy.Config.HealthCheck.Rise
+}
+
+// HealthCheckFall is the number of failed checks in a row after which a backend is down
+func (proxy *Proxy) HealthCheckFall() int {
 	proxy.Config.RLock()
 	defer proxy.Config.RUnlock()
-	return proxy.Config.ProxyTo
+	if proxy.Config.HealthCheck.Fall < 1 {
+		return 1
+	}
+	return proxy.Config.HealthCheck.Fall
 }
 
-func (proxy *Proxy) DisconnectMessage() string {
+func (proxy *Proxy) IsDefault() bool {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.Default
+}
+
+func (proxy *Proxy) DisconnectMessage() ChatMessage {
 	proxy.Config.RLock()
 	defer proxy.Config.RUnlock()
 	return proxy.Config.DisconnectMessage
@@ -93,12 +226,101 @@ func (proxy *Proxy) IsOnlineStatusConfigured() bool {
 	return proxy.Config.OnlineStatus.ProtocolNumber != 0
 }
 
+func (proxy *Proxy) IsOverrideStatusConfigured() bool {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.OverrideStatus.HasOverrides()
+}
+
+func (proxy *Proxy) OverrideStatusResponseJSON(responseJSON []byte) ([]byte, error) {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.OverrideStatus.OverrideResponseJSON(responseJSON)
+}
+
+func (proxy *Proxy) IsStatusCacheEnabled() bool {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.StatusCache.Enabled
+}
+
+func (proxy *Proxy) StatusCacheTTL() time.Duration {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return time.Millisecond * time.Duration(proxy.Config.StatusCache.TTL)
+}
+
+func (proxy *Proxy) SleepingMOTD() ChatMessage {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.StatusCache.SleepingMOTD
+}
+
+func (proxy *Proxy) ColdStartMode() string {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.ColdStart.Mode
+}
+
+// ColdStartMaxWait is clamped to stay below the login timeout of clients in the hold mode.
+// The limbo keeps clients connected, so it can wait longer.
+func (proxy *Proxy) ColdStartMaxWait() time.Duration {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	maxWait := time.Millisecond * time.Duration(proxy.Config.ColdStart.MaxWait)
+	if proxy.Config.ColdStart.Mode == ColdStartModeHold && maxWait > maxColdStartWait {
+		return maxColdStartWait
+	}
+	return maxWait
+}
+
+func (proxy *Proxy) ColdStartLimboMessage() ChatMessage {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.ColdStart.LimboMessage
+}
+
+func (proxy *Proxy) ColdStartReadyMessage() ChatMessage {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.ColdStart.ReadyMessage
+}
+
+// ColdStartPollInterval is clamped to at least minColdStartPollInterval
+func (proxy *Proxy) ColdStartPollInterval() time.Duration {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	pollInterval := time.Millisecond * time.Duration(proxy.Config.ColdStart.PollInterval)
+	if pollInterval < minColdStartPollInterval {
+		return minColdStartPollInterval
+	}
+	return pollInterval
+}
+
+func (proxy *Proxy) ColdStartPingStatus() bool {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.ColdStart.PingStatus
+}
+
 func (proxy *Proxy) OnlineStatusPacket() (protocol.Packet, error) {
 	proxy.Config.Lock()
 	defer proxy.Config.Unlock()
 	return proxy.Config.OnlineStatus.StatusResponsePacket()
 }
 
+func (proxy *Proxy) OnlineLegacyStatusResponse() status.LegacyServerListPong {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.OnlineStatus.LegacyStatusResponse()
+}
+
+func (proxy *Proxy) OfflineLegacyStatusResponse() status.LegacyServerListPong {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.OfflineStatus.LegacyStatusResponse()
+}
+
 func (proxy *Proxy) OfflineStatusPacket() (protocol.Packet, error) {
 	proxy.Config.Lock()
 	defer proxy.Config.Unlock()
@@ -129,42 +351,184 @@ func (proxy *Proxy) RealIP() bool {
 	return proxy.Config.RealIP
 }
 
-func (proxy *Proxy) CallbackLogger() callback.Logger {
+func (proxy *Proxy) ForwardingMode() string {
 	proxy.Config.RLock()
 	defer proxy.Config.RUnlock()
+	return proxy.Config.Forwarding.Mode
+}
+
+func (proxy *Proxy) ForwardingSecret() string {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.Forwarding.Secret
+}
+
+func (proxy *Proxy) OnlineMode() bool {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.OnlineMode
+}
+
+func (proxy *Proxy) SessionServerURL() string {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.SessionServerURL
+}
+
+// CallbackLoggers returns a logger for every global callback server of the gateway
+// and every callback server of the proxy
+func (proxy *Proxy) CallbackLoggers() []callback.Logger {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+
+	var loggers []callback.Logger
+	for _, cfg := range proxy.globalCallbacks {
+		loggers = append(loggers, cfg.logger())
+	}
+	if proxy.Config.CallbackServer.URL != "" {
+		loggers = append(loggers, proxy.Config.CallbackServer.logger())
+	}
+	for _, cfg := range proxy.Config.CallbackServers {
+		loggers = append(loggers, cfg.logger())
+	}
+	return loggers
+}
+
+// SinkConfigs returns the global sinks of the gateway and the sinks of the proxy
+func (proxy *Proxy) SinkConfigs() []callback.SinkConfig {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+
+	var cfgs []callback.SinkConfig
+	cfgs = append(cfgs, proxy.globalSinks...)
+	return append(cfgs, proxy.Config.Sinks...)
+}
+
+func (cfg CallbackServerConfig) logger() callback.Logger {
 	return callback.Logger{
-		URL:    proxy.Config.CallbackServer.URL,
-		Events: proxy.Config.CallbackServer.Events,
+		URL:         cfg.URL,
+		Events:      cfg.Events,
+		Format:      cfg.Format,
+		Template:    cfg.Template,
+		ContentType: cfg.ContentType,
+		Headers:     cfg.Headers,
+		Token:       cfg.Token,
+		Secret:      cfg.Secret,
 	}
 }
 
+// UID identifies the proxy by its first domain name and listen address
 func (proxy *Proxy) UID() string {
 	return proxyUID(proxy.DomainName(), proxy.ListenTo())
 }
 
-func (proxy *Proxy) addPlayer(conn Conn, username string) {
+// metricUID labels the metrics of the proxy. Proxies of matched domains use the UID of their
+// proxy with the domain pattern, since clients could create a series for every domain otherwise.
+func (proxy *Proxy) metricUID() string {
+	if proxy.patternUID != "" {
+		return proxy.patternUID
+	}
+	return proxy.UID()
+}
+
+// metricBackend labels the metrics of a backend. Backends of matched domains use their template,
+// e.g. "{1}.internal:25565", since clients could create a series for every domain otherwise.
+func (proxy *Proxy) metricBackend(backend string) string {
+	if template, ok := proxy.backendTemplates[backend]; ok {
+		return template
+	}
+	return backend
+}
+
+// UIDs returns the UIDs of every pair of domain name and listen address of the proxy
+func (proxy *Proxy) UIDs() []string {
+	var proxyUIDs []string
+	for _, listenTo := range proxy.ListenAddrs() {
+		for _, domainName := range proxy.DomainNames() {
+			proxyUIDs = append(proxyUIDs, proxyUID(domainName, listenTo))
+		}
+	}
+	return proxyUIDs
+}
+
+func (proxy *Proxy) addPlayer(conn Conn, connRemoteAddr net.Addr, username, backend string) {
 	proxy.mu.Lock()
 	defer proxy.mu.Unlock()
 	if proxy.players == nil {
-		proxy.players = map[Conn]string{}
+		proxy.players = map[Conn]player{}
+	}
+	p := player{
+		username:      username,
+		backend:       backend,
+		remoteAddress: connRemoteAddr.String(),
+		joinedAt:      time.Now(),
+		metricUID:     proxy.metricUID(),
 	}
-	proxy.players[conn] = username
+	proxy.players[conn] = p
+	metricPlayersOnline.With(p.metricUID).Inc()
 }
 
 func (proxy *Proxy) removePlayer(conn Conn) int {
 	proxy.mu.Lock()
 	defer proxy.mu.Unlock()
 	if proxy.players == nil {
-		proxy.players = map[Conn]string{}
+		proxy.players = map[Conn]player{}
 		return 0
 	}
-	delete(proxy.players, conn)
+	if p, ok := proxy.players[conn]; ok {
+		metricPlayersOnline.With(p.metricUID).Dec()
+		delete(proxy.players, conn)
+	}
 	return len(proxy.players)
 }
 
+// authenticator returns an Authenticator with a key pair that is
+// generated once and then reused for the lifetime of the Proxy
+func (proxy *Proxy) authenticator() (Authenticator, error) {
+	proxy.mu.Lock()
+	defer proxy.mu.Unlock()
+	if proxy.privateKey == nil {
+		privateKey, err := GenerateAuthenticationKey()
+		if err != nil {
+			return Authenticator{}, err
+		}
+		proxy.privateKey = privateKey
+	}
+
+	return Authenticator{
+		SessionServerURL: proxy.SessionServerURL(),
+		PrivateKey:       proxy.privateKey,
+	}, nil
+}
+
+// defaultSinkPool holds the sinks of proxies that are not registered in a gateway
+var defaultSinkPool = NewSinkPool()
+
+// logEvent writes the event to the sinks and queues it for the callback servers.
+// Proxies that are not registered in a gateway log the event synchronously instead.
 func (proxy *Proxy) logEvent(event callback.Event) {
-	if _, err := proxy.CallbackLogger().LogEvent(event); err != nil {
-		log.Println("[w] Failed callback logging; error:", err)
+	EventStream.PublishPattern(event, proxy.patternUID)
+
+	sinkPool := proxy.sinkPool
+	if sinkPool == nil {
+		sinkPool = defaultSinkPool
+	}
+	for _, cfg := range proxy.SinkConfigs() {
+		// Failed and dropped events are reported by the sink pool
+		_ = sinkPool.LogEvent(cfg, event)
+	}
+
+	for _, logger := range proxy.CallbackLoggers() {
+		if proxy.callbacks != nil {
+			// Failed and dropped events are reported by the dispatcher
+			_ = proxy.callbacks.LogEvent(logger, event)
+			continue
+		}
+
+		if _, err := logger.LogEvent(event); err != nil {
+			metricCallbackFailures.With(event.EventType()).Inc()
+			log.Println("[w] Failed callback logging; error:", err)
+		}
 	}
 }
 
@@ -179,19 +543,33 @@ func (proxy *Proxy) handleConn(conn Conn, connRemoteAddr net.Addr) error {
 		return err
 	}
 
-	proxyTo := proxy.ProxyTo()
+	if hs.IsTransferRequest() {
+		// Clients come back with a transfer from the limbo, but servers reject
+		// transfers by default, so they are passed on as a normal login
+		hs.NextState = handshaking.ServerBoundHandshakeLoginState
+		pk = hs.Marshal()
+	}
+
 	proxyUID := proxy.UID()
-	rconn, err := DialTimeout(proxyTo, proxy.Timeout())
+	rconn, proxyTo, err := proxy.dialBackend(proxy.stickyKey(conn, hs, connRemoteAddr))
 	if err != nil {
-		log.Printf("[i] %s did not respond to ping; is the target offline?", proxyTo)
+		proxy.logUnreachable(err)
 		if hs.IsStatusRequest() {
 			return proxy.handleStatusRequest(conn, false)
 		}
-		if err := proxy.startProcessIfNotRunning(); err != nil {
-			return err
+
+		switch proxy.ColdStartMode() {
+		case ColdStartModeHold:
+			rconn, proxyTo, err = proxy.waitForServer(hs, connRemoteAddr, proxy.ColdStartMaxWait(), nil)
+			if err != nil {
+				log.Printf("[i] %s did not start in time for %s; error: %s", proxy.ProxyTo(), connRemoteAddr, err)
+				return proxy.handleLoginRequest(conn, hs)
+			}
+		case ColdStartModeLimbo:
+			return proxy.handleLimbo(conn, hs, connRemoteAddr)
+		default:
+			return proxy.handleLoginRequest(conn, hs)
 		}
-		proxy.timeoutProcess()
-		return proxy.handleLoginRequest(conn)
 	}
 	defer rconn.Close()
 
@@ -199,16 +577,29 @@ func (proxy *Proxy) handleConn(conn Conn, connRemoteAddr net.Addr) error {
 		return proxy.handleStatusRequest(conn, true)
 	}
 
-	if proxy.ProxyProtocol() {
-		header := &proxyproto.Header{
-			Version:           2,
-			Command:           proxyproto.PROXY,
-			TransportProtocol: proxyproto.TCPv4,
-			SourceAddr:        connRemoteAddr,
-			DestinationAddr:   rconn.RemoteAddr(),
+	var username, playerUUID string
+	var loginStartPk protocol.Packet
+	var profile GameProfile
+	if hs.IsLoginRequest() {
+		proxy.cancelProcessTimeout()
+		var loginStart login.ServerLoginStart
+		loginStartPk, loginStart, err = proxy.sniffUsername(conn, hs, connRemoteAddr)
+		if err != nil {
+			return err
 		}
+		username = string(loginStart.Name)
 
-		if _, err = header.WriteTo(rconn); err != nil {
+		if proxy.ForwardingMode() != "" {
+			profile, err = proxy.gameProfile(conn, hs, loginStart)
+			if err != nil {
+				return err
+			}
+		}
+		playerUUID = loginStartUUID(loginStart, profile)
+	}
+
+	if proxy.ProxyProtocol() {
+		if err := writeProxyProtocolHeader(rconn, connRemoteAddr); err != nil {
 			return err
 		}
 	}
@@ -218,31 +609,51 @@ func (proxy *Proxy) handleConn(conn Conn, connRemoteAddr net.Addr) error {
 		pk = hs.Marshal()
 	}
 
+	if hs.IsLoginRequest() && proxy.ForwardingMode() == ForwardingModeBungeeCord {
+		if err := upgradeToBungeeCord(&hs, connRemoteAddr, profile); err != nil {
+			return err
+		}
+		pk = hs.Marshal()
+	}
+
 	if err := rconn.WritePacket(pk); err != nil {
 		return err
 	}
 
-	var username string
+	if hs.IsStatusRequest() && (proxy.IsOverrideStatusConfigured() || proxy.IsStatusCacheEnabled()) {
+		if err := proxy.interceptStatusResponse(conn, rconn); err != nil {
+			return err
+		}
+	}
+
 	if hs.IsLoginRequest() {
-		proxy.cancelProcessTimeout()
-		username, err = proxy.sniffUsername(conn, rconn, connRemoteAddr)
-		if err != nil {
+		if err := rconn.WritePacket(loginStartPk); err != nil {
 			return err
 		}
-		proxy.addPlayer(conn, username)
+
+		if proxy.ForwardingMode() == ForwardingModeVelocity {
+			if err := proxy.forwardVelocity(conn, rconn, connRemoteAddr, profile); err != nil {
+				return err
+			}
+		}
+
+		proxy.addPlayer(conn, connRemoteAddr, username, proxyTo)
 		proxy.logEvent(callback.PlayerJoinEvent{
 			Username:      username,
+			UUID:          playerUUID,
 			RemoteAddress: connRemoteAddr.String(),
 			TargetAddress: proxyTo,
 			ProxyUID:      proxyUID,
 		})
 	}
 
-	go pipe(rconn, conn)
-	pipe(conn, rconn)
+	metricUID := proxy.metricUID()
+	go pipe(rconn, conn, metricBytes.With(metricUID, directionClientBound))
+	pipe(conn, rconn, metricBytes.With(metricUID, directionServerBound))
 
 	proxy.logEvent(callback.PlayerLeaveEvent{
 		Username:      username,
+		UUID:          playerUUID,
 		RemoteAddress: connRemoteAddr.String(),
 		TargetAddress: proxyTo,
 		ProxyUID:      proxyUID,
@@ -255,7 +666,32 @@ func (proxy *Proxy) handleConn(conn Conn, connRemoteAddr net.Addr) error {
 	return nil
 }
 
-func pipe(src, dst Conn) {
+// logUnreachable logs why no backend of the proxy could be reached
+func (proxy *Proxy) logUnreachable(err error) {
+	switch err {
+	case ErrNoProxyTo:
+	case ErrBackendsDown:
+		log.Printf("[i] All backends of %s are down", proxy.UID())
+	default:
+		log.Printf("[i] %s did not respond to ping; is the target offline?", strings.Join(proxy.Backends(), ", "))
+	}
+}
+
+func writeProxyProtocolHeader(rconn Conn, connRemoteAddr net.Addr) error {
+	header := &proxyproto.Header{
+		Version:           2,
+		Command:           proxyproto.PROXY,
+		TransportProtocol: proxyproto.TCPv4,
+		SourceAddr:        connRemoteAddr,
+		DestinationAddr:   rconn.RemoteAddr(),
+	}
+
+	_, err := header.WriteTo(rconn)
+	return err
+}
+
+// pipe copies from src to dst until one of them is closed and counts the bytes
+func pipe(src, dst Conn, bytesCounter *metrics.Counter) {
 	buffer := make([]byte, 0xffff)
 
 	for {
@@ -266,7 +702,8 @@ func pipe(src, dst Conn) {
 
 		data := buffer[:n]
 
-		_, err = dst.Write(data)
+		n, err = dst.Write(data)
+		bytesCounter.Add(float64(n))
 		if err != nil {
 			return
 		}
@@ -289,7 +726,13 @@ func (proxy *Proxy) startProcessIfNotRunning() error {
 
 	log.Println("[i] Starting container for", proxy.UID())
 	proxy.logEvent(callback.ContainerStartEvent{ProxyUID: proxy.UID()})
-	return proxy.Process().Start()
+	metricContainerStarts.With(proxy.metricUID()).Inc()
+	start := time.Now()
+	if err := proxy.Process().Start(); err != nil {
+		return err
+	}
+	observeDuration(metricContainerStartDuration.With(proxy.metricUID()), start)
+	return nil
 }
 
 func (proxy *Proxy) timeoutProcess() {
@@ -297,66 +740,135 @@ func (proxy *Proxy) timeoutProcess() {
 		return
 	}
 
-	if proxy.DockerTimeout() <= 0 {
+	if proxy.DockerTimeout() <= 0 || proxy.IsProcessPinned() {
 		return
 	}
 
 	proxy.cancelProcessTimeout()
 
 	log.Printf("[i] Starting container timeout %s on %s", proxy.DockerTimeout(), proxy.UID())
-	timer := time.AfterFunc(proxy.DockerTimeout(), func() {
-		log.Println("[i] Stopping container on", proxy.UID())
-		proxy.logEvent(callback.ContainerStopEvent{ProxyUID: proxy.UID()})
-		if err := proxy.Process().Stop(); err != nil {
+	proxy.mu.Lock()
+	defer proxy.mu.Unlock()
+	var timer *time.Timer
+	timer = time.AfterFunc(proxy.DockerTimeout(), func() {
+		proxy.mu.Lock()
+		if proxy.processTimer == timer {
+			proxy.processTimer = nil
+		}
+		proxy.mu.Unlock()
+
+		if err := proxy.stopProcess(); err != nil {
 			log.Printf("[w] Failed to stop the container for %s; error: %s", proxy.UID(), err)
 		}
 	})
+	proxy.processTimer = timer
+}
 
-	proxy.cancelTimeoutFunc = func() {
-		if timer.Stop() {
-			log.Println("[i] Timout stopped for", proxy.UID())
-		}
-	}
+func (proxy *Proxy) stopProcess() error {
+	log.Println("[i] Stopping container on", proxy.UID())
+	proxy.logEvent(callback.ContainerStopEvent{ProxyUID: proxy.UID()})
+	metricContainerStops.With(proxy.metricUID()).Inc()
+	return proxy.Process().Stop()
 }
 
 func (proxy *Proxy) cancelProcessTimeout() {
-	if proxy.cancelTimeoutFunc == nil {
-		return
-	}
+	proxy.mu.Lock()
+	timer := proxy.processTimer
+	proxy.processTimer = nil
+	proxy.mu.Unlock()
 
-	proxy.cancelTimeoutFunc()
-	proxy.cancelTimeoutFunc = nil
+	if timer != nil && timer.Stop() {
+		log.Println("[i] Timout stopped for", proxy.UID())
+	}
 }
 
-func (proxy *Proxy) sniffUsername(conn, rconn Conn, connRemoteAddr net.Addr) (string, error) {
+// sniffUsername reads the Login Start packet of the client.
+// The packet still needs to be sent to the backend by the caller.
+func (proxy *Proxy) sniffUsername(conn Conn, hs handshaking.ServerBoundHandshake, connRemoteAddr net.Addr) (protocol.Packet, login.ServerLoginStart, error) {
 	pk, err := conn.ReadPacket()
 	if err != nil {
-		return "", err
+		return protocol.Packet{}, login.ServerLoginStart{}, err
 	}
-	rconn.WritePacket(pk)
 
-	ls, err := login.UnmarshalServerBoundLoginStart(pk)
+	ls, err := login.UnmarshalServerBoundLoginStart(pk, hs.ProtocolVersion)
 	if err != nil {
-		return "", err
+		return protocol.Packet{}, login.ServerLoginStart{}, err
 	}
 	log.Printf("[i] %s with username %s connects through %s", connRemoteAddr, ls.Name, proxy.UID())
-	return string(ls.Name), nil
+	return pk, ls, nil
+}
+
+// gameProfile returns the profile of the player that should be forwarded to the backend.
+// In online mode the player is authenticated first, otherwise the offline profile is used.
+func (proxy *Proxy) gameProfile(conn Conn, hs handshaking.ServerBoundHandshake, loginStart login.ServerLoginStart) (GameProfile, error) {
+	if !proxy.OnlineMode() {
+		return OfflineGameProfile(string(loginStart.Name)), nil
+	}
+
+	auth, err := proxy.authenticator()
+	if err != nil {
+		return GameProfile{}, err
+	}
+
+	profile, err := auth.Authenticate(conn, hs.ProtocolVersion, loginStart)
+	if err != nil {
+		return GameProfile{}, err
+	}
+	log.Printf("[i] %s authenticated as %s on %s", conn.RemoteAddr(), profile.Name, proxy.UID())
+	return profile, nil
 }
 
-func (proxy *Proxy) handleLoginRequest(conn Conn) error {
+// loginStartUUID returns the UUID of the player. The UUID of a forwarded profile
+// is preferred over the one that the client claims in its Login Start packet.
+func loginStartUUID(loginStart login.ServerLoginStart, profile GameProfile) string {
+	if profile.ID != "" {
+		if id, err := uuid.FromString(profile.ID); err == nil {
+			return id.String()
+		}
+	}
+
+	if !loginStart.HasPlayerUUID {
+		return ""
+	}
+
+	return uuid.UUID(loginStart.PlayerUUID).String()
+}
+
+func (proxy *Proxy) handleLoginRequest(conn Conn, hs handshaking.ServerBoundHandshake) error {
 	packet, err := conn.ReadPacket()
 	if err != nil {
 		return err
 	}
 
-	loginStart, err := login.UnmarshalServerBoundLoginStart(packet)
+	loginStart, err := login.UnmarshalServerBoundLoginStart(packet, hs.ProtocolVersion)
 	if err != nil {
 		return err
 	}
 
-	message := proxy.DisconnectMessage()
+	if proxy.OnlineMode() {
+		if _, err := proxy.gameProfile(conn, hs, loginStart); err != nil {
+			return err
+		}
+	}
+
+	if err := proxy.startProcessIfNotRunning(); err != nil {
+		return err
+	}
+	proxy.timeoutProcess()
+
+	message := proxy.templateMessage(proxy.DisconnectMessage(), conn, string(loginStart.Name), map[string]string{
+		"requestedDomain": hs.ParseServerAddress(),
+	})
+	return conn.WritePacket(login.ClientBoundDisconnect{
+		Reason: message.Chat(),
+	}.Marshal())
+}
+
+// templateMessage replaces the placeholders in the text of the message.
+// Additional templates can be passed for placeholders that only some messages have.
+func (proxy *Proxy) templateMessage(msg ChatMessage, conn Conn, username string, additionalTemplates map[string]string) protocol.ChatComponent {
 	templates := map[string]string{
-		"username":      string(loginStart.Name),
+		"username":      username,
 		"now":           time.Now().Format(time.RFC822),
 		"remoteAddress": conn.LocalAddr().String(),
 		"localAddress":  conn.LocalAddr().String(),
@@ -365,15 +877,89 @@ func (proxy *Proxy) handleLoginRequest(conn Conn) error {
 		"listenTo":      proxy.ListenTo(),
 	}
 
-	for key, value := range templates {
-		message = strings.Replace(message, fmt.Sprintf("{{%s}}", key), value, -1)
+	for key, value := range additionalTemplates {
+		templates[key] = value
 	}
 
-	return conn.WritePacket(login.ClientBoundDisconnect{
-		Reason: protocol.Chat(fmt.Sprintf("{\"text\":\"%s\"}", message)),
+	return msg.Component().MapText(func(text string) string {
+		for key, value := range templates {
+			text = strings.Replace(text, fmt.Sprintf("{{%s}}", key), value, -1)
+		}
+		return text
+	})
+}
+
+// interceptStatusResponse forwards the status request of the client to the backend
+// and answers it with the status response of the backend with overridden fields.
+// The response of the backend is cached if the status cache is enabled.
+// The following ping and pong are left for the caller to pipe.
+func (proxy *Proxy) interceptStatusResponse(conn, rconn Conn) error {
+	pk, err := conn.ReadPacket()
+	if err != nil {
+		return err
+	}
+
+	if err := rconn.WritePacket(pk); err != nil {
+		return err
+	}
+
+	pk, err = rconn.ReadPacket()
+	if err != nil {
+		return err
+	}
+
+	response, err := status.UnmarshalClientBoundResponse(pk)
+	if err != nil {
+		return err
+	}
+
+	if proxy.IsStatusCacheEnabled() && proxy.statusCache != nil {
+		if err := proxy.statusCache.Put(proxy.UID(), []byte(response.JSONResponse)); err != nil {
+			log.Printf("[w] Failed to cache status of %s; error: %s", proxy.UID(), err)
+		}
+	}
+
+	bb, err := proxy.OverrideStatusResponseJSON([]byte(response.JSONResponse))
+	if err != nil {
+		return err
+	}
+
+	return conn.WritePacket(status.ClientBoundResponse{
+		JSONResponse: protocol.String(bb),
 	}.Marshal())
 }
 
+// cachedStatusPacket builds a status response from the last known status of the server
+// with the overridden fields and the sleeping MOTD
+func (proxy *Proxy) cachedStatusPacket() (protocol.Packet, bool) {
+	if !proxy.IsStatusCacheEnabled() || proxy.statusCache == nil {
+		return protocol.Packet{}, false
+	}
+
+	responseJSON, ok := proxy.statusCache.Get(proxy.UID(), proxy.StatusCacheTTL())
+	if !ok {
+		return protocol.Packet{}, false
+	}
+
+	bb, err := proxy.OverrideStatusResponseJSON(responseJSON)
+	if err != nil {
+		log.Printf("[w] Failed to override cached status of %s; error: %s", proxy.UID(), err)
+		return protocol.Packet{}, false
+	}
+
+	if motd := proxy.SleepingMOTD(); motd != "" {
+		bb, err = StatusConfig{MOTD: motd}.OverrideResponseJSON(bb)
+		if err != nil {
+			log.Printf("[w] Failed to set sleeping MOTD of %s; error: %s", proxy.UID(), err)
+			return protocol.Packet{}, false
+		}
+	}
+
+	return status.ClientBoundResponse{
+		JSONResponse: protocol.String(bb),
+	}.Marshal(), true
+}
+
 func (proxy *Proxy) handleStatusRequest(conn Conn, online bool) error {
 	// Read the request packet and send status response back
 	_, err := conn.ReadPacket()
@@ -387,6 +973,8 @@ func (proxy *Proxy) handleStatusRequest(conn Conn, online bool) error {
 		if err != nil {
 			return err
 		}
+	} else if cachedPk, ok := proxy.cachedStatusPacket(); ok {
+		responsePk = cachedPk
 	} else {
 		responsePk, err = proxy.OfflineStatusPacket()
 		if err != nil {
@@ -405,3 +993,29 @@ func (proxy *Proxy) handleStatusRequest(conn Conn, online bool) error {
 
 	return conn.WritePacket(pingPk)
 }
+
+// handleLegacyServerListPing answers a legacy server list ping. The raw bytes of the ping
+// are needed to let the backend answer the ping itself if no online status is configured.
+func (proxy *Proxy) handleLegacyServerListPing(conn Conn, rawPing []byte) error {
+	rconn, _, err := proxy.dialBackend("")
+	if err != nil {
+		proxy.logUnreachable(err)
+		_, err := conn.Write(proxy.OfflineLegacyStatusResponse().Marshal())
+		return err
+	}
+	defer rconn.Close()
+
+	if proxy.IsOnlineStatusConfigured() {
+		_, err := conn.Write(proxy.OnlineLegacyStatusResponse().Marshal())
+		return err
+	}
+
+	if _, err := rconn.Write(rawPing); err != nil {
+		return err
+	}
+
+	metricUID := proxy.metricUID()
+	go pipe(rconn, conn, metricBytes.With(metricUID, directionClientBound))
+	pipe(conn, rconn, metricBytes.With(metricUID, directionServerBound))
+	return nil
+}
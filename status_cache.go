@@ -0,0 +1,182 @@
+package infrared
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+)
+
+var statusCacheFileNameReplacer = regexp.MustCompile(`[^a-zA-Z0-9.\-]`)
+
+// statusCacheStoreInterval is how often an unchanged entry is stored again,
+// so that the time it was cached at is not too old after a restart
+const statusCacheStoreInterval = time.Minute
+
+// StatusCache remembers the last status responses of the servers behind the proxies,
+// so that they can still be shown while the servers are asleep. If the cache has a
+// directory, every entry is also stored as a file to survive restarts.
+type StatusCache struct {
+	dir     string
+	entries map[string]statusCacheEntry
+	mu      sync.Mutex
+}
+
+type statusCacheEntry struct {
+	CachedAt     time.Time       `json:"cachedAt"`
+	ResponseJSON json.RawMessage `json:"response"`
+	// PlayerSample is the last non-empty player sample, which is
+	// kept even if nobody was online when the server was last pinged
+	PlayerSample json.RawMessage `json:"playerSample,omitempty"`
+	// storedAt is when the entry was last written to its file
+	storedAt time.Time
+}
+
+// NewStatusCache creates a StatusCache that stores its entries in dir.
+// An empty dir keeps the entries in memory only.
+func NewStatusCache(dir string) *StatusCache {
+	return &StatusCache{
+		dir:     dir,
+		entries: map[string]statusCacheEntry{},
+	}
+}
+
+// Put stores the status response JSON of the server behind the proxy with the given UID
+func (cache *StatusCache) Put(proxyUID string, responseJSON []byte) error {
+	var response struct {
+		Players struct {
+			Sample json.RawMessage `json:"sample"`
+		} `json:"players"`
+	}
+	if err := json.Unmarshal(responseJSON, &response); err != nil {
+		return err
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	entry := statusCacheEntry{
+		CachedAt:     time.Now(),
+		ResponseJSON: responseJSON,
+		PlayerSample: response.Players.Sample,
+	}
+
+	previous := cache.load(proxyUID)
+	if !hasPlayerSample(entry.PlayerSample) {
+		entry.PlayerSample = previous.PlayerSample
+	}
+
+	// Servers are pinged a lot, so the file is only written if the status changed
+	unchanged := bytes.Equal(entry.ResponseJSON, previous.ResponseJSON) &&
+		bytes.Equal(entry.PlayerSample, previous.PlayerSample)
+	if cache.dir == "" || unchanged && entry.CachedAt.Sub(previous.storedAt) < statusCacheStoreInterval {
+		entry.storedAt = previous.storedAt
+		cache.entries[proxyUID] = entry
+		return nil
+	}
+
+	bb, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(cache.dir, 0755); err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(cache.path(proxyUID), bb, 0644); err != nil {
+		cache.entries[proxyUID] = entry
+		return err
+	}
+
+	entry.storedAt = entry.CachedAt
+	cache.entries[proxyUID] = entry
+	return nil
+}
+
+// Get returns the cached status response JSON of the server behind the proxy with the
+// given UID. The player count is set to zero, since nobody can play on a sleeping server,
+// and the sample shows the last players that were online. Entries that are older than
+// the ttl are ignored; a ttl of zero or less never expires.
+func (cache *StatusCache) Get(proxyUID string, ttl time.Duration) ([]byte, bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	entry := cache.load(proxyUID)
+	if entry.ResponseJSON == nil {
+		return nil, false
+	}
+
+	if ttl > 0 && time.Since(entry.CachedAt) > ttl {
+		return nil, false
+	}
+
+	var response map[string]json.RawMessage
+	if err := json.Unmarshal(entry.ResponseJSON, &response); err != nil {
+		return nil, false
+	}
+
+	players := map[string]json.RawMessage{}
+	if raw, ok := response["players"]; ok {
+		if err := json.Unmarshal(raw, &players); err != nil {
+			return nil, false
+		}
+	}
+
+	players["online"] = json.RawMessage("0")
+	if hasPlayerSample(entry.PlayerSample) {
+		players["sample"] = entry.PlayerSample
+	}
+
+	if err := setRawJSON(response, "players", players); err != nil {
+		return nil, false
+	}
+
+	bb, err := json.Marshal(response)
+	if err != nil {
+		return nil, false
+	}
+
+	return bb, true
+}
+
+// load returns the entry from memory or from its file if it was not loaded yet
+func (cache *StatusCache) load(proxyUID string) statusCacheEntry {
+	if entry, ok := cache.entries[proxyUID]; ok {
+		return entry
+	}
+
+	if cache.dir == "" {
+		return statusCacheEntry{}
+	}
+
+	bb, err := ioutil.ReadFile(cache.path(proxyUID))
+	if err != nil {
+		return statusCacheEntry{}
+	}
+
+	var entry statusCacheEntry
+	if err := json.Unmarshal(bb, &entry); err != nil {
+		return statusCacheEntry{}
+	}
+
+	cache.entries[proxyUID] = entry
+	return entry
+}
+
+func (cache *StatusCache) path(proxyUID string) string {
+	fileName := statusCacheFileNameReplacer.ReplaceAllString(proxyUID, "_") + ".json"
+	return filepath.Join(cache.dir, fileName)
+}
+
+func hasPlayerSample(sample json.RawMessage) bool {
+	var players []json.RawMessage
+	if err := json.Unmarshal(sample, &players); err != nil {
+		return false
+	}
+	return len(players) > 0
+}
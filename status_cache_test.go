@@ -0,0 +1,84 @@
+package infrared
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/haveachin/infrared/protocol/status"
+)
+
+func TestStatusCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "infrared-status-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	proxyUID := "mc.example.com@:25565"
+	withPlayers := `{"version":{"name":"1.16.5","protocol":754},"players":{"max":20,"online":1,"sample":[{"name":"Notch","id":"069a79f4-44e9-4726-a5be-fca90e38aaf5"}]},"description":{"text":"Hi"}}`
+	withoutPlayers := `{"version":{"name":"1.16.5","protocol":754},"players":{"max":30,"online":0},"description":{"text":"Hi"}}`
+
+	cache := NewStatusCache(dir)
+	if _, ok := cache.Get(proxyUID, time.Hour); ok {
+		t.Fatal("empty cache returned a status")
+	}
+
+	if err := cache.Put(proxyUID, []byte(withPlayers)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cache.Put(proxyUID, []byte(withoutPlayers)); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new cache has to load the status from disk
+	bb, ok := NewStatusCache(dir).Get(proxyUID, time.Hour)
+	if !ok {
+		t.Fatal("status was not persisted")
+	}
+
+	var response status.ResponseJSON
+	if err := json.Unmarshal(bb, &response); err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Players.Max != 30 || response.Players.Online != 0 {
+		t.Errorf("players: got: %v; want: max 30 and online 0", response.Players)
+	}
+
+	if len(response.Players.Sample) != 1 || response.Players.Sample[0].Name != "Notch" {
+		t.Errorf("sample: got: %v; want: the last sample with Notch", response.Players.Sample)
+	}
+
+	if _, ok := cache.Get(proxyUID, time.Nanosecond); ok {
+		t.Error("expired status was returned")
+	}
+
+	if _, ok := cache.Get(proxyUID, 0); !ok {
+		t.Error("status without ttl was not returned")
+	}
+
+	// An unchanged status must not be written again
+	if err := os.Remove(cache.path(proxyUID)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cache.Put(proxyUID, []byte(withoutPlayers)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(cache.path(proxyUID)); !os.IsNotExist(err) {
+		t.Errorf("got: %v; want: unchanged status not written", err)
+	}
+
+	if err := cache.Put(proxyUID, []byte(withPlayers)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(cache.path(proxyUID)); err != nil {
+		t.Errorf("got: %v; want: changed status written", err)
+	}
+}